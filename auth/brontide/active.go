@@ -44,3 +44,26 @@ func ActiveHandshake(conn io.ReadWriteCloser, localKey *btcec.PrivateKey, remote
 
 	return b, nil
 }
+
+// ActiveHandshakeIK performs the IK handshake over provided transport as the initiator. The payload is sent to
+// the responder along with the first packet. Returns the connection and the payload of the response.
+func ActiveHandshakeIK(conn io.ReadWriteCloser, localKey *btcec.PrivateKey, remoteKey *btcec.PublicKey, payload []byte) (*Conn, []byte, error) {
+	b := &Conn{
+		conn:    conn,
+		noise:   NewIKMachine(true, &PrivKeyECDH{localKey}, remoteKey),
+		pattern: PatternIK,
+	}
+
+	if err := b.noise.WriteActOneIK(conn, payload); err != nil {
+		b.conn.Close()
+		return nil, nil, err
+	}
+
+	response, err := b.noise.ReadActTwoIK(conn)
+	if err != nil {
+		b.conn.Close()
+		return nil, nil, err
+	}
+
+	return b, response, nil
+}
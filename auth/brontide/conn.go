@@ -16,6 +16,7 @@ import (
 type Conn struct {
 	conn    io.ReadWriteCloser
 	noise   *Machine
+	pattern string
 	readBuf bytes.Buffer
 }
 
@@ -59,7 +60,7 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 	// depleted, then we read the next record, and feed it into the
 	// buffer. Otherwise, we read directly from the buffer.
 	if c.readBuf.Len() == 0 {
-		plaintext, err := c.noise.ReadMessage(c.conn)
+		plaintext, err := c.readMessage()
 		if err != nil {
 			return 0, err
 		}
@@ -80,6 +81,15 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 func (c *Conn) Write(b []byte) (n int, err error) {
 	// If the message doesn't require any chunking, then we can go ahead
 	// with a single write.
+	// empty messages announce rekeying, so they can't carry data
+	if len(b) == 0 && c.noise.rekey != nil {
+		return 0, nil
+	}
+
+	if err := c.maybeRekey(); err != nil {
+		return 0, err
+	}
+
 	if len(b) <= math.MaxUint16 {
 		err = c.noise.WriteMessage(b)
 		if err != nil {
@@ -104,6 +114,11 @@ func (c *Conn) Write(b []byte) (n int, err error) {
 		// Slice off the next chunk to be written based on our running
 		// counter and next chunk size.
 		chunk := b[bytesWritten : bytesWritten+chunkSize]
+		if bytesWritten > 0 {
+			if err := c.maybeRekey(); err != nil {
+				return bytesWritten, err
+			}
+		}
 		if err := c.noise.WriteMessage(chunk); err != nil {
 			return bytesWritten, err
 		}
@@ -156,3 +171,12 @@ func (c *Conn) RemotePub() *btcec.PublicKey {
 func (c *Conn) LocalPub() *btcec.PublicKey {
 	return c.noise.localStatic.PubKey()
 }
+
+// Pattern returns the name of the handshake pattern used to establish the
+// connection.
+func (c *Conn) Pattern() string {
+	if c.pattern == "" {
+		return PatternXK
+	}
+	return c.pattern
+}
@@ -0,0 +1,306 @@
+package brontide
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+const (
+	// protocolNameIK is the instantiation of the Noise protocol used by
+	// the IK handshake.
+	protocolNameIK = "Noise_IK_secp256k1_ChaChaPoly_SHA256"
+
+	// HandshakeVersionIK is the version byte of IK handshake packets. It
+	// lets the responder tell an IK handshake from an XK one by the first
+	// byte of the stream.
+	HandshakeVersionIK = byte(1)
+
+	// MaxPayloadSize is the maximum size of a payload carried by an IK
+	// handshake packet.
+	MaxPayloadSize = 4096
+
+	// PatternXK and PatternIK name the handshake patterns
+	PatternXK = "XK"
+	PatternIK = "IK"
+)
+
+// ErrPayloadTooLarge is returned when a handshake payload exceeds
+// MaxPayloadSize.
+var ErrPayloadTooLarge = errors.New("handshake payload too large")
+
+// ErrAuthFailed is returned when a packet of an IK handshake was read, but
+// could not be authenticated. Unlike transport errors, it means that the
+// remote party can't or won't complete an IK handshake with us.
+var ErrAuthFailed = errors.New("handshake authentication failed")
+
+// PayloadResponder is called by the responder of an IK handshake with the
+// payload sent by the initiator. It returns the payload of the response.
+type PayloadResponder func(payload []byte) ([]byte, error)
+
+// NewIKMachine creates a new instance of the brontide state-machine using
+// the IK handshake pattern. In IK the initiator sends its static key in the
+// first packet, so the handshake completes in a single round trip:
+//
+//	<- s
+//	...
+//	-> e, es, s, ss
+//	<- e, ee, se
+//
+// Both packets can carry a payload. The payload of the first packet is not
+// forward secret, since it's encrypted only with keys derived from the
+// responder's static key.
+func NewIKMachine(initiator bool, localKey SingleKeyECDH,
+	remotePub *btcec.PublicKey, options ...func(*Machine)) *Machine {
+
+	m := &Machine{
+		handshakeState: newHandshakeStateWithProtocol(
+			protocolNameIK, initiator, astralPrologue, localKey,
+			remotePub,
+		),
+		ephemeralGen: ephemeralGen,
+	}
+
+	for _, option := range options {
+		option(m)
+	}
+
+	return m
+}
+
+// WriteActOneIK writes the first packet of the IK handshake carrying the
+// payload.
+//
+//	-> e, es, s, ss
+func (b *Machine) WriteActOneIK(w io.Writer, payload []byte) error {
+	// e
+	localEphemeral, err := b.ephemeralGen()
+	if err != nil {
+		return err
+	}
+	b.localEphemeral = &PrivKeyECDH{PrivKey: localEphemeral}
+
+	ephemeral := localEphemeral.PubKey().SerializeCompressed()
+	b.mixHash(ephemeral)
+
+	// es
+	es, err := ecdh(b.remoteStatic, b.localEphemeral)
+	if err != nil {
+		return err
+	}
+	b.mixKey(es)
+
+	// s
+	static := b.EncryptAndHash(b.localStatic.PubKey().SerializeCompressed())
+
+	// ss
+	ss, err := ecdh(b.remoteStatic, b.localStatic)
+	if err != nil {
+		return err
+	}
+	b.mixKey(ss)
+
+	packet := append([]byte{HandshakeVersionIK}, ephemeral...)
+	packet = append(packet, static...)
+
+	packet, err = b.appendPayload(packet, payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(packet)
+	return err
+}
+
+// ReadActOneIK reads the first packet of the IK handshake following the
+// version byte and returns its payload. After this call the responder knows
+// the initiator's static key.
+func (b *Machine) ReadActOneIK(r io.Reader) ([]byte, error) {
+	var (
+		e [33]byte
+		s [33 + macSize]byte
+	)
+
+	if _, err := io.ReadFull(r, e[:]); err != nil {
+		return nil, err
+	}
+
+	// e
+	var err error
+	b.remoteEphemeral, err = btcec.ParsePubKey(e[:])
+	if err != nil {
+		return nil, err
+	}
+	b.mixHash(e[:])
+
+	// es
+	es, err := ecdh(b.remoteEphemeral, b.localStatic)
+	if err != nil {
+		return nil, err
+	}
+	b.mixKey(es)
+
+	// s
+	if _, err := io.ReadFull(r, s[:]); err != nil {
+		return nil, err
+	}
+	remotePub, err := b.DecryptAndHash(s[:])
+	if err != nil {
+		return nil, err
+	}
+	b.remoteStatic, err = btcec.ParsePubKey(remotePub)
+	if err != nil {
+		return nil, err
+	}
+
+	// ss
+	ss, err := ecdh(b.remoteStatic, b.localStatic)
+	if err != nil {
+		return nil, err
+	}
+	b.mixKey(ss)
+
+	return b.readPayload(r)
+}
+
+// WriteActTwoIK writes the second packet of the IK handshake carrying the
+// payload and derives the session keys.
+//
+//	<- e, ee, se
+func (b *Machine) WriteActTwoIK(w io.Writer, payload []byte) error {
+	// e
+	localEphemeral, err := b.ephemeralGen()
+	if err != nil {
+		return err
+	}
+	b.localEphemeral = &PrivKeyECDH{PrivKey: localEphemeral}
+
+	ephemeral := localEphemeral.PubKey().SerializeCompressed()
+	b.mixHash(ephemeral)
+
+	// ee
+	ee, err := ecdh(b.remoteEphemeral, b.localEphemeral)
+	if err != nil {
+		return err
+	}
+	b.mixKey(ee)
+
+	// se
+	se, err := ecdh(b.remoteStatic, b.localEphemeral)
+	if err != nil {
+		return err
+	}
+	b.mixKey(se)
+
+	packet := append([]byte{HandshakeVersionIK}, ephemeral...)
+
+	packet, err = b.appendPayload(packet, payload)
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(packet); err != nil {
+		return err
+	}
+
+	b.split()
+
+	return nil
+}
+
+// ReadActTwoIK reads the second packet of the IK handshake, derives the
+// session keys and returns the responder's payload.
+func (b *Machine) ReadActTwoIK(r io.Reader) ([]byte, error) {
+	var (
+		v [1]byte
+		e [33]byte
+	)
+
+	if _, err := io.ReadFull(r, v[:]); err != nil {
+		return nil, err
+	}
+	if v[0] != HandshakeVersionIK {
+		return nil, fmt.Errorf("%w: act two: invalid handshake version: %v, "+
+			"only %v is valid", ErrAuthFailed, v[0], HandshakeVersionIK)
+	}
+
+	if _, err := io.ReadFull(r, e[:]); err != nil {
+		return nil, err
+	}
+
+	// e
+	var err error
+	b.remoteEphemeral, err = btcec.ParsePubKey(e[:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: act two: %w", ErrAuthFailed, err)
+	}
+	b.mixHash(e[:])
+
+	// ee
+	ee, err := ecdh(b.remoteEphemeral, b.localEphemeral)
+	if err != nil {
+		return nil, fmt.Errorf("%w: act two: %w", ErrAuthFailed, err)
+	}
+	b.mixKey(ee)
+
+	// se
+	se, err := ecdh(b.remoteEphemeral, b.localStatic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: act two: %w", ErrAuthFailed, err)
+	}
+	b.mixKey(se)
+
+	payload, err := b.readPayload(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b.split()
+
+	return payload, nil
+}
+
+// appendPayload appends the length-prefixed, encrypted payload to the
+// packet. The length is sent in plaintext, but it's mixed into the handshake
+// digest.
+func (b *Machine) appendPayload(packet []byte, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(len(payload)))
+	b.mixHash(length[:])
+
+	packet = append(packet, length[:]...)
+	return append(packet, b.EncryptAndHash(payload)...), nil
+}
+
+// readPayload reads a payload written by appendPayload
+func (b *Machine) readPayload(r io.Reader) ([]byte, error) {
+	var length [2]byte
+
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+
+	var n = int(binary.BigEndian.Uint16(length[:]))
+	if n > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	b.mixHash(length[:])
+
+	var buf = make([]byte, n+macSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	payload, err := b.DecryptAndHash(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAuthFailed, err)
+	}
+	return payload, nil
+}
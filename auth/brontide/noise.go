@@ -99,6 +99,12 @@ type cipherState struct {
 	// cipher is an instance of the ChaCha20-Poly1305 AEAD construction
 	// created using the secretKey above.
 	cipher cipher.AEAD
+
+	// used is the number of messages processed since the keys were
+	// derived from a key exchange. A message is counted once, even though
+	// its length and body are encrypted separately. Unlike nonce, it is not
+	// reset by key rotation.
+	used uint64
 }
 
 // Encrypt returns a ciphertext which is the encryption of the plainText
@@ -297,6 +303,17 @@ func newHandshakeState(initiator bool, prologue []byte,
 	localKey SingleKeyECDH,
 	remotePub *btcec.PublicKey) handshakeState {
 
+	return newHandshakeStateWithProtocol(
+		protocolName, initiator, prologue, localKey, remotePub,
+	)
+}
+
+// newHandshakeStateWithProtocol returns a new instance of the handshake state
+// for the named instantiation of the Noise protocol.
+func newHandshakeStateWithProtocol(name string, initiator bool,
+	prologue []byte, localKey SingleKeyECDH,
+	remotePub *btcec.PublicKey) handshakeState {
+
 	h := handshakeState{
 		initiator:    initiator,
 		localStatic:  localKey,
@@ -307,11 +324,11 @@ func newHandshakeState(initiator bool, prologue []byte,
 	// protocol name, and additionally mix in the prologue. If either sides
 	// disagree about the prologue or protocol name, then the handshake
 	// will fail.
-	h.InitializeSymmetric([]byte(protocolName))
+	h.InitializeSymmetric([]byte(name))
 	h.mixHash(prologue)
 
-	// In Noise_XK, the initiator should know the responder's static
-	// public key, therefore we include the responder's static key in the
+	// In Noise_XK and Noise_IK, the initiator should know the responder's
+	// static public key, therefore we include the responder's static key in the
 	// handshake digest. If the initiator gets this value wrong, then the
 	// handshake will fail.
 	if initiator {
@@ -382,6 +399,17 @@ type Machine struct {
 	// out for a pending message. This allows us to tolerate timeout errors
 	// that cause partial writes.
 	nextBodySend []byte
+
+	// rekey holds the policy of replacing the sending keys with fresh ones
+	// or nil if rekeying was not enabled for the connection.
+	rekey *RekeyPolicy
+
+	// sendKeyAt is the time the current sending keys were derived.
+	sendKeyAt time.Time
+
+	// sendBytes is the number of bytes encrypted with the current sending
+	// keys.
+	sendBytes uint64
 }
 
 // NewBrontideMachine creates a new instance of the brontide state-machine. If
@@ -712,6 +740,8 @@ func (b *Machine) split() {
 		b.sendCipher = cipherState{}
 		b.sendCipher.InitializeKeyWithSalt(b.chainingKey, sendKey)
 	}
+
+	b.sendKeyAt = time.Now()
 }
 
 // WriteMessage encrypts and buffers the next message p. The ciphertext of the
@@ -735,6 +765,13 @@ func (b *Machine) WriteMessage(p []byte) error {
 		return ErrMessageNotFlushed
 	}
 
+	// Refuse to encrypt any more messages once the keys are worn out, the
+	// connection has to be rekeyed or replaced.
+	if b.sendCipher.used >= maxMessagesPerKey {
+		return ErrKeysExhausted
+	}
+	b.sendBytes += uint64(len(p))
+
 	// The full length of the packet is only the packet length, and does
 	// NOT include the MAC.
 	fullLength := uint16(len(p))
@@ -747,6 +784,7 @@ func (b *Machine) WriteMessage(p []byte) error {
 
 	// Finally, generate the encrypted packet itself.
 	b.nextBodySend = b.sendCipher.Encrypt(nil, nil, p)
+	b.sendCipher.used++
 
 	return nil
 }
@@ -853,6 +891,10 @@ func (b *Machine) ReadHeader(r io.Reader) (uint32, error) {
 		return 0, err
 	}
 
+	if b.recvCipher.used >= maxMessagesPerKey {
+		return 0, ErrKeysExhausted
+	}
+
 	// Attempt to decrypt+auth the packet length present in the stream.
 	//
 	// By passing in `nextCipherHeader` as the destination, we avoid making
@@ -863,6 +905,7 @@ func (b *Machine) ReadHeader(r io.Reader) (uint32, error) {
 	if err != nil {
 		return 0, err
 	}
+	b.recvCipher.used++
 
 	// Compute the packet length that we will need to read off the wire.
 	pktLen := uint32(binary.BigEndian.Uint16(pktLenBytes)) + macSize
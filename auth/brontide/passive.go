@@ -8,6 +8,23 @@ import (
 
 // PassiveHandshake performs the brontide handshake over provided transport as the responder.
 func PassiveHandshake(conn io.ReadWriteCloser, localStatic *btcec.PrivateKey) (*Conn, error) {
+	return PassiveHandshakeIK(conn, localStatic, nil)
+}
+
+// PassiveHandshakeIK performs the handshake over provided transport as the responder. If respond is not nil,
+// the initiator can use either the XK or the IK pattern, otherwise only XK is accepted. Respond is called only
+// for IK handshakes.
+func PassiveHandshakeIK(conn io.ReadWriteCloser, localStatic *btcec.PrivateKey, respond PayloadResponder) (*Conn, error) {
+	var version [1]byte
+	if _, err := io.ReadFull(conn, version[:]); err != nil {
+		conn.Close()
+		return nil, rejectedConnErr(err, "")
+	}
+
+	if version[0] == HandshakeVersionIK && respond != nil {
+		return passiveIK(conn, localStatic, respond)
+	}
+
 	ecdh := &PrivKeyECDH{PrivKey: localStatic}
 
 	c := &Conn{
@@ -16,7 +33,8 @@ func PassiveHandshake(conn io.ReadWriteCloser, localStatic *btcec.PrivateKey) (*
 	}
 
 	var actOne [ActOneSize]byte
-	if _, err := io.ReadFull(conn, actOne[:]); err != nil {
+	actOne[0] = version[0]
+	if _, err := io.ReadFull(conn, actOne[1:]); err != nil {
 		c.conn.Close()
 		return nil, rejectedConnErr(err, "")
 	}
@@ -48,6 +66,34 @@ func PassiveHandshake(conn io.ReadWriteCloser, localStatic *btcec.PrivateKey) (*
 	return c, nil
 }
 
+// passiveIK finishes an IK handshake after its version byte was read
+func passiveIK(conn io.ReadWriteCloser, localStatic *btcec.PrivateKey, respond PayloadResponder) (*Conn, error) {
+	c := &Conn{
+		conn:    conn,
+		noise:   NewIKMachine(false, &PrivKeyECDH{PrivKey: localStatic}, nil),
+		pattern: PatternIK,
+	}
+
+	payload, err := c.noise.ReadActOneIK(conn)
+	if err != nil {
+		c.conn.Close()
+		return nil, rejectedConnErr(err, "")
+	}
+
+	response, err := respond(payload)
+	if err != nil {
+		c.conn.Close()
+		return nil, rejectedConnErr(err, "")
+	}
+
+	if err := c.noise.WriteActTwoIK(conn, response); err != nil {
+		c.conn.Close()
+		return nil, rejectedConnErr(err, "")
+	}
+
+	return c, nil
+}
+
 // rejectedConnErr is a helper function that prepends the remote address of the
 // failed connection attempt to the original error message.
 func rejectedConnErr(err error, remoteAddr string) error {
@@ -0,0 +1,199 @@
+package brontide
+
+import (
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"golang.org/x/crypto/hkdf"
+)
+
+// maxMessagesPerKey is the number of messages that can be sent in one
+// direction with keys derived from a single key exchange. The ratcheting done
+// by rotateKey doesn't add any fresh key material, so once the limit is
+// reached the connection has to be rekeyed or closed.
+const maxMessagesPerKey = 1 << 32
+
+var (
+	// ErrKeysExhausted is returned when the session keys were used for
+	// maxMessagesPerKey messages.
+	ErrKeysExhausted = errors.New("session keys exhausted")
+
+	// ErrInvalidRekey is returned when the remote party sends a malformed
+	// rekey record.
+	ErrInvalidRekey = errors.New("invalid rekey record")
+)
+
+// RekeyPolicy decides when the sending keys of a connection are replaced with
+// keys derived from a fresh key exchange.
+type RekeyPolicy struct {
+	// Interval is the maximum age of the sending keys.
+	Interval time.Duration
+
+	// Volume is the maximum number of bytes sent with the same keys.
+	Volume uint64
+
+	// Messages is the maximum number of messages sent with the same keys.
+	Messages uint64
+}
+
+// DefaultRekeyPolicy rekeys every hour, every GiB of data or every million
+// messages, whichever comes first.
+var DefaultRekeyPolicy = RekeyPolicy{
+	Interval: time.Hour,
+	Volume:   1 << 30,
+	Messages: 1 << 20,
+}
+
+// EnableRekey enables rekeying of the connection according to the policy.
+// Both parties have to enable rekeying at the same point of the stream,
+// because the rekey records are not understood by parties that don't expect
+// them.
+//
+// A rekey is one-way and doesn't need a round trip. The sender sends an empty
+// message followed by a fresh ephemeral public key and switches its sending
+// keys. The new keys are derived from the current salt and the ECDH between
+// the ephemeral key and the static key of the receiver:
+//
+//	-> (empty), e
+//	salt', k' = hkdf(salt, ecdh(e, rs))
+//
+// Since the new keys are derived one-way from the current salt, leaking the
+// session state after a rekey doesn't reveal the keys used before it, and a
+// passive attacker who learned the session state loses track of it at the next
+// rekey. Only the sender contributes an ephemeral key, so this is not forward
+// secret with respect to the static keys: an attacker who holds the session
+// state and the receiver's static key can derive all later keys.
+func (c *Conn) EnableRekey(policy RekeyPolicy) {
+	c.noise.rekey = &policy
+}
+
+// Rekey replaces the sending keys with keys derived from a fresh key exchange.
+func (c *Conn) Rekey() error {
+	if c.noise.rekey == nil {
+		return errors.New("rekeying not enabled")
+	}
+
+	return c.rekey()
+}
+
+// maybeRekey rekeys the connection if the policy says the sending keys are
+// due for replacement.
+func (c *Conn) maybeRekey() error {
+	if !c.noise.needsRekey() {
+		return nil
+	}
+
+	return c.rekey()
+}
+
+func (c *Conn) rekey() error {
+	ephemeral, err := c.noise.ephemeralGen()
+	if err != nil {
+		return err
+	}
+
+	// announce the rekey with an empty message
+	if err := c.noise.WriteMessage(nil); err != nil {
+		return err
+	}
+	if _, err := c.noise.Flush(c.conn); err != nil {
+		return err
+	}
+
+	if err := c.noise.WriteMessage(ephemeral.PubKey().SerializeCompressed()); err != nil {
+		return err
+	}
+	if _, err := c.noise.Flush(c.conn); err != nil {
+		return err
+	}
+
+	secret, err := ecdh(c.noise.remoteStatic, &PrivKeyECDH{PrivKey: ephemeral})
+	if err != nil {
+		return err
+	}
+
+	c.noise.sendCipher.rekey(secret)
+	c.noise.sendKeyAt = time.Now()
+	c.noise.sendBytes = 0
+
+	return nil
+}
+
+// readMessage reads the next message processing any rekey records on the way.
+func (c *Conn) readMessage() ([]byte, error) {
+	for {
+		msg, err := c.noise.ReadMessage(c.conn)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(msg) > 0 || c.noise.rekey == nil {
+			return msg, nil
+		}
+
+		if err := c.recvRekey(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// recvRekey reads the ephemeral key of a rekey record and switches the
+// receiving keys.
+func (c *Conn) recvRekey() error {
+	msg, err := c.noise.ReadMessage(c.conn)
+	if err != nil {
+		return err
+	}
+
+	ephemeral, err := btcec.ParsePubKey(msg)
+	if err != nil {
+		return ErrInvalidRekey
+	}
+
+	secret, err := ecdh(ephemeral, c.noise.localStatic)
+	if err != nil {
+		return err
+	}
+
+	c.noise.recvCipher.rekey(secret)
+
+	return nil
+}
+
+// needsRekey returns true if rekeying is enabled and the sending keys are due
+// for replacement. Keys are never replaced while a message is being flushed.
+func (b *Machine) needsRekey() bool {
+	var p = b.rekey
+
+	switch {
+	case p == nil:
+		return false
+	case len(b.nextHeaderSend) > 0 || len(b.nextBodySend) > 0:
+		return false
+	case p.Interval > 0 && time.Since(b.sendKeyAt) >= p.Interval:
+		return true
+	case p.Volume > 0 && b.sendBytes >= p.Volume:
+		return true
+	case p.Messages > 0 && b.sendCipher.used >= p.Messages:
+		return true
+	case b.sendCipher.used >= maxMessagesPerKey/2:
+		return true
+	}
+
+	return false
+}
+
+// rekey replaces the key and the salt with ones derived from the current salt
+// and a fresh shared secret.
+func (c *cipherState) rekey(secret []byte) {
+	var nextKey [32]byte
+
+	h := hkdf.New(sha256.New, secret, c.salt[:], []byte("rekey"))
+	h.Read(c.salt[:])
+	h.Read(nextKey[:])
+
+	c.InitializeKey(nextKey)
+	c.used = 0
+}
@@ -0,0 +1,104 @@
+package brontide
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+func connPair(t *testing.T) (*Conn, *Conn) {
+	var left, right = net.Pipe()
+	var leftKey, _ = btcec.NewPrivateKey()
+	var rightKey, _ = btcec.NewPrivateKey()
+
+	var passive = make(chan *Conn, 1)
+	go func() {
+		c, err := PassiveHandshake(right, rightKey)
+		if err != nil {
+			t.Error(err)
+		}
+		passive <- c
+	}()
+
+	active, err := ActiveHandshake(left, leftKey, rightKey.PubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return active, <-passive
+}
+
+func TestRekey(t *testing.T) {
+	var active, passive = connPair(t)
+	defer active.Close()
+	defer passive.Close()
+
+	var policy = RekeyPolicy{Messages: 6}
+	active.EnableRekey(policy)
+	passive.EnableRekey(policy)
+
+	var initialKey = active.noise.sendCipher.secretKey
+	var expected = &bytes.Buffer{}
+	var received = &bytes.Buffer{}
+
+	var done = make(chan error, 1)
+	go func() {
+		var buf = make([]byte, 64)
+		for received.Len() < 1000 {
+			n, err := passive.Read(buf)
+			if err != nil {
+				done <- err
+				return
+			}
+			received.Write(buf[:n])
+		}
+		done <- nil
+	}()
+
+	for i := 0; expected.Len() < 1000; i++ {
+		var msg = []byte(fmt.Sprintf("message %d;", i))
+		expected.Write(msg)
+		if _, err := active.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := <-done; err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected.Bytes()[:1000], received.Bytes()[:1000]) {
+		t.Fatal("received data doesn't match sent data")
+	}
+
+	if active.noise.sendCipher.secretKey == initialKey {
+		t.Fatal("keys were not replaced")
+	}
+
+	if active.noise.sendCipher.secretKey != passive.noise.recvCipher.secretKey {
+		t.Fatal("parties derived different keys")
+	}
+}
+
+func TestRekeyMessageCount(t *testing.T) {
+	var active, passive = connPair(t)
+	defer active.Close()
+	defer passive.Close()
+
+	go io.Copy(io.Discard, passive)
+
+	for i := 0; i < 3; i++ {
+		if _, err := active.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the length and the body of a message are encrypted separately, but count as one message
+	if used := active.noise.sendCipher.used; used != 3 {
+		t.Fatalf("counted %d messages, expected 3", used)
+	}
+}
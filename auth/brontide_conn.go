@@ -45,3 +45,14 @@ func (conn *NoiseConn) LocalIdentity() id.Identity {
 func (conn *NoiseConn) RemoteIdentity() id.Identity {
 	return id.PublicKey(conn.brontide.RemotePub())
 }
+
+// EnableRekey enables periodic replacement of the session keys. Both parties must enable it at the same point
+// of the stream.
+func (conn *NoiseConn) EnableRekey(policy brontide.RekeyPolicy) {
+	conn.brontide.EnableRekey(policy)
+}
+
+// Pattern returns the name of the noise pattern used for the handshake
+func (conn *NoiseConn) Pattern() string {
+	return conn.brontide.Pattern()
+}
@@ -9,25 +9,19 @@ import (
 
 // HandshakeInbound performs a handshake as the passive party.
 func HandshakeInbound(ctx context.Context, conn net.Conn, localID id.Identity) (*NoiseConn, error) {
-	//TODO: is there a better way to handle ctx here?
-	var done = make(chan struct{})
-	var errCh = make(chan error, 1)
-	defer close(done)
-	go func() {
-		select {
-		case <-ctx.Done():
-			errCh <- ctx.Err()
-			conn.Close()
-		case <-done:
-		}
-	}()
+	return HandshakeInboundIK(ctx, conn, localID, nil)
+}
 
-	bConn, err := brontide.PassiveHandshake(conn, localID.PrivateKey())
-	select {
-	case err := <-errCh:
-		return nil, err
-	default:
-	}
+// HandshakeInboundIK performs a handshake as the passive party accepting both XK and IK handshakes. Respond is
+// called with the payload of an IK handshake and returns the payload of the response. If respond is nil, only
+// XK handshakes are accepted.
+func HandshakeInboundIK(ctx context.Context, conn net.Conn, localID id.Identity, respond brontide.PayloadResponder) (*NoiseConn, error) {
+	var bConn *brontide.Conn
+
+	err := withContext(ctx, conn, func() (err error) {
+		bConn, err = brontide.PassiveHandshakeIK(conn, localID.PrivateKey(), respond)
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +34,45 @@ func HandshakeInbound(ctx context.Context, conn net.Conn, localID id.Identity) (
 
 // HandshakeOutbound performs a handshake as the active party.
 func HandshakeOutbound(ctx context.Context, conn net.Conn, expectedRemoteID id.Identity, localID id.Identity) (*NoiseConn, error) {
+	var bConn *brontide.Conn
+
+	err := withContext(ctx, conn, func() (err error) {
+		bConn, err = brontide.ActiveHandshake(conn, localID.PrivateKey(), expectedRemoteID.PublicKey())
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &NoiseConn{
+		conn:     conn,
+		brontide: bConn,
+	}, nil
+}
+
+// HandshakeOutboundIK performs an IK handshake as the active party. It takes a single round trip, but the
+// remote party has to support it. The payload is sent along with the handshake and the payload of the
+// response is returned.
+func HandshakeOutboundIK(ctx context.Context, conn net.Conn, expectedRemoteID id.Identity, localID id.Identity, payload []byte) (*NoiseConn, []byte, error) {
+	var bConn *brontide.Conn
+	var response []byte
+
+	err := withContext(ctx, conn, func() (err error) {
+		bConn, response, err = brontide.ActiveHandshakeIK(conn, localID.PrivateKey(), expectedRemoteID.PublicKey(), payload)
+		return
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &NoiseConn{
+		conn:     conn,
+		brontide: bConn,
+	}, response, nil
+}
+
+// withContext runs the handshake closing the connection if the context is done first
+func withContext(ctx context.Context, conn net.Conn, handshake func() error) error {
 	//TODO: is there a better way to handle ctx here?
 	var done = make(chan struct{})
 	var errCh = make(chan error, 1)
@@ -52,18 +85,13 @@ func HandshakeOutbound(ctx context.Context, conn net.Conn, expectedRemoteID id.I
 		case <-done:
 		}
 	}()
-	c, err := brontide.ActiveHandshake(conn, localID.PrivateKey(), expectedRemoteID.PublicKey())
+
+	err := handshake()
 	select {
 	case err := <-errCh:
-		return nil, err
+		return err
 	default:
 	}
-	if err != nil {
-		return nil, err
-	}
 
-	return &NoiseConn{
-		conn:     conn,
-		brontide: c,
-	}, nil
+	return err
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+const defaultSocket = "~/.astrald-pipe.sock"
+
+// astrald-pipe connects its stdin and stdout to the pipe driver of the local node, so that a remote node can
+// link to it over any byte stream, e.g. `ssh host astrald-pipe`.
+func main() {
+	var socket = flag.String("socket", defaultSocket, "path to the pipe driver socket")
+	flag.Parse()
+
+	var path = *socket
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error connecting to %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	var done = make(chan struct{}, 2)
+
+	go func() {
+		io.Copy(conn, os.Stdin)
+		conn.(*net.UnixConn).CloseWrite()
+		done <- struct{}{}
+	}()
+
+	go func() {
+		io.Copy(os.Stdout, conn)
+		done <- struct{}{}
+	}()
+
+	// exit as soon as either side is done, since the link is unusable without both directions
+	<-done
+	conn.Close()
+}
@@ -6,5 +6,9 @@ import (
 	_ "github.com/cryptopunkscc/astrald/node/infra/drivers/bt"
 	_ "github.com/cryptopunkscc/astrald/node/infra/drivers/gw"
 	_ "github.com/cryptopunkscc/astrald/node/infra/drivers/inet"
+	_ "github.com/cryptopunkscc/astrald/node/infra/drivers/pipe"
 	_ "github.com/cryptopunkscc/astrald/node/infra/drivers/tor"
+	_ "github.com/cryptopunkscc/astrald/node/infra/drivers/udp"
+	_ "github.com/cryptopunkscc/astrald/node/infra/drivers/unix"
+	_ "github.com/cryptopunkscc/astrald/node/infra/drivers/ws"
 )
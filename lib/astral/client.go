@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/mod/apphost/proto"
+	astralnet "github.com/cryptopunkscc/astrald/net"
 	"math/rand"
 	"os"
 	"strings"
@@ -63,6 +64,21 @@ func (c *ApphostClient) Query(remoteID id.Identity, query string) (conn *Conn, e
 	return s.Query(remoteID, query)
 }
 
+// QueryArgs sends a query with key/value arguments
+func (c *ApphostClient) QueryArgs(remoteID id.Identity, query string, args astralnet.QueryArgs) (conn *Conn, err error) {
+	return c.QueryExt(remoteID, query, QueryOptions{Args: args})
+}
+
+// QueryExt sends a query with arguments and routing options
+func (c *ApphostClient) QueryExt(remoteID id.Identity, query string, opts QueryOptions) (conn *Conn, err error) {
+	s, err := c.Session()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.QueryExt(remoteID, query, opts)
+}
+
 func (c *ApphostClient) QueryName(name string, query string) (conn *Conn, err error) {
 	identity, err := c.Resolve(name)
 	if err != nil {
@@ -141,6 +157,14 @@ func Query(remoteID id.Identity, query string) (*Conn, error) {
 	return Client.Query(remoteID, query)
 }
 
+func QueryArgs(remoteID id.Identity, query string, args astralnet.QueryArgs) (*Conn, error) {
+	return Client.QueryArgs(remoteID, query, args)
+}
+
+func QueryExt(remoteID id.Identity, query string, opts QueryOptions) (*Conn, error) {
+	return Client.QueryExt(remoteID, query, opts)
+}
+
 func QueryName(name string, query string) (conn *Conn, err error) {
 	return Client.QueryName(name, query)
 }
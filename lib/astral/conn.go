@@ -2,6 +2,7 @@ package astral
 
 import (
 	"github.com/cryptopunkscc/astrald/auth/id"
+	astralnet "github.com/cryptopunkscc/astrald/net"
 	"net"
 )
 
@@ -11,6 +12,7 @@ type Conn struct {
 	net.Conn
 	remoteID id.Identity
 	query    string
+	args     astralnet.QueryArgs
 }
 
 func (conn Conn) RemoteIdentity() id.Identity {
@@ -24,3 +26,8 @@ func (conn Conn) RemoteAddr() net.Addr {
 func (conn Conn) Query() string {
 	return conn.query
 }
+
+// Args returns the arguments passed with the query
+func (conn Conn) Args() astralnet.QueryArgs {
+	return conn.args
+}
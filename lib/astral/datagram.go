@@ -0,0 +1,89 @@
+package astral
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/apphost/proto"
+)
+
+// DatagramListener receives datagrams sent to a service
+type DatagramListener struct {
+	session *Session
+	service string
+}
+
+// Receive waits for the next datagram and returns its sender and payload
+func (l *DatagramListener) Receive() (identity id.Identity, data []byte, err error) {
+	var d proto.InDatagram
+
+	if err = l.session.conn.ReadMsg(&d); err != nil {
+		return
+	}
+
+	return d.Identity, d.Data, nil
+}
+
+// Service returns the name of the service the listener receives datagrams for
+func (l *DatagramListener) Service() string {
+	return l.service
+}
+
+// Close stops receiving datagrams
+func (l *DatagramListener) Close() error {
+	return l.session.Close()
+}
+
+func (s *Session) SendDatagram(remoteID id.Identity, service string, data []byte) (err error) {
+	if err = s.auth(); err != nil {
+		return
+	}
+
+	return s.invoke(proto.CmdSendDatagram, proto.SendDatagramParams{
+		Identity: remoteID,
+		Service:  service,
+		Data:     data,
+	})
+}
+
+func (s *Session) Datagrams(service string) (l *DatagramListener, err error) {
+	if err = s.auth(); err != nil {
+		s.Close()
+		return
+	}
+
+	err = s.invoke(proto.CmdDatagrams, proto.DatagramsParams{Service: service})
+	if err != nil {
+		s.Close()
+		return
+	}
+
+	return &DatagramListener{session: s, service: service}, nil
+}
+
+// SendDatagram sends an unreliable datagram to a service of the remote identity
+func (c *ApphostClient) SendDatagram(remoteID id.Identity, service string, data []byte) error {
+	s, err := c.Session()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return s.SendDatagram(remoteID, service, data)
+}
+
+// Datagrams starts receiving datagrams sent to the service
+func (c *ApphostClient) Datagrams(service string) (*DatagramListener, error) {
+	s, err := c.Session()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Datagrams(service)
+}
+
+func SendDatagram(remoteID id.Identity, service string, data []byte) error {
+	return Client.SendDatagram(remoteID, service, data)
+}
+
+func Datagrams(service string) (*DatagramListener, error) {
+	return Client.Datagrams(service)
+}
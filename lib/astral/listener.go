@@ -3,6 +3,7 @@ package astral
 import (
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/mod/apphost/proto"
+	astralnet "github.com/cryptopunkscc/astrald/net"
 	"net"
 )
 
@@ -36,9 +37,12 @@ func (l *Listener) Next() (*QueryData, error) {
 		return nil, err
 	}
 
+	var query, args = astralnet.ParseQueryString(in.Query)
+
 	q := &QueryData{
 		conn:     conn,
-		query:    in.Query,
+		query:    query,
+		args:     args,
 		remoteID: in.Identity,
 	}
 
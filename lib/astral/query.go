@@ -4,12 +4,23 @@ import (
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/mod/apphost/proto"
+	astralnet "github.com/cryptopunkscc/astrald/net"
 	"net"
 )
 
+// QueryOptions holds optional parameters of an outgoing query
+type QueryOptions struct {
+	// Args are the key/value arguments of the query
+	Args astralnet.QueryArgs
+
+	// Multipath spreads the traffic of the query across all available links to the target node
+	Multipath bool
+}
+
 type QueryData struct {
 	conn     net.Conn
 	query    string
+	args     astralnet.QueryArgs
 	remoteID id.Identity
 }
 
@@ -17,6 +28,11 @@ func (q *QueryData) Query() string {
 	return q.query
 }
 
+// Args returns the arguments passed with the query
+func (q *QueryData) Args() astralnet.QueryArgs {
+	return q.args
+}
+
 func (q *QueryData) RemoteIdentity() id.Identity {
 	return q.remoteID
 }
@@ -31,6 +47,7 @@ func (q *QueryData) Accept() (*Conn, error) {
 		Conn:     q.conn,
 		remoteID: q.remoteID,
 		query:    q.query,
+		args:     q.args,
 	}
 
 	if err := cslq.Encode(q.conn, "c", proto.Success); err != nil {
@@ -46,6 +46,37 @@ func (s *Session) Query(remoteID id.Identity, query string) (conn *Conn, err err
 	}, nil
 }
 
+// QueryExt sends a query with arguments and routing options
+func (s *Session) QueryExt(remoteID id.Identity, query string, opts QueryOptions) (conn *Conn, err error) {
+	if err = s.auth(); err != nil {
+		s.Close()
+		return
+	}
+
+	var flags int
+	if opts.Multipath {
+		flags |= proto.FlagMultipath
+	}
+
+	err = s.invoke(proto.CmdQueryExt, proto.QueryExtParams{
+		Identity: remoteID,
+		Query:    query,
+		Args:     opts.Args.Pairs(),
+		Flags:    flags,
+	})
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		Conn:     s.conn,
+		remoteID: remoteID,
+		query:    query,
+		args:     opts.Args,
+	}, nil
+}
+
 func (s *Session) Resolve(name string) (identity id.Identity, err error) {
 	if err = s.auth(); err != nil {
 		return
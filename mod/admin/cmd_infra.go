@@ -0,0 +1,129 @@
+package admin
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"sort"
+	"strings"
+)
+
+var _ Command = &CmdInfra{}
+
+type CmdInfra struct {
+	mod *Module
+}
+
+func (cmd *CmdInfra) Exec(term *Terminal, args []string) error {
+	if len(args) < 2 {
+		return cmd.help(term)
+	}
+
+	switch args[1] {
+	case "list":
+		return cmd.list(term, args[2:])
+
+	case "enable":
+		return cmd.enable(term, args[2:])
+
+	case "disable":
+		return cmd.disable(term, args[2:])
+
+	case "set":
+		return cmd.set(term, args[2:])
+
+	case "reload":
+		return cmd.reload(term, args[2:])
+
+	case "help":
+		return cmd.help(term)
+
+	default:
+		return errors.New("invalid command")
+	}
+}
+
+func (cmd *CmdInfra) list(term *Terminal, _ []string) error {
+	var loaded = cmd.mod.node.Infra().LoadedDrivers()
+	var enabled = cmd.mod.node.Infra().Drivers()
+
+	var names = make([]string, 0, len(loaded))
+	for name := range loaded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var f = "%-10s %-10s %s\n"
+	term.Printf(f, Header("Driver"), Header("Status"), Header("Endpoints"))
+	for _, name := range names {
+		var status any = Faded("disabled")
+		var endpoints []string
+
+		if _, found := enabled[name]; found {
+			status = "enabled"
+
+			if lister, ok := loaded[name].(infra.EndpointLister); ok {
+				for _, e := range lister.Endpoints() {
+					endpoints = append(endpoints, e.String())
+				}
+			}
+		}
+
+		term.Printf(f, name, status, strings.Join(endpoints, " "))
+	}
+
+	return nil
+}
+
+func (cmd *CmdInfra) enable(term *Terminal, args []string) error {
+	if len(args) < 1 {
+		term.Println("usage: infra enable <driver>")
+		return errors.New("missing arguments")
+	}
+
+	return cmd.mod.node.Infra().EnableDriver(args[0])
+}
+
+func (cmd *CmdInfra) disable(term *Terminal, args []string) error {
+	if len(args) < 1 {
+		term.Println("usage: infra disable <driver>")
+		return errors.New("missing arguments")
+	}
+
+	return cmd.mod.node.Infra().DisableDriver(args[0])
+}
+
+func (cmd *CmdInfra) set(term *Terminal, args []string) error {
+	if len(args) < 3 {
+		term.Println("usage: infra set <driver> <key> <value>")
+		return errors.New("missing arguments")
+	}
+
+	return cmd.mod.node.Infra().ConfigureDriver(args[0], map[string]string{
+		args[1]: strings.Join(args[2:], " "),
+	})
+}
+
+func (cmd *CmdInfra) reload(term *Terminal, args []string) error {
+	if len(args) < 1 {
+		term.Println("usage: infra reload <driver>")
+		return errors.New("missing arguments")
+	}
+
+	return cmd.mod.node.Infra().ReloadDriver(args[0])
+}
+
+func (cmd *CmdInfra) help(term *Terminal) error {
+	term.Printf("help: infra <command> [options]\n\n")
+	term.Printf("commands:\n")
+	term.Printf("  list                           list network drivers\n")
+	term.Printf("  enable <driver>                start a driver and enable it on startup\n")
+	term.Printf("  disable <driver>               stop a driver and disable it on startup\n")
+	term.Printf("  set <driver> <key> <value>     change driver's config and reload it\n")
+	term.Printf("  reload <driver>                reload driver's config and restart it\n")
+	term.Printf("  help                           show help\n")
+	return nil
+}
+
+func (cmd *CmdInfra) ShortDescription() string {
+	return "manage network drivers"
+}
@@ -56,6 +56,9 @@ func (cmd *CmdNet) Exec(term *Terminal, args []string) error {
 	case "check":
 		return cmd.check(term, args[2:])
 
+	case "sessions":
+		return cmd.sessions(term, args[2:])
+
 	case "help":
 		return cmd.help(term)
 
@@ -289,6 +292,9 @@ func (cmd *CmdNet) show(term *Terminal, args []string) error {
 		term.Printf("Remote endpoint:  %v\n", t.RemoteEndpoint())
 		term.Printf("Outbound:         %v\n", t.Outbound())
 	}
+	if l, ok := l.Link.(*link.CoreLink); ok {
+		term.Printf("Features:         %v\n", strings.Join(l.Features(), " "))
+	}
 	if l, ok := l.Link.(checkLatency); ok {
 		term.Printf("Latency:          %v\n", l.Latency().Round(time.Millisecond))
 	}
@@ -299,6 +305,45 @@ func (cmd *CmdNet) show(term *Terminal, args []string) error {
 	if idler, ok := l.Link.(sig.Idler); ok {
 		term.Printf("Idle:             %v\n", idler.Idle().Round(time.Second))
 	}
+
+	var stats = l.Stats()
+	term.Printf("Sent:             %v (%d frames)\n", log.DataSize(stats.BytesSent).HumanReadable(), stats.FramesSent)
+	term.Printf("Received:         %v (%d frames)\n", log.DataSize(stats.BytesReceived).HumanReadable(), stats.FramesReceived)
+	term.Printf("Queued:           %v\n", log.DataSize(stats.Queued).HumanReadable())
+	term.Printf("Windows:          %v\n", log.DataSize(stats.Window).HumanReadable())
+	if len(stats.RTT) > 0 {
+		var rtts []string
+		for _, s := range stats.RTT {
+			rtts = append(rtts, s.RTT.Round(time.Millisecond).String())
+		}
+		term.Printf("RTT history:      %v\n", strings.Join(rtts, " "))
+	}
+
+	if len(stats.Ports) == 0 {
+		return nil
+	}
+
+	var f = "%-6d %-6d %-20s %10s %10s %10s %10s %10s %10s\n"
+
+	term.Printf("\n")
+	term.Printf(f,
+		Header("Port"), Header("Remote"), Header("Query"), Header("Sent"), Header("Received"),
+		Header("Window"), Header("Buffered"), Header("RWindow"), Header("Queued"),
+	)
+	for _, p := range stats.Ports {
+		term.Printf(f,
+			p.Port,
+			p.RemotePort,
+			p.Query,
+			log.DataSize(p.BytesSent).HumanReadable(),
+			log.DataSize(p.BytesReceived).HumanReadable(),
+			log.DataSize(p.Window).HumanReadable(),
+			log.DataSize(p.Buffered).HumanReadable(),
+			log.DataSize(p.RemoteWindow).HumanReadable(),
+			log.DataSize(p.Queued).HumanReadable(),
+		)
+	}
+
 	return nil
 }
 
@@ -322,9 +367,12 @@ func (cmd *CmdNet) close(term *Terminal, args []string) error {
 }
 
 func (cmd *CmdNet) links(term *Terminal, _ []string) error {
-	var f = "%-8d %-24s %-8s %10s %10s %10s\n"
+	var f = "%-8d %-24s %-8s %10s %10s %10s %10s %10s %6d\n"
 
-	term.Printf(f, Header("ID"), Header("Remote"), Header("Net"), Header("Idle"), Header("Age"), Header("Ping"))
+	term.Printf(f,
+		Header("ID"), Header("Remote"), Header("Net"), Header("Idle"), Header("Age"), Header("Ping"),
+		Header("Sent"), Header("Received"), Header("Ports"),
+	)
 	for _, l := range cmd.mod.node.Network().Links().All() {
 		if l == nil {
 			term.Printf("[nil link]\n")
@@ -341,6 +389,8 @@ func (cmd *CmdNet) links(term *Terminal, _ []string) error {
 			lat = l.Latency()
 		}
 
+		var stats = l.Stats()
+
 		term.Printf(f,
 			l.ID(),
 			l.RemoteIdentity(),
@@ -348,6 +398,36 @@ func (cmd *CmdNet) links(term *Terminal, _ []string) error {
 			idle,
 			time.Since(l.AddedAt()).Round(time.Second),
 			lat.Round(time.Millisecond),
+			log.DataSize(stats.BytesSent).HumanReadable(),
+			log.DataSize(stats.BytesReceived).HumanReadable(),
+			len(stats.Ports),
+		)
+	}
+
+	return nil
+}
+
+func (cmd *CmdNet) sessions(term *Terminal, _ []string) error {
+	var f = "%-32s %-24s %-20s %-8s %10s %10s\n"
+
+	term.Printf(f, Header("ID"), Header("Remote"), Header("Query"), Header("Net"), Header("Unacked"), Header("Age"))
+	for _, s := range cmd.mod.node.Network().Sessions().All() {
+		var networks []string
+		for _, l := range s.Links() {
+			networks = append(networks, net.Network(l))
+		}
+		var network = "-"
+		if len(networks) > 0 {
+			network = strings.Join(networks, ",")
+		}
+
+		term.Printf(f,
+			s.ID(),
+			s.Identity(),
+			s.Query(),
+			Keyword(network),
+			log.DataSize(s.Unacked()).HumanReadable(),
+			time.Since(s.CreatedAt()).Round(time.Second),
 		)
 	}
 
@@ -376,6 +456,7 @@ func (cmd *CmdNet) help(term *Terminal) error {
 	term.Printf("  link      link a node\n")
 	term.Printf("  unlink    unlink a node\n")
 	term.Printf("  conns     list all connections\n")
+	term.Printf("  sessions  list all sessions\n")
 	term.Printf("  check     run health check on all links\n")
 	term.Printf("  help      show help\n")
 	return nil
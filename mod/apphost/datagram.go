@@ -0,0 +1,81 @@
+package apphost
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/mod/apphost/proto"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/services"
+	"github.com/cryptopunkscc/astrald/streams"
+	"io"
+	"sync"
+)
+
+func (s *Session) sendDatagram(p proto.SendDatagramParams) error {
+	var d = net.Datagram{
+		Caller:  s.remoteID,
+		Target:  p.Identity,
+		Service: p.Service,
+		Data:    p.Data,
+	}
+
+	if d.Target.IsZero() {
+		d.Target = s.mod.node.Identity()
+	}
+
+	if len(d.Data) > net.MaxDatagramSize {
+		return s.WriteErr(proto.ErrFailed)
+	}
+
+	// datagrams to local services don't leave the node
+	if d.Target.IsEqual(s.mod.node.Identity()) {
+		s.mod.node.Services().HandleDatagram(d)
+		return s.WriteErr(nil)
+	}
+
+	err := s.mod.node.Network().SendDatagram(d)
+	switch {
+	case err == nil:
+		return s.WriteErr(nil)
+	case errors.Is(err, &net.ErrRouteNotFound{}):
+		return s.WriteErr(proto.ErrRouteNotFound)
+	default:
+		return s.WriteErr(proto.ErrFailed)
+	}
+}
+
+func (s *Session) datagrams(p proto.DatagramsParams) error {
+	s.mod.log.Logv(2, "%s datagrams %s", s.remoteID, p.Service)
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	defer s.Close()
+
+	var mu sync.Mutex
+	var handler = net.DatagramHandlerFunc(func(d net.Datagram) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err := s.WriteMsg(proto.InDatagram{Identity: d.Caller, Data: d.Data}); err != nil {
+			s.Close()
+		}
+	})
+
+	err := s.mod.node.Services().RegisterDatagram(ctx, s.remoteID, p.Service, handler)
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrAlreadyRegistered):
+		return s.WriteErr(proto.ErrAlreadyRegistered)
+	default:
+		return s.WriteErr(proto.ErrUnexpected)
+	}
+
+	mu.Lock()
+	s.WriteErr(nil)
+	mu.Unlock()
+
+	// deliver datagrams until the other party closes the session
+	io.Copy(streams.NilWriter{}, s)
+
+	return nil
+}
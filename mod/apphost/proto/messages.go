@@ -7,9 +7,13 @@ import (
 const (
 	CmdRegister = "register"
 	CmdQuery    = "query"
+	CmdQueryExt = "queryExt"
 	CmdResolve  = "resolve"
 	CmdNodeInfo = "nodeInfo"
 	CmdExec     = "exec"
+
+	CmdSendDatagram = "sendDatagram"
+	CmdDatagrams    = "datagrams"
 )
 
 type Command struct {
@@ -25,6 +29,20 @@ type QueryParams struct {
 	Query    string      `cslq:"[c]c"`
 }
 
+// QueryExtParams is a query with key/value arguments and routing flags. Args is a flat list of keys and values.
+type QueryExtParams struct {
+	Identity id.Identity `cslq:"v"`
+	Query    string      `cslq:"[s]c"`
+	Args     []string    `cslq:"[s][s]c"`
+	Flags    int         `cslq:"c"`
+}
+
+// query flags
+const (
+	// FlagMultipath spreads the traffic of the query across all available links to the target
+	FlagMultipath = 1 << iota
+)
+
 type RegisterParams struct {
 	Service string `cslq:"[c]c"`
 	Target  string `cslq:"[c]c"`
@@ -58,3 +76,18 @@ type ResolveParams struct {
 type ResolveData struct {
 	Identity id.Identity `cslq:"v"`
 }
+
+type SendDatagramParams struct {
+	Identity id.Identity `cslq:"v"`
+	Service  string      `cslq:"[c]c"`
+	Data     []byte      `cslq:"[s]c"`
+}
+
+type DatagramsParams struct {
+	Service string `cslq:"[c]c"`
+}
+
+type InDatagram struct {
+	Identity id.Identity `cslq:"v"`
+	Data     []byte      `cslq:"[s]c"`
+}
@@ -0,0 +1,118 @@
+package apphost
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/lib/astral"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	gonet "net"
+	"testing"
+)
+
+type testNode struct {
+	node.Node
+	identity id.Identity
+	router   net.Router
+}
+
+func (n *testNode) Identity() id.Identity { return n.identity }
+func (n *testNode) Router() net.Router    { return n.router }
+
+type testKeys struct {
+	assets.KeyStore
+}
+
+func (testKeys) Find(identity id.Identity) (id.Identity, error) { return identity, nil }
+
+// testRouter accepts every query and records the hints it was routed with
+type testRouter struct {
+	queries chan net.Query
+	hints   chan net.Hints
+}
+
+func (r *testRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	r.queries <- query
+	r.hints <- hints
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		conn.Close()
+	})
+}
+
+func TestQueryExt(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	nodeID, _ := id.GenerateIdentity()
+	appID, _ := id.GenerateIdentity()
+
+	var router = &testRouter{
+		queries: make(chan net.Query, 1),
+		hints:   make(chan net.Hints, 1),
+	}
+	var mod = &Module{
+		node:   &testNode{identity: nodeID, router: router},
+		keys:   testKeys{},
+		log:    log.NewLogger(log.NewPrinterSplitter()),
+		tokens: map[string]id.Identity{},
+	}
+	var token = mod.createToken(appID)
+
+	l, err := gonet.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go NewSession(mod, conn, mod.log).Serve(ctx)
+		}
+	}()
+
+	var client = astral.NewClient("tcp:"+l.Addr().String(), token)
+
+	conn, err := client.QueryExt(nodeID, "test?service", astral.QueryOptions{
+		Args:      net.QueryArgs{"key": "value"},
+		Multipath: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	query, hints := <-router.queries, <-router.hints
+	if !hints.Multipath {
+		t.Fatal("expected the multipath hint to be set")
+	}
+	if query.Query() != "test?service" {
+		t.Fatalf("unexpected query %q", query.Query())
+	}
+	if query.Args().Get("key") != "value" {
+		t.Fatalf("unexpected args %v", query.Args())
+	}
+	if !query.Caller().IsEqual(appID) {
+		t.Fatal("unexpected caller")
+	}
+
+	// plain queries don't use multipath and are passed as is
+	conn, err = client.Query(nodeID, "test?key=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	query, hints = <-router.queries, <-router.hints
+	if hints.Multipath {
+		t.Fatal("expected the multipath hint to be unset")
+	}
+	if query.Query() != "test?key=value" || len(query.Args()) != 0 {
+		t.Fatalf("plain query was changed to %q %v", query.Query(), query.Args())
+	}
+}
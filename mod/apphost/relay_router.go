@@ -26,7 +26,7 @@ func (fwd *RelayRouter) RouteQuery(ctx context.Context, query net.Query, caller
 
 	err = conn.WriteMsg(proto.InQueryParams{
 		Identity: query.Caller(),
-		Query:    query.Query(),
+		Query:    net.QueryString(query.Query(), query.Args()),
 	})
 	if err != nil {
 		return nil, err
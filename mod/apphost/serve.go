@@ -3,6 +3,7 @@ package apphost
 import (
 	"context"
 	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/mod/apphost/proto"
 	routerpc "github.com/cryptopunkscc/astrald/mod/route/proto"
@@ -26,6 +27,9 @@ func (s *Session) Serve(ctx context.Context) error {
 		case proto.CmdQuery:
 			return cslq.Invoke(s, s.query)
 
+		case proto.CmdQueryExt:
+			return cslq.Invoke(s, s.queryExt)
+
 		case proto.CmdResolve:
 			return cslq.Invoke(s, s.resolve)
 
@@ -38,23 +42,45 @@ func (s *Session) Serve(ctx context.Context) error {
 		case proto.CmdExec:
 			return cslq.Invoke(s, s.exec)
 
+		case proto.CmdSendDatagram:
+			return cslq.Invoke(s, s.sendDatagram)
+
+		case proto.CmdDatagrams:
+			return cslq.Invoke(s, s.datagrams)
+
 		default:
 			return s.WriteErr(proto.ErrUnknownCommand)
 		}
 	})
 }
 
+// query routes a plain query. The query string is passed as is, apps send arguments with queryExt.
 func (s *Session) query(params proto.QueryParams) error {
-	var err error
-	var targetWriter net.SecureConn
+	return s.routeQuery(params.Identity, params.Query, nil, net.Hints{Origin: net.OriginLocal})
+}
+
+func (s *Session) queryExt(params proto.QueryExtParams) error {
+	args, err := net.QueryArgsFromPairs(params.Args)
+	if err != nil {
+		return s.WriteErr(proto.ErrFailed)
+	}
+
+	var hints = net.Hints{
+		Origin:    net.OriginLocal,
+		Multipath: params.Flags&proto.FlagMultipath != 0,
+	}
+
+	return s.routeQuery(params.Identity, params.Query, args, hints)
+}
 
-	if params.Identity.IsZero() {
-		params.Identity = s.mod.node.Identity() //TODO: by default call your own service, not relay's
+func (s *Session) routeQuery(target id.Identity, name string, args net.QueryArgs, hints net.Hints) error {
+	if target.IsZero() {
+		target = s.mod.node.Identity() //TODO: by default call your own service, not relay's
 	}
 
-	q := net.NewQuery(s.remoteID, params.Identity, params.Query)
+	q := net.NewQueryWithArgs(s.remoteID, target, name, args)
 
-	targetWriter, err = net.Route(s.ctx, s.mod.node.Router(), q)
+	targetWriter, err := net.RouteWithHints(s.ctx, s.mod.node.Router(), q, hints)
 
 	if err == nil {
 		s.WriteErr(nil)
@@ -61,7 +61,13 @@ func (module *Gateway) handleQuery(conn net.SecureConn) error {
 		return err
 	}
 
-	out, err := net.Route(module.ctx, module.node.Network(), net.NewQuery(module.node.Identity(), nodeID, queryConnect))
+	// stop routing the query if the caller goes away
+	ctx, cancel := context.WithCancel(module.ctx)
+	defer cancel()
+	var input = streams.WatchReader(conn, cancel)
+	defer input.Close()
+
+	out, err := net.Route(ctx, module.node.Network(), net.NewQuery(module.node.Identity(), nodeID, queryConnect))
 	if err != nil {
 		conn.Close()
 		return err
@@ -69,7 +75,7 @@ func (module *Gateway) handleQuery(conn net.SecureConn) error {
 
 	c.Encodef("c", true)
 
-	l, r, err := streams.Join(conn, out)
+	l, r, err := streams.Join(streams.ReadWriteCloseSplit{Reader: input, Writer: conn, Closer: conn}, out)
 
 	module.log.Logv(1, "conn for %s done (bytes read %d written %d)", nodeID, l, r)
 
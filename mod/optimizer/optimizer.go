@@ -3,13 +3,14 @@ package optimizer
 import (
 	"context"
 	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node"
 	"github.com/cryptopunkscc/astrald/node/events"
-	"github.com/cryptopunkscc/astrald/node/link"
 	"github.com/cryptopunkscc/astrald/node/network"
 	"github.com/cryptopunkscc/astrald/node/tracker"
+	"sync"
 	"time"
 )
 
@@ -17,41 +18,53 @@ const optimizeDuration = 60 * time.Second
 const concurrency = 8
 
 type Module struct {
-	node node.Node
-	log  *log.Logger
+	node       node.Node
+	log        *log.Logger
+	optimizing sync.Map // identities of nodes being optimized
 }
 
 func (mod *Module) Run(ctx context.Context) error {
-	return events.Handle(ctx, mod.node.Events(), func(ctx context.Context, event network.EventPeerLinked) error {
+	return events.Handle(ctx, mod.node.Events(), func(ctx context.Context, event network.EventLinkAdded) error {
+		var nodeID = event.Link.RemoteIdentity()
+
+		// only optimize nodes that were just linked and are not optimized already
+		if mod.node.Network().Links().ByRemoteIdentity(nodeID).Count() > 1 {
+			return nil
+		}
+		if _, found := mod.optimizing.LoadOrStore(nodeID.PublicKeyHex(), struct{}{}); found {
+			return nil
+		}
+
 		go func() {
-			nodeId := event.Peer.Identity()
-			mod.log.Log("optimizing %s", nodeId)
-			if err := mod.Optimize(ctx, event.Peer); err != nil {
-				mod.log.Error("optimize %s: %s", nodeId, err)
+			defer mod.optimizing.Delete(nodeID.PublicKeyHex())
+
+			mod.log.Log("optimizing %s", nodeID)
+			if err := mod.Optimize(ctx, nodeID); err != nil {
+				mod.log.Error("optimize %s: %s", nodeID, err)
 			} else {
-				mod.log.Log("done optimizing %s", nodeId)
+				mod.log.Log("done optimizing %s", nodeID)
 			}
 		}()
 		return nil
 	})
 }
 
-func (mod *Module) Optimize(parent context.Context, peer *network.Peer) error {
+func (mod *Module) Optimize(parent context.Context, nodeID id.Identity) error {
 	ctx, cancel := context.WithTimeout(parent, optimizeDuration)
+	defer cancel()
 
-	// optimize until peer gets unlinked or optimization period ends
-	go func() {
-		select {
-		case <-peer.Done():
-		case <-parent.Done():
+	// optimize until the node gets unlinked or optimization period ends
+	go events.Handle(ctx, mod.node.Events(), func(ctx context.Context, e network.EventLinkRemoved) error {
+		if e.Link.RemoteIdentity().IsEqual(nodeID) && mod.node.Network().Links().ByRemoteIdentity(nodeID).Count() == 0 {
+			cancel()
 		}
-		cancel()
-	}()
+		return nil
+	})
 
 	// use FilterDialer to dial only addresses with potentially better quality score
 	filterDialer := NewFilterDialer(mod.node.Infra(), func(addr net.Endpoint) error {
-		sa := scoreAddr(addr)
-		sp := scorePeer(peer)
+		sa := mod.scoreAddr(nodeID, addr)
+		sp := mod.scoreNode(nodeID)
 
 		if sa <= sp {
 			return errors.New("score too low")
@@ -62,7 +75,7 @@ func (mod *Module) Optimize(parent context.Context, peer *network.Peer) error {
 	retryDialer := NewRetryDialer(filterDialer, concurrency)
 
 	go func() {
-		list, err := mod.node.Tracker().EndpointsByIdentity(peer.Identity())
+		list, err := mod.node.Tracker().EndpointsByIdentity(nodeID)
 		if err == nil {
 			for _, i := range list {
 				retryDialer.Add(i)
@@ -70,22 +83,24 @@ func (mod *Module) Optimize(parent context.Context, peer *network.Peer) error {
 		}
 
 		events.Handle(ctx, mod.node.Events(), func(ctx context.Context, e tracker.EventNewEndpoint) error {
-			if e.Identity.IsEqual(peer.Identity()) {
+			if e.Identity.IsEqual(nodeID) {
 				retryDialer.Add(e.Endpoint)
 			}
 			return nil
 		})
 	}()
 
-	for authConn := range network.NewConcurrentHandshake(
+	for l := range network.NewConcurrentHandshake(
 		mod.node.Identity(),
-		peer.Identity(),
+		nodeID,
 		concurrency,
 	).Outbound(
 		ctx,
 		retryDialer.Dial(ctx),
 	) {
-		mod.node.Network().AddLink(link.NewCoreLink(authConn))
+		if err := mod.node.Network().AddLink(l); err != nil {
+			l.Close()
+		}
 	}
 
 	return nil
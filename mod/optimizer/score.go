@@ -1,27 +1,24 @@
 package optimizer
 
 import (
+	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
-	"github.com/cryptopunkscc/astrald/node/network"
 )
 
-func scoreAddr(addr net.Endpoint) int {
-	switch addr.Network() {
-	case "tor":
-		return 10
-	case "bt":
-		return 20
-	case "gw":
-		return 30
-	case "inet":
-		return 40
-	}
-	return 0
+// scoreAddr returns the score learned for the endpoint's network with the node
+func (mod *Module) scoreAddr(nodeID id.Identity, addr net.Endpoint) int {
+	return mod.node.Network().NetworkScore(nodeID, addr.Network())
 }
 
-func scorePeer(peer *network.Peer) (best int) {
-	for _, link := range peer.Links() {
-		score := scoreAddr(link.RemoteEndpoint())
+// scoreNode returns the best score of the node's current links
+func (mod *Module) scoreNode(nodeID id.Identity) (best int) {
+	for _, l := range mod.node.Network().Links().ByRemoteIdentity(nodeID).All() {
+		var t = l.Transport()
+		if t == nil || t.RemoteEndpoint() == nil {
+			continue
+		}
+
+		score := mod.scoreAddr(nodeID, t.RemoteEndpoint())
 		if score > best {
 			best = score
 		}
@@ -92,6 +92,13 @@ func (m *Module) Run(ctx context.Context) (err error) {
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		events.Handle(ctx, m.node.Events(), m.handleAddrAdded)
+	}()
+
 	go func() {
 		select {
 		case <-ctx.Done():
@@ -265,6 +272,23 @@ func (m *Module) Announce(ctx context.Context) error {
 	return nil
 }
 
+// handleAddrAdded announces our presence on a new address and asks nodes on its network to respond
+func (m *Module) handleAddrAdded(ctx context.Context, event ip.EventAddrAdded) error {
+	if event.Addr.IP.IsLoopback() || ip.IsLinkLocal(event.Addr.IP) {
+		return nil
+	}
+
+	if err := m.broadcastPresence(&presence{
+		Identity: m.node.Identity(),
+		Port:     m.getListenPort(),
+		Flags:    flagDiscover,
+	}); err != nil {
+		m.log.Error("announce: %s", err)
+	}
+
+	return nil
+}
+
 func (m *Module) broadcastPresence(p *presence) error {
 	// check presence socket
 	if err := m.setupPresenceConn(); err != nil {
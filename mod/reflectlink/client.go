@@ -53,6 +53,10 @@ func (mod *Client) handleLinkAdded(ctx context.Context, event network.EventLinkA
 		endpoint,
 	)
 
+	mod.mu.Lock()
+	mod.reflected[endpoint.Network()] = endpoint
+	mod.mu.Unlock()
+
 	mod.node.Events().Emit(EventLinkReflected{Link: event.Link, Endpoint: endpoint})
 
 	return nil
@@ -2,6 +2,7 @@ package reflectlink
 
 import (
 	_log "github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/assets"
 	"github.com/cryptopunkscc/astrald/node/modules"
 )
@@ -12,8 +13,9 @@ type Loader struct{}
 
 func (Loader) Load(node modules.Node, _ assets.Store, log *_log.Logger) (modules.Module, error) {
 	mod := &Module{
-		node: node,
-		log:  log,
+		node:      node,
+		log:       log,
+		reflected: make(map[string]net.Endpoint),
 	}
 
 	return mod, nil
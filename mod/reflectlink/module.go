@@ -8,6 +8,7 @@ import (
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node"
 	"github.com/cryptopunkscc/astrald/tasks"
+	"sync"
 )
 
 const serviceName = "net.reflectlink"
@@ -16,12 +17,16 @@ const serviceType = "net.reflectlink"
 type Module struct {
 	node node.Node
 	log  *log.Logger
+
+	mu        sync.Mutex
+	reflected map[string]net.Endpoint // last endpoint reflected back to us, by network
 }
 
 func (mod *Module) Run(ctx context.Context) error {
 	return tasks.Group(
 		&Server{Module: mod},
 		&Client{Module: mod},
+		&Puncher{Module: mod},
 	).Run(ctx)
 }
 
@@ -8,3 +8,21 @@ type Endpoint struct {
 type Reflection struct {
 	RemoteEndpoint Endpoint `json:"endpoint,omitempty"`
 }
+
+// PunchRequest asks a mediator to arrange a hole punch with the target node
+type PunchRequest struct {
+	Target    string     `json:"target"`
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// RendezvousRequest is relayed by the mediator to the target of a hole punch
+type RendezvousRequest struct {
+	Peer      string     `json:"peer"`
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// PunchResponse carries the endpoints of the target node back to the caller
+type PunchResponse struct {
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
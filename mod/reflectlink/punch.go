@@ -0,0 +1,389 @@
+package reflectlink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/reflectlink/proto"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/node/infra/drivers/gw"
+	"github.com/cryptopunkscc/astrald/node/infra/drivers/inet"
+	"github.com/cryptopunkscc/astrald/node/infra/drivers/udp"
+	"github.com/cryptopunkscc/astrald/node/network"
+	"github.com/cryptopunkscc/astrald/tasks"
+	_net "net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	punchServiceName      = "net.reflectlink.punch"
+	rendezvousServiceName = "net.reflectlink.rendezvous"
+	punchTimeout          = 15 * time.Second
+	rendezvousTimeout     = 5 * time.Second
+	punchRetryInterval    = 5 * time.Minute
+	maxPunchEndpoints     = 8
+)
+
+var (
+	ErrNoPunchEndpoints = errors.New("no endpoints to punch with")
+	ErrNoMediator       = errors.New("no mediator could reach the target")
+	ErrPunchFailed      = errors.New("hole punch failed")
+)
+
+// Puncher establishes direct UDP links with nodes behind NATs. Both nodes send their reflected endpoints
+// through a mediator they are linked with and then dial each other at the same time, so that each side's
+// NAT sees outbound traffic before the other side's packets arrive.
+type Puncher struct {
+	*Module
+	ctx context.Context
+
+	mu       sync.Mutex
+	attempts map[string]time.Time
+	expected map[string]expectedPunch
+}
+
+// expectedPunch lists the mediators we accept a rendezvous with a peer from
+type expectedPunch struct {
+	mediators map[string]struct{}
+	expires   time.Time
+}
+
+func (p *Puncher) Run(ctx context.Context) error {
+	p.ctx = ctx
+	p.attempts = make(map[string]time.Time)
+	p.expected = make(map[string]expectedPunch)
+
+	punch, err := p.node.Services().Register(ctx, p.node.Identity(), punchServiceName, &punchService{p})
+	if err != nil {
+		return err
+	}
+
+	rendezvous, err := p.node.Services().Register(ctx, p.node.Identity(), rendezvousServiceName, &rendezvousService{p})
+	if err != nil {
+		return err
+	}
+
+	err = tasks.Group(
+		events.Runner(p.node.Events(), p.handleLinkAdded),
+	).Run(ctx)
+
+	<-punch.Done()
+	<-rendezvous.Done()
+
+	return err
+}
+
+// Punch tries to establish a direct UDP link with the target using any linked node as a mediator
+func (mod *Module) Punch(ctx context.Context, target id.Identity) (net.Link, error) {
+	ctx, cancel := context.WithTimeout(ctx, punchTimeout)
+	defer cancel()
+
+	var local = mod.punchEndpoints()
+	if len(local) == 0 {
+		return nil, ErrNoPunchEndpoints
+	}
+
+	for _, mediator := range mod.mediators(target) {
+		remote, err := mod.requestPunch(ctx, mediator, target, local)
+		if err != nil {
+			mod.log.Logv(2, "punch with %v via %v failed: %v", target, mediator, err)
+			continue
+		}
+
+		return mod.linkEndpoints(ctx, target, remote)
+	}
+
+	return nil, ErrNoMediator
+}
+
+// handleLinkAdded tries to replace relayed links with direct ones
+func (p *Puncher) handleLinkAdded(ctx context.Context, event network.EventLinkAdded) error {
+	if net.Network(event.Link) != gw.DriverName {
+		return nil
+	}
+
+	if _, found := p.node.Infra().Drivers()[udp.DriverName]; !found {
+		return nil
+	}
+
+	var remoteID = event.Link.RemoteIdentity()
+
+	// only one side of the link starts the punch, the other waits for a rendezvous
+	if !event.Link.Transport().Outbound() {
+		p.expectPunch(remoteID)
+		return nil
+	}
+
+	if !p.shouldPunch(remoteID) {
+		return nil
+	}
+
+	go func() {
+		l, err := p.Punch(ctx, remoteID)
+		if err != nil {
+			p.log.Logv(1, "hole punch with %v failed: %v", remoteID, err)
+			return
+		}
+
+		p.log.Info("punched a direct link with %v at %v", remoteID, l.Transport().RemoteEndpoint())
+	}()
+
+	return nil
+}
+
+// shouldPunch returns true if there was no recent punch attempt with the node
+func (p *Puncher) shouldPunch(nodeID id.Identity) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var hex = nodeID.PublicKeyHex()
+	if last, found := p.attempts[hex]; found && time.Since(last) < punchRetryInterval {
+		return false
+	}
+	p.attempts[hex] = time.Now()
+
+	return true
+}
+
+// expectPunch allows our current mediators to arrange a punch with the node for the duration of a punch
+func (p *Puncher) expectPunch(nodeID id.Identity) {
+	var mediators = map[string]struct{}{}
+	for _, m := range p.mediators(nodeID) {
+		mediators[m.PublicKeyHex()] = struct{}{}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for hex, e := range p.expected {
+		if time.Now().After(e.expires) {
+			delete(p.expected, hex)
+		}
+	}
+
+	p.expected[nodeID.PublicKeyHex()] = expectedPunch{
+		mediators: mediators,
+		expires:   time.Now().Add(punchTimeout),
+	}
+}
+
+// acceptRendezvous returns true if we expect a punch with the node arranged by the mediator. An accepted
+// rendezvous is consumed.
+func (p *Puncher) acceptRendezvous(nodeID id.Identity, mediatorID id.Identity) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var hex = nodeID.PublicKeyHex()
+	e, found := p.expected[hex]
+	if !found || time.Now().After(e.expires) {
+		return false
+	}
+	if _, found := e.mediators[mediatorID.PublicKeyHex()]; !found {
+		return false
+	}
+	delete(p.expected, hex)
+
+	return true
+}
+
+// mediators returns all linked nodes other than the target
+func (mod *Module) mediators(target id.Identity) []id.Identity {
+	var list []id.Identity
+	var seen = map[string]struct{}{}
+
+	for _, l := range mod.node.Network().Links().All() {
+		remoteID := l.RemoteIdentity()
+		if remoteID.IsEqual(target) {
+			continue
+		}
+		if _, found := seen[remoteID.PublicKeyHex()]; found {
+			continue
+		}
+		seen[remoteID.PublicKeyHex()] = struct{}{}
+		list = append(list, remoteID)
+	}
+
+	return list
+}
+
+func (mod *Module) requestPunch(ctx context.Context, mediator id.Identity, target id.Identity, local []net.Endpoint) ([]net.Endpoint, error) {
+	conn, err := net.Route(ctx, mod.node.Network(), net.NewQuery(mod.node.Identity(), mediator, punchServiceName))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	go closeOnDone(ctx, conn)
+
+	err = json.NewEncoder(conn).Encode(proto.PunchRequest{
+		Target:    target.PublicKeyHex(),
+		Endpoints: packEndpoints(local),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res proto.PunchResponse
+	if err = json.NewDecoder(conn).Decode(&res); err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+
+	var remote = mod.parseEndpoints(res.Endpoints)
+	if len(remote) == 0 {
+		return nil, ErrNoPunchEndpoints
+	}
+
+	return remote, nil
+}
+
+// linkEndpoints dials all endpoints at once and adds the first link that completes the handshake
+func (mod *Module) linkEndpoints(ctx context.Context, remoteID id.Identity, endpoints []net.Endpoint) (net.Link, error) {
+	var ch = make(chan net.Endpoint, len(endpoints))
+	for _, e := range endpoints {
+		ch <- e
+	}
+	close(ch)
+
+	dialCtx, cancelDial := context.WithCancel(ctx)
+	defer cancelDial()
+
+	links := network.NewConcurrentHandshake(
+		mod.node.Identity(),
+		remoteID,
+		len(endpoints),
+	).Outbound(
+		ctx,
+		network.NewConcurrentDialer(
+			mod.node.Infra(),
+			len(endpoints),
+		).Dial(
+			dialCtx,
+			ch,
+		),
+	)
+
+	l, ok := <-links
+	cancelDial()
+
+	go func() {
+		for a := range links {
+			a.Close()
+		}
+	}()
+
+	if !ok {
+		return nil, ErrPunchFailed
+	}
+
+	if err := mod.node.Network().AddLink(l); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// punchEndpoints returns the UDP endpoints under which other nodes are likely to reach us
+func (mod *Module) punchEndpoints() []net.Endpoint {
+	drv, ok := mod.node.Infra().Drivers()[udp.DriverName].(*udp.Driver)
+	if !ok {
+		return nil
+	}
+
+	var list []net.Endpoint
+	var seen = map[string]struct{}{}
+	var add = func(e net.Endpoint) {
+		if _, found := seen[e.String()]; found {
+			return
+		}
+		seen[e.String()] = struct{}{}
+		list = append(list, e)
+	}
+
+	mod.mu.Lock()
+	if e, found := mod.reflected[udp.DriverName]; found {
+		add(e)
+	}
+
+	// most NATs preserve the source port, so our UDP port at the reflected IP is worth a try
+	if e, found := mod.reflected[inet.DriverName]; found {
+		if host, _, err := _net.SplitHostPort(e.String()); err == nil {
+			if e, err := udp.Parse(_net.JoinHostPort(host, strconv.Itoa(drv.ListenPort()))); err == nil {
+				add(e)
+			}
+		}
+	}
+	mod.mu.Unlock()
+
+	for _, e := range drv.Endpoints() {
+		add(e)
+	}
+
+	return list
+}
+
+func (mod *Module) parseEndpoints(list []proto.Endpoint) []net.Endpoint {
+	var endpoints = make([]net.Endpoint, 0, min(len(list), maxPunchEndpoints))
+
+	for _, e := range list {
+		if len(endpoints) >= maxPunchEndpoints {
+			break
+		}
+
+		if e.Network != udp.DriverName {
+			continue
+		}
+
+		endpoint, err := mod.node.Infra().Parse(e.Network, e.Address)
+		if err != nil {
+			continue
+		}
+
+		if !isPunchable(endpoint) {
+			continue
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
+
+// isPunchable returns false for endpoints that can't belong to a remote node
+func isPunchable(e net.Endpoint) bool {
+	u, ok := e.(udp.Endpoint)
+	if !ok {
+		return false
+	}
+
+	var addr = u.UDPAddr()
+	if addr.Port == 0 {
+		return false
+	}
+
+	return !(addr.IP.IsUnspecified() || addr.IP.IsLoopback() || addr.IP.IsLinkLocalUnicast())
+}
+
+func packEndpoints(list []net.Endpoint) []proto.Endpoint {
+	var endpoints = make([]proto.Endpoint, 0, len(list))
+
+	for _, e := range list {
+		endpoints = append(endpoints, proto.Endpoint{
+			Network: e.Network(),
+			Address: e.String(),
+		})
+	}
+
+	return endpoints
+}
+
+func closeOnDone(ctx context.Context, conn net.SecureConn) {
+	<-ctx.Done()
+	conn.Close()
+}
@@ -0,0 +1,130 @@
+package reflectlink
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/reflectlink/proto"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+// punchService mediates hole punches between two of our linked peers
+type punchService struct {
+	*Puncher
+}
+
+// rendezvousService answers hole punch requests relayed by a mediator
+type rendezvousService struct {
+	*Puncher
+}
+
+func (srv *punchService) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	return net.Accept(query, caller, srv.mediate)
+}
+
+func (srv *punchService) mediate(conn net.SecureConn) {
+	defer conn.Close()
+
+	var req proto.PunchRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	var res = srv.relay(conn.RemoteIdentity(), req)
+	json.NewEncoder(conn).Encode(res)
+
+	if res.Error != "" {
+		srv.log.Logv(2, "punch from %v to %s failed: %s", conn.RemoteIdentity(), req.Target, res.Error)
+	} else {
+		srv.log.Infov(2, "mediated punch from %v to %s", conn.RemoteIdentity(), req.Target)
+	}
+}
+
+// relay passes the request to the target and returns its response
+func (srv *punchService) relay(callerID id.Identity, req proto.PunchRequest) proto.PunchResponse {
+	targetID, err := id.ParsePublicKeyHex(req.Target)
+	if err != nil {
+		return proto.PunchResponse{Error: "malformed target identity"}
+	}
+
+	// only mediate with nodes we're already linked with
+	if srv.node.Network().Links().ByRemoteIdentity(targetID).Count() == 0 {
+		return proto.PunchResponse{Error: "target not linked"}
+	}
+
+	ctx, cancel := context.WithTimeout(srv.ctx, rendezvousTimeout)
+	defer cancel()
+
+	conn, err := net.Route(ctx, srv.node.Network(), net.NewQuery(srv.node.Identity(), targetID, rendezvousServiceName))
+	if err != nil {
+		return proto.PunchResponse{Error: err.Error()}
+	}
+	defer conn.Close()
+	go closeOnDone(ctx, conn)
+
+	err = json.NewEncoder(conn).Encode(proto.RendezvousRequest{
+		Peer:      callerID.PublicKeyHex(),
+		Endpoints: req.Endpoints,
+	})
+	if err != nil {
+		return proto.PunchResponse{Error: err.Error()}
+	}
+
+	var res proto.PunchResponse
+	if err = json.NewDecoder(conn).Decode(&res); err != nil {
+		return proto.PunchResponse{Error: err.Error()}
+	}
+
+	return res
+}
+
+func (srv *rendezvousService) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	return net.Accept(query, caller, srv.rendezvous)
+}
+
+func (srv *rendezvousService) rendezvous(conn net.SecureConn) {
+	var req proto.RendezvousRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		conn.Close()
+		return
+	}
+
+	peerID, err := id.ParsePublicKeyHex(req.Peer)
+	if err != nil {
+		json.NewEncoder(conn).Encode(proto.PunchResponse{Error: "malformed peer identity"})
+		conn.Close()
+		return
+	}
+
+	// only punch with peers that have a relayed link with us through a mediator we know, and not too often
+	if !srv.acceptRendezvous(peerID, conn.RemoteIdentity()) || !srv.shouldPunch(peerID) {
+		json.NewEncoder(conn).Encode(proto.PunchResponse{Error: "rendezvous refused"})
+		conn.Close()
+		return
+	}
+
+	var local = srv.punchEndpoints()
+	var remote = srv.parseEndpoints(req.Endpoints)
+	if len(local) == 0 || len(remote) == 0 {
+		json.NewEncoder(conn).Encode(proto.PunchResponse{Error: ErrNoPunchEndpoints.Error()})
+		conn.Close()
+		return
+	}
+
+	json.NewEncoder(conn).Encode(proto.PunchResponse{Endpoints: packEndpoints(local)})
+	conn.Close()
+
+	srv.log.Logv(1, "punching %v via %v", peerID, conn.RemoteIdentity())
+
+	// dial the peer while it dials us
+	ctx, cancel := context.WithTimeout(srv.ctx, punchTimeout)
+	defer cancel()
+
+	l, err := srv.linkEndpoints(ctx, peerID, remote)
+	if err != nil {
+		srv.log.Logv(1, "hole punch with %v failed: %v", peerID, err)
+		return
+	}
+
+	srv.log.Info("punched a direct link with %v at %v", peerID, l.Transport().RemoteEndpoint())
+}
@@ -48,6 +48,12 @@ func (m *Module) RouteVia(ctx context.Context, relay id.Identity, query net.Quer
 		}
 	}()
 
+	// closing the conn lets the relay know that it should stop routing the query
+	var stop = context.AfterFunc(ctx, func() {
+		routeConn.Close()
+	})
+	defer stop()
+
 	var rpc = proto.New(routeConn)
 
 	// present a certificate if needed
@@ -59,8 +65,12 @@ func (m *Module) RouteVia(ctx context.Context, relay id.Identity, query net.Quer
 		}
 	}
 
-	// send the query
-	err = rpc.Query(query.Target(), query.Query())
+	// send the query, relays that don't support arguments still get plain queries
+	if len(query.Args()) > 0 {
+		err = rpc.QueryExt(query.Target(), query.Query(), query.Args().Pairs())
+	} else {
+		err = rpc.Query(query.Target(), query.Query())
+	}
 	if err != nil {
 		if errors.Is(err, proto.ErrRejected) {
 			return net.Reject()
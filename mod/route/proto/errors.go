@@ -41,3 +41,16 @@ func (s *Session) Query(target id.Identity, query string) error {
 	}
 	return s.DecodeErr()
 }
+
+// QueryExt sends a query with arguments. Relays that don't support it reject the request as invalid.
+func (s *Session) QueryExt(target id.Identity, query string, args []string) error {
+	err := s.Encodef("[c]cv", CmdQueryExt, QueryExtParams{
+		Target: target,
+		Query:  query,
+		Args:   args,
+	})
+	if err != nil {
+		return err
+	}
+	return s.DecodeErr()
+}
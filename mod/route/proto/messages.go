@@ -3,8 +3,9 @@ package proto
 import "github.com/cryptopunkscc/astrald/auth/id"
 
 const (
-	CmdCert  = "cert"
-	CmdQuery = "query"
+	CmdCert     = "cert"
+	CmdQuery    = "query"
+	CmdQueryExt = "queryExt"
 )
 
 type Cmd struct {
@@ -15,3 +16,10 @@ type QueryParams struct {
 	Target id.Identity `cslq:"v"`
 	Query  string      `cslq:"[c]c"`
 }
+
+// QueryExtParams is a query with key/value arguments. Args is a flat list of keys and values.
+type QueryExtParams struct {
+	Target id.Identity `cslq:"v"`
+	Query  string      `cslq:"[s]c"`
+	Args   []string    `cslq:"[s][s]c"`
+}
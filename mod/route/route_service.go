@@ -3,8 +3,10 @@ package route
 import (
 	"context"
 	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/mod/route/proto"
 	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/streams"
 	"io"
 )
 
@@ -66,6 +68,9 @@ func (service *RouteService) serve(ctx context.Context, conn net.SecureConn, ori
 
 			service.log.Logv(2, "%s shifted to %s", caller, cert.Identity)
 
+			// the relay may also send datagrams on behalf of the identity
+			service.node.Network().AuthorizeRelay(cert.Identity, cert.Relay, cert.ExpiresAt.Time())
+
 			caller = cert.Identity
 
 			if err := rpc.EncodeErr(nil); err != nil {
@@ -78,51 +83,77 @@ func (service *RouteService) serve(ctx context.Context, conn net.SecureConn, ori
 				return err
 			}
 
-			var target = service.node.Identity()
+			// plain queries carry no arguments, the query string is passed as is
+			return service.routeQuery(ctx, conn, rpc, caller, origin, params.Target, params.Query, nil)
 
-			// if query target is not the node, look up private keys for the target identity
-			if !params.Target.IsEqual(target) {
-				target, err = service.keys.Find(params.Target)
-				if err != nil {
-					return rpc.EncodeErr(proto.ErrUnableToProcess)
-				}
+		case proto.CmdQueryExt:
+			var params proto.QueryExtParams
+			if err := rpc.Decode(&params); err != nil {
+				return err
 			}
 
-			rpc.EncodeErr(nil)
-
-			// send a certificate if node is not the target
-			if !target.IsEqual(service.node.Identity()) {
-				var cert = proto.NewRelayCert(target, service.node.Identity())
-				if err = cert.Sign(); err != nil {
-					return err
-				}
-
-				if err = rpc.Encode(cert); err != nil {
-					return err
-				}
+			args, err := net.QueryArgsFromPairs(params.Args)
+			if err != nil {
+				return rpc.EncodeErr(proto.ErrInvalidRequest)
 			}
 
-			var query = net.NewQuery(caller, target, params.Query)
-			var shiftedConn = &replaceIdentity{SecureConn: conn, remoteIdentity: caller}
-			shiftedConn.Lock()
+			return service.routeQuery(ctx, conn, rpc, caller, origin, params.Target, params.Query, args)
 
-			localWriter, err := service.node.Router().RouteQuery(ctx, query, shiftedConn, net.Hints{Origin: origin})
-			if err != nil {
-				return rpc.EncodeErr(proto.ErrRejected)
-			}
+		default:
+			return rpc.EncodeErr(proto.ErrInvalidRequest)
+		}
+	}
+}
 
-			if err := rpc.EncodeErr(nil); err != nil {
-				return err
-			}
+// routeQuery routes a query on behalf of the caller and pipes the connection until either side closes it
+func (service *RouteService) routeQuery(ctx context.Context, conn net.SecureConn, rpc proto.Session, caller id.Identity, origin string, targetID id.Identity, name string, args net.QueryArgs) error {
+	var err error
+	var target = service.node.Identity()
 
-			shiftedConn.Unlock()
-			io.Copy(localWriter, conn)
-			localWriter.Close()
+	// if query target is not the node, look up private keys for the target identity
+	if !targetID.IsEqual(target) {
+		target, err = service.keys.Find(targetID)
+		if err != nil {
+			return rpc.EncodeErr(proto.ErrUnableToProcess)
+		}
+	}
 
-			return nil
+	rpc.EncodeErr(nil)
 
-		default:
-			return rpc.EncodeErr(proto.ErrInvalidRequest)
+	// send a certificate if node is not the target
+	if !target.IsEqual(service.node.Identity()) {
+		var cert = proto.NewRelayCert(target, service.node.Identity())
+		if err = cert.Sign(); err != nil {
+			return err
 		}
+
+		if err = rpc.Encode(cert); err != nil {
+			return err
+		}
+	}
+
+	var query = net.NewQueryWithArgs(caller, target, name, args)
+	var shiftedConn = &replaceIdentity{SecureConn: conn, remoteIdentity: caller}
+	shiftedConn.Lock()
+
+	// stop routing the query if the caller goes away
+	routeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var input = streams.WatchReader(conn, cancel)
+	defer input.Close()
+
+	localWriter, err := service.node.Router().RouteQuery(routeCtx, query, shiftedConn, net.Hints{Origin: origin})
+	if err != nil {
+		return rpc.EncodeErr(proto.ErrRejected)
 	}
+
+	if err := rpc.EncodeErr(nil); err != nil {
+		return err
+	}
+
+	shiftedConn.Unlock()
+	io.Copy(localWriter, input)
+	localWriter.Close()
+
+	return nil
 }
@@ -9,3 +9,4 @@ var ErrPortNotInUse = errors.New("port not in use")
 var ErrAllPortsUsed = errors.New("all ports used")
 var ErrPortClosed = errors.New("port closed")
 var ErrCloseUnsupported = errors.New("transport does not support closing")
+var ErrMuxClosed = errors.New("mux closed")
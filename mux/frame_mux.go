@@ -10,6 +10,9 @@ import (
 type FrameMux struct {
 	mu             sync.Mutex
 	mux            *RawMux
+	scheduler      *writeScheduler
+	startWriter    sync.Once
+	counters       counters
 	portHandlers   map[int]HandlerFunc
 	defaultHandler HandlerFunc
 	logID          int
@@ -18,19 +21,24 @@ type FrameMux struct {
 
 var nextID atomic.Int64
 
+// NewFrameMux returns a new FrameMux. Frames can be written right away, but the FrameMux has to be run in order to
+// receive frames. A FrameMux that is written to, but never run, has to be stopped to release its writer.
 func NewFrameMux(transport io.ReadWriter, defaultHandler HandlerFunc) *FrameMux {
 	return &FrameMux{
 		mux:            NewRawMux(transport),
+		scheduler:      newWriteScheduler(),
 		portHandlers:   make(map[int]HandlerFunc),
 		defaultHandler: defaultHandler,
 	}
 }
 
-// Run runs the FrameMux for the duration of the context
+// Run runs the FrameMux for the duration of the context. Once Run returns, the FrameMux is stopped.
 func (mux *FrameMux) Run(ctx context.Context) error {
 	var frame Frame
 	var err error
 
+	defer mux.Stop()
+
 	defer mux.unbindAll()
 
 	frame.Mux = mux
@@ -40,6 +48,7 @@ func (mux *FrameMux) Run(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+		mux.counters.received(len(frame.Data))
 
 		handler := mux.portHandler(frame.Port)
 		if handler != nil {
@@ -120,13 +129,49 @@ func (mux *FrameMux) Unbind(port int) error {
 	return mux.unbind(port)
 }
 
+// Write schedules a frame for writing and waits until it's written. Frames sent to the ControlPort take priority
+// over all other frames, while the remaining ports share the transport fairly.
+// Errors: ErrInvalidPort, ErrFrameTooLarge, ErrMuxClosed, ...
 func (mux *FrameMux) Write(frame Frame) error {
-	return mux.mux.Write(frame.Port, frame.Data)
+	if frame.Port < 0 || frame.Port > MaxPorts-1 {
+		return ErrInvalidPort
+	}
+
+	if len(frame.Data) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+
+	// the writer only starts with the first write, so that an unused FrameMux holds no resources
+	mux.startWriter.Do(func() {
+		go mux.scheduler.run(mux.mux)
+	})
+
+	if err := mux.scheduler.write(frame.Port, frame.Data); err != nil {
+		return err
+	}
+	mux.counters.sent(len(frame.Data))
+
+	return nil
+}
+
+// Queued returns the number of bytes waiting to be written to the specified remote port
+func (mux *FrameMux) Queued(remotePort int) int {
+	return mux.scheduler.queued(remotePort)
+}
+
+// Pending returns the number of frames that were scheduled, but not yet written to the transport
+func (mux *FrameMux) Pending() int {
+	return mux.scheduler.pending()
+}
+
+// Stop fails all pending and future writes and releases the writer. It's safe to call Stop many times.
+func (mux *FrameMux) Stop() {
+	mux.scheduler.close(ErrMuxClosed)
 }
 
 // Close sends an EOF frame to the specified remote port
 func (mux *FrameMux) Close(remotePort int) error {
-	return mux.mux.Write(remotePort, []byte{})
+	return mux.Write(Frame{Port: remotePort, Data: []byte{}})
 }
 
 // Unbind unbinds any handler assigned to the specified port.
@@ -0,0 +1,55 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFrameMuxWriteBeforeRun(t *testing.T) {
+	var buf bytes.Buffer
+	var mux = NewFrameMux(&buf, nil)
+
+	var done = make(chan error, 1)
+	go func() {
+		done <- mux.Write(Frame{Port: 1, Data: []byte("hello")})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write blocked before run")
+	}
+
+	// buf is drained by now, so Run reads EOF and closes the mux
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	buf.Reset()
+	mux.Run(ctx)
+
+	if err := mux.Write(Frame{Port: 1, Data: []byte("late")}); !errors.Is(err, ErrMuxClosed) {
+		t.Fatalf("expected ErrMuxClosed, got %v", err)
+	}
+}
+
+func TestFrameMuxStop(t *testing.T) {
+	var buf bytes.Buffer
+	var mux = NewFrameMux(&buf, nil)
+
+	if err := mux.Write(Frame{Port: 1, Data: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// stopping a mux that never ran releases its writer and fails further writes
+	mux.Stop()
+	mux.Stop()
+
+	if err := mux.Write(Frame{Port: 1, Data: []byte("late")}); !errors.Is(err, ErrMuxClosed) {
+		t.Fatalf("expected ErrMuxClosed, got %v", err)
+	}
+}
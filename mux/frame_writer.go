@@ -30,7 +30,7 @@ func (writer *FrameWriter) Write(p []byte) (n int, err error) {
 
 		writer.mu.Lock()
 		if writer.err == nil {
-			err = writer.mux.mux.Write(writer.port, left[0:chunkLen])
+			err = writer.mux.Write(Frame{Port: writer.port, Data: left[0:chunkLen]})
 		} else {
 			err = writer.err
 		}
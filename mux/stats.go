@@ -0,0 +1,40 @@
+package mux
+
+import "sync/atomic"
+
+// Stats holds traffic counters of a FrameMux. Byte counts include frame payloads only.
+type Stats struct {
+	BytesSent      uint64
+	FramesSent     uint64
+	BytesReceived  uint64
+	FramesReceived uint64
+	Queued         int // bytes waiting to be written to the transport
+}
+
+type counters struct {
+	bytesSent      atomic.Uint64
+	framesSent     atomic.Uint64
+	bytesReceived  atomic.Uint64
+	framesReceived atomic.Uint64
+}
+
+func (c *counters) sent(n int) {
+	c.bytesSent.Add(uint64(n))
+	c.framesSent.Add(1)
+}
+
+func (c *counters) received(n int) {
+	c.bytesReceived.Add(uint64(n))
+	c.framesReceived.Add(1)
+}
+
+// Stats returns a snapshot of mux's traffic counters
+func (mux *FrameMux) Stats() Stats {
+	return Stats{
+		BytesSent:      mux.counters.bytesSent.Load(),
+		FramesSent:     mux.counters.framesSent.Load(),
+		BytesReceived:  mux.counters.bytesReceived.Load(),
+		FramesReceived: mux.counters.framesReceived.Load(),
+		Queued:         mux.scheduler.queuedTotal(),
+	}
+}
@@ -0,0 +1,230 @@
+package mux
+
+import (
+	"sync"
+)
+
+// ControlPort is the port reserved for control messages. Frames sent to it are always written before any other.
+const ControlPort = 0
+
+// schedulerQuantum is the number of bytes a port can write in a single round
+const schedulerQuantum = MaxFrameSize
+
+// writeScheduler orders outgoing frames, so that a busy port cannot starve the others. Frames sent to the
+// ControlPort are written first, remaining ports are served using deficit round robin.
+type writeScheduler struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	control  []*pendingFrame
+	queues   map[int]*portQueue
+	active   []int // ports with pending frames in round robin order
+	visiting bool  // true if the port at the head of active already received its quantum in this round
+	inflight int   // number of frames that were pushed, but not yet written
+	err      error
+}
+
+type portQueue struct {
+	frames  []*pendingFrame
+	deficit int
+}
+
+type pendingFrame struct {
+	port int
+	data []byte
+	done chan error
+}
+
+func newWriteScheduler() *writeScheduler {
+	s := &writeScheduler{
+		queues: make(map[int]*portQueue),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// write queues a frame and waits until it's written to the transport
+func (s *writeScheduler) write(port int, data []byte) error {
+	var frame = &pendingFrame{
+		port: port,
+		data: data,
+		done: make(chan error, 1),
+	}
+
+	if err := s.push(frame); err != nil {
+		return err
+	}
+
+	return <-frame.done
+}
+
+func (s *writeScheduler) push(frame *pendingFrame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	if frame.port == ControlPort {
+		s.control = append(s.control, frame)
+	} else {
+		q, found := s.queues[frame.port]
+		if !found {
+			q = &portQueue{}
+			s.queues[frame.port] = q
+			s.active = append(s.active, frame.port)
+		}
+		q.frames = append(q.frames, frame)
+	}
+	s.inflight++
+
+	s.cond.Signal()
+
+	return nil
+}
+
+// next waits for the next frame to be written. Returns nil after the scheduler is closed.
+func (s *writeScheduler) next() *pendingFrame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.err != nil {
+			return nil
+		}
+
+		if frame := s.pick(); frame != nil {
+			return frame
+		}
+
+		s.cond.Wait()
+	}
+}
+
+// pick removes and returns the next frame to be written or nil if no frames are pending
+func (s *writeScheduler) pick() *pendingFrame {
+	if len(s.control) > 0 {
+		frame := s.control[0]
+		s.control = s.control[1:]
+		return frame
+	}
+
+	for len(s.active) > 0 {
+		var port = s.active[0]
+		var q = s.queues[port]
+
+		if !s.visiting {
+			q.deficit += schedulerQuantum
+			s.visiting = true
+		}
+
+		if frame := q.frames[0]; len(frame.data) <= q.deficit {
+			q.deficit -= len(frame.data)
+			q.frames = q.frames[1:]
+
+			// an idle port does not keep its deficit
+			if len(q.frames) == 0 {
+				delete(s.queues, port)
+				s.active = s.active[1:]
+				s.visiting = false
+			}
+
+			return frame
+		}
+
+		// the port used up its quantum, move it to the end of the round
+		s.active = append(s.active[1:], port)
+		s.visiting = false
+	}
+
+	return nil
+}
+
+// run writes scheduled frames to the RawMux until the scheduler is closed
+func (s *writeScheduler) run(raw *RawMux) {
+	for {
+		frame := s.next()
+		if frame == nil {
+			return
+		}
+
+		frame.done <- raw.Write(frame.port, frame.data)
+
+		s.mu.Lock()
+		if s.err == nil {
+			s.inflight--
+		}
+		s.mu.Unlock()
+	}
+}
+
+// pending returns the number of frames that were scheduled, but not yet written
+func (s *writeScheduler) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.inflight
+}
+
+// queued returns the number of bytes waiting to be written to the port
+func (s *writeScheduler) queued(port int) (n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var frames = s.control
+	if port != ControlPort {
+		if q, found := s.queues[port]; found {
+			frames = q.frames
+		} else {
+			frames = nil
+		}
+	}
+
+	for _, f := range frames {
+		n += len(f.data)
+	}
+	return
+}
+
+// queuedTotal returns the number of bytes waiting to be written to all ports
+func (s *writeScheduler) queuedTotal() (n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.control {
+		n += len(f.data)
+	}
+	for _, q := range s.queues {
+		for _, f := range q.frames {
+			n += len(f.data)
+		}
+	}
+	return
+}
+
+// close fails all pending and future writes with the provided error
+func (s *writeScheduler) close(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	s.err = err
+
+	for _, f := range s.control {
+		f.done <- err
+	}
+	for _, q := range s.queues {
+		for _, f := range q.frames {
+			f.done <- err
+		}
+	}
+
+	s.control = nil
+	s.queues = make(map[int]*portQueue)
+	s.active = nil
+	s.inflight = 0
+
+	s.cond.Broadcast()
+}
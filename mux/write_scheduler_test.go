@@ -0,0 +1,67 @@
+package mux
+
+import (
+	"testing"
+)
+
+func TestWriteSchedulerOrder(t *testing.T) {
+	var s = newWriteScheduler()
+	var big = make([]byte, MaxFrameSize)
+
+	// two bulk ports with three full frames each
+	for i := 0; i < 3; i++ {
+		s.push(&pendingFrame{port: 1, data: big})
+		s.push(&pendingFrame{port: 2, data: big})
+	}
+
+	// a control frame queued last should be written first
+	s.push(&pendingFrame{port: ControlPort, data: []byte{1}})
+
+	var expected = []int{ControlPort, 1, 2, 1, 2, 1, 2}
+	for i, port := range expected {
+		frame := s.pick()
+		if frame == nil {
+			t.Fatalf("frame %d: no frame picked", i)
+		}
+		if frame.port != port {
+			t.Fatalf("frame %d: picked port %d, expected %d", i, frame.port, port)
+		}
+	}
+
+	if s.pick() != nil {
+		t.Fatal("expected the scheduler to be empty")
+	}
+}
+
+func TestWriteSchedulerSmallFrames(t *testing.T) {
+	var s = newWriteScheduler()
+
+	// a port sending small frames can send many of them in a single round
+	s.push(&pendingFrame{port: 1, data: make([]byte, MaxFrameSize)})
+	s.push(&pendingFrame{port: 1, data: make([]byte, MaxFrameSize)})
+	for i := 0; i < 4; i++ {
+		s.push(&pendingFrame{port: 2, data: make([]byte, 100)})
+	}
+
+	var expected = []int{1, 2, 2, 2, 2, 1}
+	for i, port := range expected {
+		if frame := s.pick(); frame.port != port {
+			t.Fatalf("frame %d: picked port %d, expected %d", i, frame.port, port)
+		}
+	}
+}
+
+func TestWriteSchedulerClose(t *testing.T) {
+	var s = newWriteScheduler()
+	var frame = &pendingFrame{port: 1, data: []byte{1}, done: make(chan error, 1)}
+
+	s.push(frame)
+	s.close(ErrMuxClosed)
+
+	if err := <-frame.done; err != ErrMuxClosed {
+		t.Fatalf("pending frame returned %v, expected %v", err, ErrMuxClosed)
+	}
+	if err := s.write(1, []byte{1}); err != ErrMuxClosed {
+		t.Fatalf("write returned %v, expected %v", err, ErrMuxClosed)
+	}
+}
@@ -0,0 +1,38 @@
+package net
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+)
+
+// MaxDatagramSize is the maximum size of a datagram payload
+const MaxDatagramSize = 8 * 1024
+
+// ErrDatagramTooLarge - the datagram payload exceeds MaxDatagramSize
+var ErrDatagramTooLarge = errors.New("datagram too large")
+
+// ErrDatagramsUnsupported - the link cannot carry datagrams
+var ErrDatagramsUnsupported = errors.New("datagrams not supported")
+
+// Datagram is a small, unreliable message sent to a service. Datagrams can be lost, duplicated or reordered and
+// are never retransmitted. The caller of a datagram received over a link is either the remote node itself or an
+// identity the remote node is an authorized relay for. The target may be an identity hosted by the local node.
+type Datagram struct {
+	Caller  id.Identity
+	Target  id.Identity
+	Service string
+	Data    []byte
+}
+
+// DatagramHandler handles incoming datagrams. Handlers should return quickly, since a slow handler will cause
+// other datagrams to be dropped.
+type DatagramHandler interface {
+	HandleDatagram(d Datagram)
+}
+
+// DatagramHandlerFunc is an adapter that allows use of ordinary functions as datagram handlers
+type DatagramHandlerFunc func(d Datagram)
+
+func (f DatagramHandlerFunc) HandleDatagram(d Datagram) {
+	f(d)
+}
@@ -12,6 +12,8 @@ type Link interface {
 	LocalIdentity() id.Identity
 	RemoteIdentity() id.Identity
 	Transport() SecureConn
+	SendDatagram(d Datagram) error
+	Stats() LinkStats
 	Close() error
 	Done() <-chan struct{}
 }
@@ -0,0 +1,37 @@
+package net
+
+import "time"
+
+// LinkStats is a snapshot of link's traffic counters
+type LinkStats struct {
+	BytesSent      uint64 // bytes written to the transport
+	BytesReceived  uint64 // bytes read from the transport
+	FramesSent     uint64
+	FramesReceived uint64
+	Queued         int         // bytes waiting to be written to the transport
+	Window         int         // memory reserved by all receive windows
+	RTT            []RTTSample // recent round trip times, oldest first
+	Ports          []PortStats
+}
+
+// PortStats is a snapshot of traffic counters of a single connection carried by a link. Byte counts include
+// payload only and are counted before compression.
+type PortStats struct {
+	Port           int    // local port
+	RemotePort     int    // remote port or -1 if unknown
+	Query          string // query that opened the connection
+	BytesSent      uint64
+	BytesReceived  uint64
+	FramesSent     uint64
+	FramesReceived uint64
+	Window         int // size of the receive window
+	Buffered       int // received bytes waiting for the consumer
+	RemoteWindow   int // bytes the remote party can still accept
+	Queued         int // bytes waiting to be written to the transport
+}
+
+// RTTSample is a single round trip time measurement
+type RTTSample struct {
+	At  time.Time
+	RTT time.Duration
+}
@@ -11,6 +11,7 @@ type Query interface {
 	Caller() id.Identity
 	Target() id.Identity
 	Query() string
+	Args() QueryArgs
 }
 
 var _ Query = &basicQuery{}
@@ -19,12 +20,18 @@ type basicQuery struct {
 	caller id.Identity
 	target id.Identity
 	query  string
+	args   QueryArgs
 }
 
 func NewQuery(caller id.Identity, target id.Identity, query string) Query {
 	return &basicQuery{caller: caller, target: target, query: query}
 }
 
+// NewQueryWithArgs returns a new query carrying key/value arguments
+func NewQueryWithArgs(caller id.Identity, target id.Identity, query string, args QueryArgs) Query {
+	return &basicQuery{caller: caller, target: target, query: query, args: args.Clone()}
+}
+
 func (q *basicQuery) Caller() id.Identity {
 	return q.caller
 }
@@ -36,3 +43,8 @@ func (q *basicQuery) Target() id.Identity {
 func (q *basicQuery) Query() string {
 	return q.query
 }
+
+// Args returns the arguments of the query
+func (q *basicQuery) Args() QueryArgs {
+	return q.args
+}
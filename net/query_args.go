@@ -0,0 +1,114 @@
+package net
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// QueryArgs holds key/value arguments of a query
+type QueryArgs map[string]string
+
+var ErrInvalidQueryArgs = errors.New("invalid query arguments")
+
+// Get returns the value of the argument or an empty string if the argument is not set
+func (args QueryArgs) Get(key string) string {
+	return args[key]
+}
+
+// Has returns true if the argument is set
+func (args QueryArgs) Has(key string) bool {
+	_, found := args[key]
+	return found
+}
+
+// Pairs returns the arguments as a flat list of keys and values sorted by key
+func (args QueryArgs) Pairs() []string {
+	var keys = make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var list = make([]string, 0, 2*len(args))
+	for _, k := range keys {
+		list = append(list, k, args[k])
+	}
+	return list
+}
+
+// Clone returns a copy of the arguments
+func (args QueryArgs) Clone() QueryArgs {
+	if args == nil {
+		return nil
+	}
+	var c = make(QueryArgs, len(args))
+	for k, v := range args {
+		c[k] = v
+	}
+	return c
+}
+
+// QueryArgsFromPairs returns arguments built from a flat list of keys and values.
+// Errors: ErrInvalidQueryArgs
+func QueryArgsFromPairs(pairs []string) (QueryArgs, error) {
+	if len(pairs)%2 != 0 {
+		return nil, ErrInvalidQueryArgs
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	var args = make(QueryArgs, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		args[pairs[i]] = pairs[i+1]
+	}
+	return args, nil
+}
+
+// QueryString returns the query with its arguments in URL query format, for example "storage.read?id=abc".
+// Protocols that carry queries as a plain string use this format to pass arguments. A query without arguments
+// is returned unchanged.
+func QueryString(query string, args QueryArgs) string {
+	if len(args) == 0 {
+		return query
+	}
+
+	var values = url.Values{}
+	for k, v := range args {
+		values.Set(k, v)
+	}
+	return query + "?" + values.Encode()
+}
+
+// ParseQueryString splits a string in the format returned by QueryString into the query and its arguments.
+// Encoded arguments never contain a question mark, so the query is everything before the last one. The string
+// is only split if everything after the last question mark is a list of key=value pairs, so that queries
+// without arguments, like "what?" or "ask?why", are returned unchanged.
+func ParseQueryString(s string) (string, QueryArgs) {
+	i := strings.LastIndex(s, "?")
+	if i < 0 {
+		return s, nil
+	}
+	query, rawArgs := s[:i], s[i+1:]
+
+	for _, pair := range strings.Split(rawArgs, "&") {
+		if !strings.Contains(pair, "=") {
+			return s, nil
+		}
+	}
+
+	values, err := url.ParseQuery(rawArgs)
+	if err != nil {
+		return s, nil
+	}
+
+	var args = make(QueryArgs, len(values))
+	for k, v := range values {
+		if len(v) > 0 {
+			args[k] = v[0]
+		}
+	}
+	return query, args
+}
@@ -0,0 +1,20 @@
+package net
+
+import "testing"
+
+func TestQueryString(t *testing.T) {
+	for _, q := range []string{"storage.read", "what?", "ask?why", "a?b?c"} {
+		if s := QueryString(q, nil); s != q {
+			t.Fatalf("query %q without arguments became %q", q, s)
+		}
+		if query, args := ParseQueryString(q); query != q || len(args) != 0 {
+			t.Fatalf("parsed %q as %q with %v", q, query, args)
+		}
+	}
+
+	var args = QueryArgs{"id": "a?b=c&d"}
+	query, parsed := ParseQueryString(QueryString("ask?why", args))
+	if query != "ask?why" || len(parsed) != 1 || parsed["id"] != args["id"] {
+		t.Fatalf("parsed %q with %v", query, parsed)
+	}
+}
@@ -10,7 +10,8 @@ type Router interface {
 }
 
 type Hints struct {
-	Origin string
+	Origin    string
+	Multipath bool // spread the traffic across all available links to the target
 }
 
 // Accept accepts the query and runs the handler in a new goroutine.
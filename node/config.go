@@ -1,10 +1,19 @@
 package node
 
+import (
+	"github.com/cryptopunkscc/astrald/node/network"
+	"github.com/cryptopunkscc/astrald/node/tracker"
+)
+
 const configName = "node"
 
 type Config struct {
-	Identity string   `yaml:"identity"`
-	Modules  []string `yaml:"modules"`
+	Identity string         `yaml:"identity"`
+	Modules  []string       `yaml:"modules"`
+	Network  network.Config `yaml:"network"`
+	Tracker  tracker.Config `yaml:"tracker"`
 }
 
-var defaultConfig = Config{}
+var defaultConfig = Config{
+	Tracker: tracker.DefaultConfig,
+}
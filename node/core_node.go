@@ -79,7 +79,7 @@ func NewCoreNode(rootDir string) (*CoreNode, error) {
 	}
 
 	// tracker
-	node.tracker, err = tracker.NewCoreTracker(node.assets, node.infra, node.log, &node.events)
+	node.tracker, err = tracker.NewCoreTracker(node.assets, node.infra, node.config.Tracker, node.log, &node.events)
 	if err != nil {
 		return nil, err
 	}
@@ -96,10 +96,11 @@ func NewCoreNode(rootDir string) (*CoreNode, error) {
 	node.services = services.NewCoreServices(&node.events, node.log)
 
 	// network
-	node.network, err = network.NewCoreNetwork(node, &node.events, node.log)
+	node.network, err = network.NewCoreNetwork(node, node.config.Network, &node.events, node.log)
 	if err != nil {
 		return nil, fmt.Errorf("error setting up peer manager: %w", err)
 	}
+	node.network.SetDatagramHandler(node.services)
 
 	// modules
 	var enabled = node.config.Modules
@@ -7,7 +7,7 @@ type Config struct {
 }
 
 var defaultConfig = Config{
-	Drivers: []string{"inet", "gw"},
+	Drivers: []string{"inet", "udp", "gw"},
 }
 
 func (cfg Config) driversContain(driver string) bool {
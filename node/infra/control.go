@@ -0,0 +1,189 @@
+package infra
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"gopkg.in/yaml.v2"
+	"strings"
+)
+
+// EnableDriver adds the driver to the enabled drivers and starts it if the infra is running
+func (i *CoreInfra) EnableDriver(name string) error {
+	i.mu.Lock()
+	if _, found := i.networkDrivers[name]; !found {
+		i.mu.Unlock()
+		return ErrDriverNotFound
+	}
+
+	if i.config.driversContain(name) {
+		i.mu.Unlock()
+		return ErrDriverEnabled
+	}
+
+	i.config.Drivers = append(append([]string{}, i.config.Drivers...), name)
+
+	if i.ctx != nil {
+		i.startDriver(name)
+	}
+	i.mu.Unlock()
+
+	i.log.Info("network %s enabled", name)
+
+	return i.saveConfig()
+}
+
+// DisableDriver stops the driver and its listener and removes it from the enabled drivers
+func (i *CoreInfra) DisableDriver(name string) error {
+	i.mu.Lock()
+	if !i.config.driversContain(name) {
+		i.mu.Unlock()
+		return ErrDriverDisabled
+	}
+
+	var list = make([]string, 0, len(i.config.Drivers))
+	for _, d := range i.config.Drivers {
+		if d != name {
+			list = append(list, d)
+		}
+	}
+	i.config.Drivers = list
+
+	done := i.stopDriver(name)
+	i.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+
+	i.log.Info("network %s disabled", name)
+
+	return i.saveConfig()
+}
+
+// ConfigureDriver updates the driver's config file with the settings and reloads the driver. Values are
+// parsed as YAML, so numbers, booleans and lists keep their types.
+func (i *CoreInfra) ConfigureDriver(name string, settings map[string]string) error {
+	injector, found := drivers[name]
+	if !found {
+		return ErrDriverNotFound
+	}
+
+	var config = make(map[string]any)
+	if err := i.assets.LoadYAML(name, &config); err != nil && !errors.Is(err, assets.ErrNotFound) {
+		return err
+	}
+
+	for key, s := range settings {
+		var value any
+		if err := yaml.Unmarshal([]byte(s), &value); err != nil {
+			return err
+		}
+		config[key] = value
+	}
+
+	if err := i.validateConfig(name, injector, config); err != nil {
+		return err
+	}
+
+	if err := i.assets.StoreYAML(name, config); err != nil {
+		return err
+	}
+
+	return i.ReloadDriver(name)
+}
+
+// ReloadDriver stops the driver, loads it again with a fresh config and restarts it if it's enabled. If the
+// driver fails to load, the previous instance is restored.
+func (i *CoreInfra) ReloadDriver(name string) error {
+	injector, found := drivers[name]
+	if !found {
+		return ErrDriverNotFound
+	}
+
+	i.mu.Lock()
+	done := i.stopDriver(name)
+	old, loaded := i.networkDrivers[name]
+	delete(i.networkDrivers, name)
+	i.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+
+	err := injector.Inject(i, i.assets, i.log.Tag(name))
+
+	i.mu.Lock()
+	if _, found := i.networkDrivers[name]; !found && err != nil && loaded {
+		i.networkDrivers[name] = old
+	}
+	if _, found := i.networkDrivers[name]; found && i.ctx != nil && i.config.driversContain(name) {
+		i.startDriver(name)
+	}
+	i.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	i.log.Info("network %s reloaded", name)
+
+	return nil
+}
+
+// validateConfig loads the driver with the config without adding it to the infra, so that invalid values
+// are reported before the config is saved
+func (i *CoreInfra) validateConfig(name string, injector DriverInjector, config map[string]any) error {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	var store = &configStore{Store: i.assets, name: name, data: data}
+
+	err = injector.Inject(&configInfra{i}, store, log.NewLogger(log.NewPrinterSplitter()))
+	if err != nil {
+		return err
+	}
+
+	return store.err
+}
+
+// configInfra is an infra that discards added drivers
+type configInfra struct {
+	*CoreInfra
+}
+
+func (i *configInfra) AddDriver(string, Driver) error {
+	return nil
+}
+
+// configStore serves a pending driver config and keeps the first error hit while parsing it, since drivers
+// only log config errors
+type configStore struct {
+	assets.Store
+	name string
+	data []byte
+	err  error
+}
+
+func (s *configStore) LoadYAML(name string, out interface{}) error {
+	if strings.TrimSuffix(name, ".yaml") != s.name {
+		return s.Store.LoadYAML(name, out)
+	}
+
+	err := yaml.UnmarshalStrict(s.data, out)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+
+	return err
+}
+
+func (i *CoreInfra) saveConfig() error {
+	i.mu.RLock()
+	var config = i.config
+	i.mu.RUnlock()
+
+	return i.assets.StoreYAML(configName, config)
+}
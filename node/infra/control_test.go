@@ -0,0 +1,223 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testDriver counts running instances of itself and its listeners
+type testDriver struct {
+	running   atomic.Int32
+	listening atomic.Int32
+}
+
+func (drv *testDriver) Run(ctx context.Context) error {
+	drv.running.Add(1)
+	defer drv.running.Add(-1)
+	<-ctx.Done()
+	return nil
+}
+
+func (drv *testDriver) Listen(ctx context.Context) (<-chan net.Conn, error) {
+	var ch = make(chan net.Conn)
+	drv.listening.Add(1)
+	go func() {
+		<-ctx.Done()
+		drv.listening.Add(-1)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out")
+}
+
+func TestEnableDisableDriver(t *testing.T) {
+	var l = log.NewLogger(log.NewPrinterSplitter())
+
+	store, err := assets.NewFileStore(t.TempDir(), l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var i = &CoreInfra{
+		assets:         store,
+		networkDrivers: make(map[string]Driver),
+		running:        make(map[string]*runningDriver),
+		config:         Config{Drivers: []string{}},
+		log:            l,
+	}
+
+	var drv = &testDriver{}
+	if err := i.AddDriver("test", drv); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var done = make(chan struct{})
+	go func() {
+		i.Run(ctx)
+		close(done)
+	}()
+
+	output, err := i.Listen(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.EnableDriver("test"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return drv.running.Load() == 1 && drv.listening.Load() == 1 })
+
+	if _, found := i.Drivers()["test"]; !found {
+		t.Fatal("driver not enabled")
+	}
+
+	if err := i.DisableDriver("test"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return drv.running.Load() == 0 && drv.listening.Load() == 0 })
+
+	if _, found := i.Drivers()["test"]; found {
+		t.Fatal("driver not disabled")
+	}
+	if err := i.DisableDriver("test"); err != ErrDriverDisabled {
+		t.Fatalf("expected %v, got %v", ErrDriverDisabled, err)
+	}
+
+	// the config is saved
+	var config Config
+	if err := store.LoadYAML(configName, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Drivers) != 0 {
+		t.Fatalf("unexpected drivers %v", config.Drivers)
+	}
+
+	if err := i.EnableDriver("test"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return drv.running.Load() == 1 && drv.listening.Load() == 1 })
+
+	cancel()
+	<-done
+
+	select {
+	case _, ok := <-output:
+		if ok {
+			t.Fatal("unexpected connection")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("output not closed")
+	}
+}
+
+// testInjector loads a testDriver and refuses negative ports
+type testInjector struct{}
+
+func (testInjector) Inject(i Infra, assets assets.Store, l *log.Logger) error {
+	var config struct {
+		Port int `yaml:"port"`
+	}
+	if err := assets.LoadYAML("testcfg", &config); err != nil {
+		l.Error("error reading config: %s", err)
+	}
+	if config.Port < 0 {
+		return errors.New("invalid port")
+	}
+	return i.AddDriver("testcfg", &testDriver{})
+}
+
+// formatInjections counts how many times testInjector added its log formatters
+var formatInjections int
+
+func (testInjector) InjectFormats(l *log.Logger) {
+	formatInjections++
+}
+
+func TestConfigureDriver(t *testing.T) {
+	var l = log.NewLogger(log.NewPrinterSplitter())
+
+	store, err := assets.NewFileStore(t.TempDir(), l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the driver stays registered across repeated runs of the test
+	if _, found := drivers["testcfg"]; !found {
+		if err := RegisterDriver("testcfg", testInjector{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	formatInjections = 0
+
+	var i = &CoreInfra{
+		assets:         store,
+		networkDrivers: make(map[string]Driver),
+		running:        make(map[string]*runningDriver),
+		config:         Config{Drivers: []string{}},
+		log:            l,
+	}
+
+	if err := i.loadDrivers(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.ConfigureDriver("testcfg", map[string]string{"port": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	var drv = i.LoadedDrivers()["testcfg"]
+	if drv == nil {
+		t.Fatal("driver not loaded")
+	}
+
+	// invalid values are refused before the config is saved
+	for _, port := range []string{"-1", "abc"} {
+		if err := i.ConfigureDriver("testcfg", map[string]string{"port": port}); err == nil {
+			t.Fatalf("port %s accepted", port)
+		}
+	}
+	if err := i.ConfigureDriver("testcfg", map[string]string{"unknown": "1"}); err == nil {
+		t.Fatal("unknown key accepted")
+	}
+
+	var config map[string]any
+	if err := store.LoadYAML("testcfg", &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config) != 1 || config["port"] != 1 {
+		t.Fatalf("unexpected config %v", config)
+	}
+
+	// a failed reload keeps the old driver
+	if err := store.StoreYAML("testcfg", map[string]any{"port": -1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.ReloadDriver("testcfg"); err == nil {
+		t.Fatal("reload succeeded")
+	}
+	if i.LoadedDrivers()["testcfg"] != drv {
+		t.Fatal("driver not restored")
+	}
+
+	// reloads don't add log formatters again
+	if formatInjections != 1 {
+		t.Fatalf("log formatters added %d times", formatInjections)
+	}
+}
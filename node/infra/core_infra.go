@@ -18,14 +18,27 @@ type CoreInfra struct {
 	config         Config
 	assets         assets.Store
 	networkDrivers map[string]Driver
+	running        map[string]*runningDriver
+	listener       *listener // collects connections from all driver listeners, nil when not listening
+	ctx            context.Context
+	wg             sync.WaitGroup
+	mu             sync.RWMutex
 	log            *log.Logger
 }
 
+// runningDriver holds the state of a driver started by the infra
+type runningDriver struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
 func NewCoreInfra(node Node, assets assets.Store, log *log.Logger) (*CoreInfra, error) {
 	var i = &CoreInfra{
 		node:           node,
 		assets:         assets,
 		networkDrivers: make(map[string]Driver),
+		running:        make(map[string]*runningDriver),
 		config:         defaultConfig,
 		log:            log.Tag(logTag),
 	}
@@ -42,7 +55,8 @@ func NewCoreInfra(node Node, assets assets.Store, log *log.Logger) (*CoreInfra,
 }
 
 func (i *CoreInfra) Run(ctx context.Context) error {
-	var wg sync.WaitGroup
+	i.mu.Lock()
+	i.ctx = ctx
 
 	var loaded []string
 	for name := range i.networkDrivers {
@@ -55,33 +69,73 @@ func (i *CoreInfra) Run(ctx context.Context) error {
 	)
 
 	for _, name := range i.config.Drivers {
-		name := name
-		if network, found := i.networkDrivers[name]; found {
-			wg.Add(1)
-			go func() {
-				defer debug.SaveLog(func(p any) {
-					i.log.Error("network driver %s panicked: %v", name, p)
-					debug.SigInt(p)
-				})
-
-				defer wg.Done()
-
-				if err := network.Run(ctx); err != nil {
-					i.log.Error("network %s error: %s", name, err)
-				} else {
-					i.log.Logv(1, "network %s done", name)
-				}
-			}()
+		if _, found := i.networkDrivers[name]; found {
+			i.startDriver(name)
 		} else {
 			i.log.Error("network driver not found: %s", name)
 		}
 	}
+	i.mu.Unlock()
+
+	<-ctx.Done()
 
-	wg.Wait()
+	i.wg.Wait()
 
 	return nil
 }
 
+// startDriver runs the driver and its listener if the infra is listening. Caller must hold the lock.
+func (i *CoreInfra) startDriver(name string) {
+	if i.ctx.Err() != nil {
+		return
+	}
+
+	var network = i.networkDrivers[name]
+
+	ctx, cancel := context.WithCancel(i.ctx)
+	var r = &runningDriver{
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	i.running[name] = r
+
+	i.wg.Add(1)
+	go func() {
+		defer debug.SaveLog(func(p any) {
+			i.log.Error("network driver %s panicked: %v", name, p)
+			debug.SigInt(p)
+		})
+
+		defer i.wg.Done()
+		defer close(r.done)
+
+		if err := network.Run(ctx); err != nil {
+			i.log.Error("network %s error: %s", name, err)
+		} else {
+			i.log.Logv(1, "network %s done", name)
+		}
+	}()
+
+	if i.listener != nil {
+		i.startListener(name, r.ctx)
+	}
+}
+
+// stopDriver cancels the driver's context and returns a channel closed when the driver returns or nil if the
+// driver wasn't running. Caller must hold the lock.
+func (i *CoreInfra) stopDriver(name string) <-chan struct{} {
+	r, found := i.running[name]
+	if !found {
+		return nil
+	}
+
+	delete(i.running, name)
+	r.cancel()
+
+	return r.done
+}
+
 func (i *CoreInfra) Node() Node {
 	return i.node
 }
@@ -38,12 +38,12 @@ func (i *CoreInfra) Dial(ctx context.Context, e net.Endpoint) (conn net.Conn, er
 }
 
 func (i *CoreInfra) dial(ctx context.Context, addr net.Endpoint) (net.Conn, error) {
-	if !i.config.driversContain(addr.Network()) {
-		return nil, ErrUnsupportedNetwork
-	}
-
+	i.mu.RLock()
+	enabled := i.config.driversContain(addr.Network())
 	network, found := i.networkDrivers[addr.Network()]
-	if !found {
+	i.mu.RUnlock()
+
+	if !enabled || !found {
 		return nil, ErrUnsupportedNetwork
 	}
 
@@ -18,6 +18,12 @@ type DriverInjector interface {
 	Inject(Infra, assets.Store, *log.Logger) error
 }
 
+// FormatInjector is implemented by driver injectors that add log formatters for their endpoints. Formatters are
+// added once, when the infra loads its drivers, since a driver is injected again every time it's reloaded.
+type FormatInjector interface {
+	InjectFormats(*log.Logger)
+}
+
 func RegisterDriver(name string, driver DriverInjector) error {
 	if drivers == nil {
 		drivers = make(map[string]DriverInjector)
@@ -32,6 +38,9 @@ func RegisterDriver(name string, driver DriverInjector) error {
 }
 
 func (i *CoreInfra) AddDriver(name string, driver Driver) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
 	if _, found := i.networkDrivers[name]; found {
 		return errors.New("driver already added")
 	}
@@ -40,7 +49,11 @@ func (i *CoreInfra) AddDriver(name string, driver Driver) error {
 	return nil
 }
 
+// Drivers returns all enabled drivers
 func (i *CoreInfra) Drivers() map[string]Driver {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
 	enabledDrivers := make(map[string]Driver)
 	for k, v := range i.networkDrivers {
 		if i.config.driversContain(k) {
@@ -50,8 +63,24 @@ func (i *CoreInfra) Drivers() map[string]Driver {
 	return enabledDrivers
 }
 
+// LoadedDrivers returns all loaded drivers, enabled or not
+func (i *CoreInfra) LoadedDrivers() map[string]Driver {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var loaded = make(map[string]Driver, len(i.networkDrivers))
+	for k, v := range i.networkDrivers {
+		loaded[k] = v
+	}
+	return loaded
+}
+
 func (i *CoreInfra) loadDrivers() error {
 	for name, injector := range drivers {
+		if f, ok := injector.(FormatInjector); ok {
+			f.InjectFormats(i.log.Root())
+		}
+
 		if err := injector.Inject(i, i.assets, i.log.Tag(name)); err != nil {
 			i.log.Errorv(1, "error loading network driver %s: %s", name, err)
 		}
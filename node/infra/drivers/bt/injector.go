@@ -7,6 +7,7 @@ import (
 )
 
 var _ infra.DriverInjector = &Injector{}
+var _ infra.FormatInjector = &Injector{}
 
 type Injector struct{}
 
@@ -20,7 +21,12 @@ func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error
 		assets.LoadYAML(DriverName, &drv.config)
 	}
 
-	l.Root().PushFormatFunc(func(v any) ([]log.Op, bool) {
+	return i.AddDriver(DriverName, drv)
+}
+
+// InjectFormats adds a log formatter for the driver's endpoints
+func (*Injector) InjectFormats(l *log.Logger) {
+	l.PushFormatFunc(func(v any) ([]log.Op, bool) {
 		ep, ok := v.(Endpoint)
 		if !ok {
 			return nil, false
@@ -32,8 +38,6 @@ func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error
 			log.OpReset{},
 		}, true
 	})
-
-	return i.AddDriver(DriverName, drv)
 }
 
 func init() {
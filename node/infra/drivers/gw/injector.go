@@ -7,6 +7,7 @@ import (
 )
 
 var _ infra.DriverInjector = &Injector{}
+var _ infra.FormatInjector = &Injector{}
 
 type Injector struct{}
 
@@ -17,7 +18,14 @@ func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error
 		log:    l,
 	}
 
-	l.Root().PushFormatFunc(func(v any) ([]log.Op, bool) {
+	_ = assets.LoadYAML(DriverName, &drv.config)
+
+	return i.AddDriver(DriverName, drv)
+}
+
+// InjectFormats adds a log formatter for the driver's endpoints
+func (*Injector) InjectFormats(l *log.Logger) {
+	l.PushFormatFunc(func(v any) ([]log.Op, bool) {
 		ep, ok := v.(Endpoint)
 		if !ok {
 			return nil, false
@@ -45,10 +53,6 @@ func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error
 
 		return ops, true
 	})
-
-	_ = assets.LoadYAML(DriverName, &drv.config)
-
-	return i.AddDriver(DriverName, drv)
 }
 
 func init() {
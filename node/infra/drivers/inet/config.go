@@ -5,8 +5,10 @@ const (
 )
 
 type Config struct {
-	PublicAddr []string `yaml:"public_addr"`
-	ListenPort int      `yaml:"listen_port"`
+	PublicAddr  []string `yaml:"public_addr"`
+	ListenPort  int      `yaml:"listen_port"`
+	PortMapping bool     `yaml:"port_mapping"` // request a port mapping from the router via UPnP, NAT-PMP or PCP
+	Proxy       string   `yaml:"proxy"`        // dial public endpoints through socks5://, socks5h:// or http:// proxy
 }
 
 var defaultConfig = Config{
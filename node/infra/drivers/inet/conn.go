@@ -10,6 +10,7 @@ var _ net.Conn = Conn{}
 type Conn struct {
 	_net.Conn
 	outbound bool
+	target   Endpoint // remote endpoint of proxied connections, whose remote address is the proxy's
 }
 
 // newConn wraps a standard net.Conn into a astral's net.Conn with the addition of boundness
@@ -26,6 +27,9 @@ func (conn Conn) LocalEndpoint() net.Endpoint {
 }
 
 func (conn Conn) RemoteEndpoint() net.Endpoint {
+	if !conn.target.IsZero() {
+		return conn.target
+	}
 	e, _ := Parse(conn.Conn.RemoteAddr().String())
 	return e
 }
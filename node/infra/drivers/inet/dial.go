@@ -30,6 +30,11 @@ func (drv *Driver) Dial(ctx context.Context, endpoint net.Endpoint) (net.Conn, e
 		config.Timeout = dialTimeoutPrivate
 	}
 
+	// LAN and loopback addresses are usually unreachable through a proxy
+	if drv.proxy != nil && inetEndpoint.IsPublicUnicast() {
+		return drv.dialProxy(ctx, inetEndpoint)
+	}
+
 	tcpConn, err := config.DialContext(ctx, "tcp", endpoint.String())
 	if err != nil {
 		return nil, err
@@ -38,6 +43,22 @@ func (drv *Driver) Dial(ctx context.Context, endpoint net.Endpoint) (net.Conn, e
 	return newConn(tcpConn, true), nil
 }
 
+// dialProxy opens a connection to the endpoint through the configured proxy
+func (drv *Driver) dialProxy(ctx context.Context, endpoint Endpoint) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	tcpConn, err := drv.proxy.DialContext(ctx, "tcp", endpoint.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var conn = newConn(tcpConn, true)
+	conn.target = endpoint
+
+	return conn, nil
+}
+
 func (drv *Driver) DialFrom(ctx context.Context, addr Endpoint, from Endpoint) (net.Conn, error) {
 	var err error
 	var config = dialConfig
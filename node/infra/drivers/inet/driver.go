@@ -5,6 +5,7 @@ import (
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/infra"
+	"github.com/cryptopunkscc/astrald/node/infra/proxy"
 	"sync"
 )
 
@@ -17,10 +18,16 @@ type Driver struct {
 	infra       infra.Infra
 	log         *log.Logger
 	publicAddrs []net.Endpoint
+	mappedAddr  *Endpoint    // public endpoint mapped on the router
+	proxy       proxy.Dialer // proxy for outbound dials, nil if dialing directly
 	mu          sync.Mutex
 }
 
 func (drv *Driver) Run(ctx context.Context) error {
+	if drv.config.PortMapping {
+		drv.runPortMapping(ctx)
+	}
+
 	<-ctx.Done()
 	return nil
 }
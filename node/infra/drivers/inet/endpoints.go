@@ -39,5 +39,10 @@ func (drv *Driver) Endpoints() []net.Endpoint {
 	// Add custom addresses
 	list = append(list, drv.publicAddrs...)
 
+	// Add the address mapped on the router
+	if e := drv.mappedEndpoint(); e != nil {
+		list = append(list, *e)
+	}
+
 	return list
 }
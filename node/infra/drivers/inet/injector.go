@@ -1,14 +1,17 @@
 package inet
 
 import (
+	"fmt"
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/assets"
 	"github.com/cryptopunkscc/astrald/node/infra"
+	"github.com/cryptopunkscc/astrald/node/infra/proxy"
 	"strconv"
 )
 
 var _ infra.DriverInjector = &Injector{}
+var _ infra.FormatInjector = &Injector{}
 
 type Injector struct{}
 
@@ -37,7 +40,21 @@ func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error
 		drv.publicAddrs = append(drv.publicAddrs, addr)
 	}
 
-	l.Root().PushFormatFunc(func(v any) ([]log.Op, bool) {
+	// Set up the proxy. Never fall back to direct dials if the configured proxy is invalid.
+	if drv.config.Proxy != "" {
+		p, err := proxy.New(drv.config.Proxy, &dialConfig)
+		if err != nil {
+			return fmt.Errorf("invalid proxy '%s': %w", drv.config.Proxy, err)
+		}
+		drv.proxy = p
+	}
+
+	return i.AddDriver(DriverName, drv)
+}
+
+// InjectFormats adds a log formatter for the driver's endpoints
+func (*Injector) InjectFormats(l *log.Logger) {
+	l.PushFormatFunc(func(v any) ([]log.Op, bool) {
 		ep, ok := v.(Endpoint)
 		if !ok {
 			return nil, false
@@ -69,8 +86,6 @@ func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error
 
 		return ops, true
 	})
-
-	return i.AddDriver(DriverName, drv)
 }
 
 func init() {
@@ -0,0 +1,155 @@
+package inet
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/node/infra/drivers/inet/portmap"
+	"github.com/cryptopunkscc/astrald/node/infra/drivers/ip"
+	"time"
+)
+
+const (
+	portMappingLifetime = time.Hour
+	portMappingRetry    = 5 * time.Minute
+	portMappingTimeout  = 10 * time.Second
+)
+
+// runPortMapping keeps a mapping of the listen port on the router for as long as the context is active.
+// The mapping is requested again whenever local addresses change, since it points to the old address.
+func (drv *Driver) runPortMapping(ctx context.Context) {
+	var changes = addrChanges(ctx, drv.infra.Node().Events())
+
+	for {
+		mapCtx, cancel := context.WithCancel(ctx)
+		var done = make(chan error, 1)
+		go func() {
+			done <- drv.mapPort(mapCtx)
+		}()
+
+		select {
+		case err := <-done:
+			cancel()
+			if err != nil {
+				drv.log.Errorv(1, "port mapping: %s", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-changes:
+			case <-time.After(portMappingRetry):
+			}
+
+		case <-changes:
+			cancel()
+			<-done
+			drv.log.Logv(1, "local addresses changed, renewing port mapping")
+
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return
+		}
+	}
+}
+
+// addrChanges returns a channel that receives a value whenever an address is added or removed
+func addrChanges(ctx context.Context, q *events.Queue) <-chan struct{} {
+	var ch = make(chan struct{}, 1)
+
+	go func() {
+		for event := range q.Subscribe(ctx) {
+			switch event.(type) {
+			case ip.EventAddrAdded, ip.EventAddrRemoved:
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return ch
+}
+
+// mapPort requests a port mapping and renews it until the context is done or the renewal fails
+func (drv *Driver) mapPort(ctx context.Context) error {
+	discoverCtx, cancel := context.WithTimeout(ctx, portMappingTimeout)
+	mapper, err := portmap.Discover(discoverCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	var port = drv.ListenPort()
+	var mapping portmap.Mapping
+
+	defer func() {
+		if mapping.ExternalPort == 0 {
+			return
+		}
+
+		drv.setMappedEndpoint(nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), portMappingTimeout)
+		defer cancel()
+		if err := mapper.DeleteMapping(ctx, mapping); err != nil {
+			drv.log.Errorv(1, "error removing port mapping: %s", err)
+		}
+	}()
+
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, portMappingTimeout)
+		m, err := mapper.AddMapping(reqCtx, portmap.TCP, port, port, portMappingLifetime)
+		cancel()
+		if err != nil {
+			return err
+		}
+		mapping = m
+
+		var endpoint = Endpoint{ip: m.ExternalIP.To4(), port: uint16(m.ExternalPort)}
+		if endpoint.ip == nil {
+			endpoint = Endpoint{ver: ipv6, ip: m.ExternalIP, port: uint16(m.ExternalPort)}
+		}
+
+		if drv.setMappedEndpoint(&endpoint) {
+			drv.log.Info("%s mapped port %d to %v", mapper.Name(), port, endpoint)
+		}
+
+		// renew halfway through the lifetime
+		var renew = m.Lifetime / 2
+		if renew <= 0 {
+			renew = portMappingLifetime / 2
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(renew):
+		}
+	}
+}
+
+func (drv *Driver) mappedEndpoint() *Endpoint {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+
+	return drv.mappedAddr
+}
+
+// setMappedEndpoint sets the mapped endpoint and returns true if it changed. Mappings to non-public
+// addresses (like with double NAT) are not published.
+func (drv *Driver) setMappedEndpoint(e *Endpoint) bool {
+	if e != nil && !e.IsPublicUnicast() {
+		e = nil
+	}
+
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+
+	var changed = (e == nil) != (drv.mappedAddr == nil) ||
+		(e != nil && e.String() != drv.mappedAddr.String())
+	drv.mappedAddr = e
+
+	return changed
+}
@@ -0,0 +1,47 @@
+//go:build linux
+
+package portmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"strings"
+)
+
+// DefaultGateway returns the IPv4 address of the default gateway
+func DefaultGateway() (net.IP, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var scanner = bufio.NewScanner(file)
+	scanner.Scan() // skip the header
+
+	for scanner.Scan() {
+		var fields = strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+
+		// the kernel prints addresses in host byte order
+		var ip = make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		if ip.IsUnspecified() {
+			continue
+		}
+
+		return ip, nil
+	}
+
+	return nil, ErrNoGateway
+}
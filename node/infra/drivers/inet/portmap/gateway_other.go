@@ -0,0 +1,10 @@
+//go:build !linux
+
+package portmap
+
+import "net"
+
+// DefaultGateway returns the IPv4 address of the default gateway
+func DefaultGateway() (net.IP, error) {
+	return nil, ErrNoGateway
+}
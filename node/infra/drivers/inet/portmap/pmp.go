@@ -0,0 +1,264 @@
+package portmap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	pmpPort         = 5351
+	pmpVersion      = 0
+	pcpVersion      = 2
+	pmpInitialDelay = 250 * time.Millisecond
+	pmpRetries      = 4
+)
+
+// NAT-PMP opcodes (RFC 6886)
+const (
+	pmpOpExternalAddr = 0
+	pmpOpMapUDP       = 1
+	pmpOpMapTCP       = 2
+	pmpOpResponse     = 128
+)
+
+// PCP opcodes (RFC 6887)
+const (
+	pcpOpMap      = 1
+	pcpOpResponse = 0x80
+)
+
+const (
+	resultSuccess      = 0
+	resultUnsuppVer    = 1
+	pcpRequestLen      = 24 + 36
+	pcpResponseLen     = 24 + 36
+	ipProtoTCP         = 6
+	ipProtoUDP         = 17
+	pmpAddrResponseLen = 12
+	pmpMapResponseLen  = 16
+)
+
+var _ Mapper = &NATPMP{}
+var _ Mapper = &PCP{}
+
+// NATPMP is a NAT-PMP client
+type NATPMP struct {
+	gateway *net.UDPAddr
+}
+
+// PCP is a Port Control Protocol client
+type PCP struct {
+	gateway *net.UDPAddr
+	nonce   [12]byte
+}
+
+// DiscoverPMP checks whether the gateway speaks NAT-PMP or PCP and returns a matching mapper
+func DiscoverPMP(ctx context.Context, gateway *net.UDPAddr) (Mapper, error) {
+	res, err := pmpRequest(ctx, gateway, []byte{pmpVersion, pmpOpExternalAddr})
+	if err != nil {
+		return nil, err
+	}
+
+	// PCP servers answer NAT-PMP requests with their own version or an unsupported version error
+	if res[0] == pcpVersion || binary.BigEndian.Uint16(res[2:]) == resultUnsuppVer {
+		return NewPCP(gateway), nil
+	}
+
+	return NewNATPMP(gateway), nil
+}
+
+func NewNATPMP(gateway *net.UDPAddr) *NATPMP {
+	return &NATPMP{gateway: gateway}
+}
+
+func (c *NATPMP) Name() string {
+	return "NAT-PMP"
+}
+
+// ExternalIP returns the public address of the gateway
+func (c *NATPMP) ExternalIP(ctx context.Context) (net.IP, error) {
+	res, err := pmpRequest(ctx, c.gateway, []byte{pmpVersion, pmpOpExternalAddr})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPMPResponse(res, pmpOpExternalAddr, pmpAddrResponseLen); err != nil {
+		return nil, err
+	}
+
+	return net.IPv4(res[8], res[9], res[10], res[11]), nil
+}
+
+func (c *NATPMP) AddMapping(ctx context.Context, proto Protocol, internalPort int, externalPort int, lifetime time.Duration) (Mapping, error) {
+	ip, err := c.ExternalIP(ctx)
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	m, err := c.mapPort(ctx, proto, internalPort, externalPort, lifetime)
+	if err != nil {
+		return Mapping{}, err
+	}
+	m.ExternalIP = ip
+
+	return m, nil
+}
+
+func (c *NATPMP) DeleteMapping(ctx context.Context, m Mapping) error {
+	// a mapping request with zero lifetime and external port deletes the mapping
+	_, err := c.mapPort(ctx, m.Protocol, m.InternalPort, 0, 0)
+	return err
+}
+
+func (c *NATPMP) mapPort(ctx context.Context, proto Protocol, internalPort int, externalPort int, lifetime time.Duration) (Mapping, error) {
+	var op byte = pmpOpMapTCP
+	if proto == UDP {
+		op = pmpOpMapUDP
+	}
+
+	var req = make([]byte, 12)
+	req[0] = pmpVersion
+	req[1] = op
+	binary.BigEndian.PutUint16(req[4:], uint16(internalPort))
+	binary.BigEndian.PutUint16(req[6:], uint16(externalPort))
+	binary.BigEndian.PutUint32(req[8:], uint32(lifetime/time.Second))
+
+	res, err := pmpRequest(ctx, c.gateway, req)
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	if err := checkPMPResponse(res, op, pmpMapResponseLen); err != nil {
+		return Mapping{}, err
+	}
+
+	return Mapping{
+		Protocol:     proto,
+		InternalPort: int(binary.BigEndian.Uint16(res[8:])),
+		ExternalPort: int(binary.BigEndian.Uint16(res[10:])),
+		Lifetime:     time.Duration(binary.BigEndian.Uint32(res[12:])) * time.Second,
+	}, nil
+}
+
+func NewPCP(gateway *net.UDPAddr) *PCP {
+	var c = &PCP{gateway: gateway}
+	rand.Read(c.nonce[:])
+	return c
+}
+
+func (c *PCP) Name() string {
+	return "PCP"
+}
+
+func (c *PCP) AddMapping(ctx context.Context, proto Protocol, internalPort int, externalPort int, lifetime time.Duration) (Mapping, error) {
+	return c.mapPort(ctx, proto, internalPort, externalPort, lifetime)
+}
+
+func (c *PCP) DeleteMapping(ctx context.Context, m Mapping) error {
+	_, err := c.mapPort(ctx, m.Protocol, m.InternalPort, 0, 0)
+	return err
+}
+
+func (c *PCP) mapPort(ctx context.Context, proto Protocol, internalPort int, externalPort int, lifetime time.Duration) (Mapping, error) {
+	clientIP, err := localIPFor(c.gateway.IP)
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	var ipProto byte = ipProtoTCP
+	if proto == UDP {
+		ipProto = ipProtoUDP
+	}
+
+	var req = make([]byte, pcpRequestLen)
+	req[0] = pcpVersion
+	req[1] = pcpOpMap
+	binary.BigEndian.PutUint32(req[4:], uint32(lifetime/time.Second))
+	copy(req[8:24], clientIP.To16())
+	copy(req[24:36], c.nonce[:])
+	req[36] = ipProto
+	binary.BigEndian.PutUint16(req[40:], uint16(internalPort))
+	binary.BigEndian.PutUint16(req[42:], uint16(externalPort))
+	copy(req[44:60], net.IPv4zero.To16())
+
+	res, err := pmpRequest(ctx, c.gateway, req)
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	if len(res) < pcpResponseLen || res[0] != pcpVersion || res[1] != pcpOpResponse|pcpOpMap {
+		return Mapping{}, ErrInvalidPacket
+	}
+	if res[3] != resultSuccess {
+		return Mapping{}, fmt.Errorf("pcp error %d", res[3])
+	}
+	if [12]byte(res[24:36]) != c.nonce {
+		return Mapping{}, ErrInvalidPacket
+	}
+
+	return Mapping{
+		Protocol:     proto,
+		InternalPort: int(binary.BigEndian.Uint16(res[40:])),
+		ExternalIP:   net.IP(append([]byte(nil), res[44:60]...)),
+		ExternalPort: int(binary.BigEndian.Uint16(res[42:])),
+		Lifetime:     time.Duration(binary.BigEndian.Uint32(res[4:])) * time.Second,
+	}, nil
+}
+
+func checkPMPResponse(res []byte, op byte, size int) error {
+	if len(res) < size || res[0] != pmpVersion || res[1] != pmpOpResponse+op {
+		return ErrInvalidPacket
+	}
+	if code := binary.BigEndian.Uint16(res[2:]); code != resultSuccess {
+		return fmt.Errorf("nat-pmp error %d", code)
+	}
+	return nil
+}
+
+// pmpRequest sends a request to the gateway and waits for a response, retransmitting with an exponential backoff
+func pmpRequest(ctx context.Context, gateway *net.UDPAddr, req []byte) ([]byte, error) {
+	conn, err := net.DialUDP("udp", nil, gateway)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var buf = make([]byte, 1100)
+	var delay = pmpInitialDelay
+
+	for i := 0; i < pmpRetries; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+
+		var deadline = time.Now().Add(delay)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break
+			}
+
+			// both protocols put the version, opcode and result code in the first 4 bytes
+			if n >= 4 {
+				return append([]byte(nil), buf[:n]...), nil
+			}
+		}
+
+		delay *= 2
+	}
+
+	return nil, ErrNoResponse
+}
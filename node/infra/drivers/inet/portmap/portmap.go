@@ -0,0 +1,71 @@
+// Package portmap requests port mappings from local routers using UPnP IGD, NAT-PMP or PCP.
+package portmap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+type Protocol string
+
+const (
+	TCP Protocol = "TCP"
+	UDP Protocol = "UDP"
+)
+
+var (
+	ErrNoGateway     = errors.New("default gateway not found")
+	ErrNoMapper      = errors.New("no port mapping service found")
+	ErrNoResponse    = errors.New("no response")
+	ErrInvalidPacket = errors.New("invalid response")
+)
+
+// Mapping describes a port mapping created on the router
+type Mapping struct {
+	Protocol     Protocol
+	InternalPort int
+	ExternalIP   net.IP
+	ExternalPort int
+	Lifetime     time.Duration // zero if the mapping doesn't expire
+}
+
+// Mapper wraps the methods of a port mapping protocol
+type Mapper interface {
+	// Name returns the name of the protocol
+	Name() string
+
+	// AddMapping creates or renews a mapping. The router may assign a different external port.
+	AddMapping(ctx context.Context, proto Protocol, internalPort int, externalPort int, lifetime time.Duration) (Mapping, error)
+
+	// DeleteMapping removes the mapping
+	DeleteMapping(ctx context.Context, m Mapping) error
+}
+
+// Discover returns a mapper for the first port mapping service found on the local network
+func Discover(ctx context.Context) (Mapper, error) {
+	if gateway, err := DefaultGateway(); err == nil {
+		var addr = &net.UDPAddr{IP: gateway, Port: pmpPort}
+		if m, err := DiscoverPMP(ctx, addr); err == nil {
+			return m, nil
+		}
+	}
+
+	if m, err := DiscoverUPnP(ctx); err == nil {
+		return m, nil
+	}
+
+	return nil, ErrNoMapper
+}
+
+// localIPFor returns the local IP address used to reach the remote host
+func localIPFor(host net.IP) (net.IP, error) {
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: host, Port: 9})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,235 @@
+package portmap
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testExternalIP = net.IPv4(203, 0, 113, 7)
+
+// fakePMP runs a NAT-PMP or PCP server on the loopback interface
+func fakePMP(t *testing.T, pcp bool) (*net.UDPAddr, *sync.Map) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var mappings = &sync.Map{}
+
+	go func() {
+		var buf = make([]byte, 1100)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			var req = buf[:n]
+			var res []byte
+
+			switch {
+			case pcp && req[0] != pcpVersion:
+				res = []byte{pcpVersion, pcpOpResponse | req[1], 0, resultUnsuppVer}
+
+			case pcp:
+				var lifetime = binary.BigEndian.Uint32(req[4:])
+				var internalPort = binary.BigEndian.Uint16(req[40:])
+				res = make([]byte, pcpResponseLen)
+				res[0] = pcpVersion
+				res[1] = pcpOpResponse | pcpOpMap
+				binary.BigEndian.PutUint32(res[4:], lifetime)
+				copy(res[24:44], req[24:44])
+				binary.BigEndian.PutUint16(res[42:], internalPort+1000)
+				copy(res[44:60], testExternalIP.To16())
+				mappings.Store(internalPort, lifetime)
+
+			case req[1] == pmpOpExternalAddr:
+				res = make([]byte, pmpAddrResponseLen)
+				res[1] = pmpOpResponse
+				copy(res[8:], testExternalIP.To4())
+
+			default:
+				var internalPort = binary.BigEndian.Uint16(req[4:])
+				var lifetime = binary.BigEndian.Uint32(req[8:])
+				res = make([]byte, pmpMapResponseLen)
+				res[1] = pmpOpResponse + req[1]
+				binary.BigEndian.PutUint16(res[8:], internalPort)
+				binary.BigEndian.PutUint16(res[10:], internalPort+1000)
+				binary.BigEndian.PutUint32(res[12:], lifetime)
+				mappings.Store(internalPort, lifetime)
+			}
+
+			conn.WriteToUDP(res, addr)
+		}
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr), mappings
+}
+
+func TestNATPMP(t *testing.T) {
+	addr, mappings := fakePMP(t, false)
+	testMapper(t, addr, "NAT-PMP", mappings)
+}
+
+func TestPCP(t *testing.T) {
+	addr, mappings := fakePMP(t, true)
+	testMapper(t, addr, "PCP", mappings)
+}
+
+func testMapper(t *testing.T, addr *net.UDPAddr, name string, mappings *sync.Map) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mapper, err := DiscoverPMP(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapper.Name() != name {
+		t.Fatalf("expected %s, got %s", name, mapper.Name())
+	}
+
+	m, err := mapper.AddMapping(ctx, TCP, 1791, 1791, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.ExternalIP.Equal(testExternalIP) || m.ExternalPort != 2791 || m.Lifetime != time.Hour {
+		t.Fatalf("unexpected mapping %+v", m)
+	}
+
+	if err := mapper.DeleteMapping(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	if lifetime, _ := mappings.Load(uint16(1791)); lifetime != uint32(0) {
+		t.Fatalf("mapping not deleted")
+	}
+}
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+        <deviceList>
+          <device>
+            <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+            <serviceList>
+              <service>
+                <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+                <controlURL>/ctl/IPConn</controlURL>
+              </service>
+            </serviceList>
+          </device>
+        </deviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+func TestUPnP(t *testing.T) {
+	var mu sync.Mutex
+	var mapped = map[string]string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/desc.xml" {
+			io.WriteString(w, testDescription)
+			return
+		}
+
+		var action = strings.Trim(r.Header.Get("SOAPAction"), `"`)
+		action = action[strings.Index(action, "#")+1:]
+
+		var req struct {
+			Body struct {
+				Action struct {
+					Args []struct {
+						XMLName xml.Name
+						Value   string `xml:",chardata"`
+					} `xml:",any"`
+				} `xml:",any"`
+			} `xml:"Body"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		xml.Unmarshal(body, &req)
+
+		var args = map[string]string{}
+		for _, a := range req.Body.Action.Args {
+			args[a.XMLName.Local] = a.Value
+		}
+
+		var out string
+		switch action {
+		case "GetExternalIPAddress":
+			out = "<NewExternalIPAddress>" + testExternalIP.String() + "</NewExternalIPAddress>"
+
+		case "AddPortMapping":
+			if args["NewLeaseDuration"] != "0" {
+				// behave like a router that only supports permanent mappings
+				w.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>`+
+					`<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+					`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>725</errorCode>`+
+					`<errorDescription>OnlyPermanentLeasesSupported</errorDescription></UPnPError>`+
+					`</detail></s:Fault></s:Body></s:Envelope>`)
+				return
+			}
+			mu.Lock()
+			mapped[args["NewExternalPort"]] = args["NewInternalClient"] + ":" + args["NewInternalPort"]
+			mu.Unlock()
+
+		case "DeletePortMapping":
+			mu.Lock()
+			delete(mapped, args["NewExternalPort"])
+			mu.Unlock()
+		}
+
+		fmt.Fprintf(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>`+
+			`<u:%sResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">%s</u:%sResponse>`+
+			`</s:Body></s:Envelope>`, action, out, action)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := NewUPnP(ctx, srv.URL+"/desc.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := c.AddMapping(ctx, TCP, 1791, 1791, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.ExternalIP.Equal(testExternalIP) || m.ExternalPort != 1791 || m.Lifetime != 0 {
+		t.Fatalf("unexpected mapping %+v", m)
+	}
+
+	mu.Lock()
+	target := mapped["1791"]
+	mu.Unlock()
+	if target != "127.0.0.1:1791" {
+		t.Fatalf("unexpected mapping target %q", target)
+	}
+
+	if err := c.DeleteMapping(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(mapped) != 0 {
+		t.Fatal("mapping not deleted")
+	}
+}
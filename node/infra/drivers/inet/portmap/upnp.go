@@ -0,0 +1,381 @@
+package portmap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ssdpAddr        = "239.255.255.250:1900"
+	ssdpTimeout     = 2 * time.Second
+	upnpDescription = "astrald"
+	maxResponseSize = 1 << 20
+)
+
+// UPnP error code returned by routers that can't expire mappings
+const errOnlyPermanentLeases = 725
+
+var searchTargets = []string{
+	"urn:schemas-upnp-org:device:InternetGatewayDevice:2",
+	"urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+}
+
+var connectionServices = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+var _ Mapper = &UPnP{}
+
+// UPnP is a client of the WAN connection service of an Internet Gateway Device
+type UPnP struct {
+	controlURL  string
+	serviceType string
+	localIP     net.IP
+	client      *http.Client
+}
+
+// SOAPError is an error returned by the gateway
+type SOAPError struct {
+	Code        int
+	Description string
+}
+
+func (e *SOAPError) Error() string {
+	return fmt.Sprintf("upnp error %d: %s", e.Code, e.Description)
+}
+
+// DiscoverUPnP searches the local network for an Internet Gateway Device
+func DiscoverUPnP(ctx context.Context) (*UPnP, error) {
+	locations, err := ssdpSearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, location := range locations {
+		if c, err := NewUPnP(ctx, location); err == nil {
+			return c, nil
+		}
+	}
+
+	return nil, ErrNoMapper
+}
+
+// NewUPnP returns a client for the gateway described by the document at the location
+func NewUPnP(ctx context.Context, location string) (*UPnP, error) {
+	var c = &UPnP{client: &http.Client{}}
+
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	var desc upnpRoot
+	if err := xml.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&desc); err != nil {
+		return nil, err
+	}
+
+	if desc.URLBase != "" {
+		if b, err := url.Parse(desc.URLBase); err == nil {
+			base = b
+		}
+	}
+
+	svc := desc.Device.findService(connectionServices)
+	if svc == nil {
+		return nil, ErrNoMapper
+	}
+
+	control, err := base.Parse(svc.ControlURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c.controlURL = control.String()
+	c.serviceType = svc.ServiceType
+
+	// the router needs to know which host to forward the port to
+	host, err := net.ResolveIPAddr("ip", base.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	if c.localIP, err = localIPFor(host.IP); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *UPnP) Name() string {
+	return "UPnP"
+}
+
+// ExternalIP returns the public address of the gateway
+func (c *UPnP) ExternalIP(ctx context.Context) (net.IP, error) {
+	res, err := c.call(ctx, "GetExternalIPAddress", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var ip = net.ParseIP(res["NewExternalIPAddress"])
+	if ip == nil {
+		return nil, ErrInvalidPacket
+	}
+
+	return ip, nil
+}
+
+func (c *UPnP) AddMapping(ctx context.Context, proto Protocol, internalPort int, externalPort int, lifetime time.Duration) (Mapping, error) {
+	ip, err := c.ExternalIP(ctx)
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	var args = []soapArg{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(externalPort)},
+		{"NewProtocol", string(proto)},
+		{"NewInternalPort", strconv.Itoa(internalPort)},
+		{"NewInternalClient", c.localIP.String()},
+		{"NewEnabled", "1"},
+		{"NewPortMappingDescription", upnpDescription},
+		{"NewLeaseDuration", strconv.Itoa(int(lifetime / time.Second))},
+	}
+
+	_, err = c.call(ctx, "AddPortMapping", args)
+
+	// fall back to a permanent mapping if the router doesn't support leases
+	var soapErr *SOAPError
+	if errors.As(err, &soapErr) && soapErr.Code == errOnlyPermanentLeases {
+		lifetime = 0
+		args[len(args)-1].value = "0"
+		_, err = c.call(ctx, "AddPortMapping", args)
+	}
+	if err != nil {
+		return Mapping{}, err
+	}
+
+	return Mapping{
+		Protocol:     proto,
+		InternalPort: internalPort,
+		ExternalIP:   ip,
+		ExternalPort: externalPort,
+		Lifetime:     lifetime,
+	}, nil
+}
+
+func (c *UPnP) DeleteMapping(ctx context.Context, m Mapping) error {
+	_, err := c.call(ctx, "DeletePortMapping", []soapArg{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(m.ExternalPort)},
+		{"NewProtocol", string(m.Protocol)},
+	})
+	return err
+}
+
+type soapArg struct {
+	name  string
+	value string
+}
+
+// call invokes a SOAP action on the connection service and returns the output arguments
+func (c *UPnP) call(ctx context.Context, action string, args []soapArg) (map[string]string, error) {
+	var body = &bytes.Buffer{}
+
+	body.WriteString(`<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body>`)
+	fmt.Fprintf(body, `<u:%s xmlns:u="%s">`, action, c.serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(body, "<%s>", arg.name)
+		xml.EscapeText(body, []byte(arg.value))
+		fmt.Fprintf(body, "</%s>", arg.name)
+	}
+	fmt.Fprintf(body, `</u:%s></s:Body></s:Envelope>`, action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.controlURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, c.serviceType, action))
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var env soapEnvelope
+	if err := xml.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&env); err != nil {
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %s", res.Status)
+		}
+		return nil, err
+	}
+
+	if env.Body.Fault != nil {
+		return nil, &SOAPError{
+			Code:        env.Body.Fault.Detail.UPnPError.ErrorCode,
+			Description: env.Body.Fault.Detail.UPnPError.ErrorDescription,
+		}
+	}
+
+	var out = make(map[string]string)
+	for _, arg := range env.Body.Response.Args {
+		out[arg.XMLName.Local] = arg.Value
+	}
+
+	return out, nil
+}
+
+// ssdpSearch sends SSDP search requests and returns the locations of gateway descriptions
+func ssdpSearch(ctx context.Context) ([]string, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	dst, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, st := range searchTargets {
+		var msg = "M-SEARCH * HTTP/1.1\r\n" +
+			"HOST: " + ssdpAddr + "\r\n" +
+			"ST: " + st + "\r\n" +
+			"MAN: \"ssdp:discover\"\r\n" +
+			"MX: 2\r\n\r\n"
+
+		if _, err := conn.WriteToUDP([]byte(msg), dst); err != nil {
+			return nil, err
+		}
+	}
+
+	var deadline = time.Now().Add(ssdpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	var locations []string
+	var seen = map[string]struct{}{}
+	var buf = make([]byte, 2048)
+
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+
+		res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		res.Body.Close()
+
+		var location = res.Header.Get("Location")
+		if location == "" || !strings.Contains(res.Header.Get("St"), "InternetGatewayDevice") {
+			continue
+		}
+		if _, found := seen[location]; found {
+			continue
+		}
+		seen[location] = struct{}{}
+		locations = append(locations, location)
+	}
+
+	if len(locations) == 0 {
+		return nil, ErrNoMapper
+	}
+
+	return locations, nil
+}
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	DeviceType string        `xml:"deviceType"`
+	Services   []upnpService `xml:"serviceList>service"`
+	Devices    []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// findService returns the first service of the given types, in order of preference
+func (d *upnpDevice) findService(types []string) *upnpService {
+	for _, t := range types {
+		if svc := d.findServiceType(t); svc != nil {
+			return svc
+		}
+	}
+	return nil
+}
+
+func (d *upnpDevice) findServiceType(t string) *upnpService {
+	for i := range d.Services {
+		if d.Services[i].ServiceType == t {
+			return &d.Services[i]
+		}
+	}
+	for i := range d.Devices {
+		if svc := d.Devices[i].findServiceType(t); svc != nil {
+			return svc
+		}
+	}
+	return nil
+}
+
+type soapEnvelope struct {
+	Body struct {
+		Fault *struct {
+			Detail struct {
+				UPnPError struct {
+					ErrorCode        int    `xml:"errorCode"`
+					ErrorDescription string `xml:"errorDescription"`
+				} `xml:"UPnPError"`
+			} `xml:"detail"`
+		} `xml:"Fault"`
+		Response struct {
+			Args []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
@@ -0,0 +1,172 @@
+package ip
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// settleDelay groups bursts of change notifications into a single scan
+const settleDelay = 200 * time.Millisecond
+
+// EventInterfaceUp is emitted when an interface appears or goes up
+type EventInterfaceUp struct {
+	Interface string
+}
+
+// EventInterfaceDown is emitted when an interface goes down or disappears
+type EventInterfaceDown struct {
+	Interface string
+}
+
+// EventAddrAdded is emitted when an interface that is up gets a new address
+type EventAddrAdded struct {
+	Interface string
+	Addr      *net.IPNet
+}
+
+// EventAddrRemoved is emitted when an address is removed or its interface goes down
+type EventAddrRemoved struct {
+	Interface string
+	Addr      *net.IPNet
+}
+
+type ifaceState struct {
+	addrs map[string]*net.IPNet
+}
+
+// Monitor emits events about changes to interfaces and addresses of the system until the context is done.
+// It reacts to kernel notifications where they are available and polls the system otherwise.
+func Monitor(ctx context.Context) <-chan any {
+	var output = make(chan any)
+
+	// fall back to polling if notifications are unavailable
+	changes, err := notify(ctx)
+	if err != nil {
+		changes = nil
+	}
+
+	var poll = monitorPollInterval
+	if changes == nil {
+		poll = pollInterval
+	}
+
+	go func() {
+		defer close(output)
+
+		// interfaces present at start are the baseline
+		state, err := scanInterfaces()
+		if err != nil {
+			state = make(map[string]*ifaceState)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(poll):
+			case _, ok := <-changes:
+				if !ok {
+					changes, poll = nil, pollInterval
+					continue
+				}
+				time.Sleep(settleDelay)
+				drain(changes)
+			}
+
+			updated, err := scanInterfaces()
+			if err == nil {
+				for _, event := range diffInterfaces(state, updated) {
+					select {
+					case output <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+				state = updated
+			}
+		}
+	}()
+
+	return output
+}
+
+// scanInterfaces returns addresses of all interfaces that are up
+func scanInterfaces() (map[string]*ifaceState, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var m = make(map[string]*ifaceState)
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		var s = &ifaceState{addrs: make(map[string]*net.IPNet)}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok {
+				s.addrs[ipnet.String()] = ipnet
+			}
+		}
+
+		m[iface.Name] = s
+	}
+
+	return m, nil
+}
+
+// diffInterfaces returns events that turn the old state into the new one
+func diffInterfaces(old map[string]*ifaceState, updated map[string]*ifaceState) []any {
+	var events []any
+
+	// First check what's gone
+	for name, s := range old {
+		u, found := updated[name]
+		for key, addr := range s.addrs {
+			if !found {
+				events = append(events, EventAddrRemoved{Interface: name, Addr: addr})
+			} else if _, found := u.addrs[key]; !found {
+				events = append(events, EventAddrRemoved{Interface: name, Addr: addr})
+			}
+		}
+		if !found {
+			events = append(events, EventInterfaceDown{Interface: name})
+		}
+	}
+
+	// Then check what's new
+	for name, u := range updated {
+		s, found := old[name]
+		if !found {
+			events = append(events, EventInterfaceUp{Interface: name})
+		}
+		for key, addr := range u.addrs {
+			if found {
+				if _, found := s.addrs[key]; found {
+					continue
+				}
+			}
+			events = append(events, EventAddrAdded{Interface: name, Addr: addr})
+		}
+	}
+
+	return events
+}
+
+func drain(ch <-chan struct{}) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
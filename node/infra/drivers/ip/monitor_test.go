@@ -0,0 +1,69 @@
+package ip
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDiffInterfaces(t *testing.T) {
+	var addr = func(s string) *net.IPNet {
+		ip, ipnet, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ipnet.IP = ip
+		return ipnet
+	}
+	var state = func(addrs ...*net.IPNet) *ifaceState {
+		var s = &ifaceState{addrs: make(map[string]*net.IPNet)}
+		for _, a := range addrs {
+			s.addrs[a.String()] = a
+		}
+		return s
+	}
+
+	var a1, a2, a3 = addr("192.168.1.10/24"), addr("10.0.0.5/8"), addr("192.168.1.11/24")
+
+	var old = map[string]*ifaceState{
+		"eth0":  state(a1),
+		"wlan0": state(a2),
+	}
+	var updated = map[string]*ifaceState{
+		"eth0": state(a3),
+		"tun0": state(),
+	}
+
+	var expected = map[string]bool{
+		"removed eth0 " + a1.String():  true,
+		"removed wlan0 " + a2.String(): true,
+		"down wlan0":                   true,
+		"up tun0":                      true,
+		"added eth0 " + a3.String():    true,
+	}
+
+	var events = diffInterfaces(old, updated)
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %v", len(expected), len(events), events)
+	}
+
+	for _, e := range events {
+		var key string
+		switch e := e.(type) {
+		case EventAddrRemoved:
+			key = "removed " + e.Interface + " " + e.Addr.String()
+		case EventAddrAdded:
+			key = "added " + e.Interface + " " + e.Addr.String()
+		case EventInterfaceUp:
+			key = "up " + e.Interface
+		case EventInterfaceDown:
+			key = "down " + e.Interface
+		}
+		if !expected[key] {
+			t.Fatalf("unexpected event %v", e)
+		}
+	}
+
+	if len(diffInterfaces(updated, updated)) != 0 {
+		t.Fatal("unexpected events for unchanged state")
+	}
+}
@@ -0,0 +1,61 @@
+package ip
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/sys/unix"
+	"os"
+	"time"
+)
+
+// with kernel notifications polling only catches up on missed messages
+const monitorPollInterval = time.Minute
+
+// notify subscribes to netlink notifications about links and addresses. The returned channel receives
+// a value after every batch of notifications and closes when the subscription ends.
+func notify(ctx context.Context) (<-chan struct{}, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	})
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	// wrapping a non-blocking socket in a file lets the runtime poller interrupt reads on close
+	var file = os.NewFile(uintptr(fd), "netlink")
+	var ch = make(chan struct{}, 1)
+
+	go func() {
+		<-ctx.Done()
+		file.Close()
+	}()
+
+	go func() {
+		defer close(ch)
+
+		var buf = make([]byte, os.Getpagesize())
+		for {
+			if _, err := file.Read(buf); err != nil {
+				// the kernel drops messages if we can't keep up, which is fine since we rescan anyway
+				if errors.Is(err, unix.ENOBUFS) {
+					continue
+				}
+				return
+			}
+
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package ip
+
+import (
+	"context"
+	"errors"
+)
+
+const monitorPollInterval = pollInterval
+
+func notify(ctx context.Context) (<-chan struct{}, error) {
+	return nil, errors.New("not supported")
+}
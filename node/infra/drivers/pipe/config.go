@@ -0,0 +1,17 @@
+package pipe
+
+const defaultListen = "~/.astrald-pipe.sock"
+
+type Config struct {
+	// Listen is the path of the unix socket served to astrald-pipe. Empty disables listening.
+	Listen string `yaml:"listen"`
+
+	// Endpoints maps endpoint names to byte streams. Targets have the form exec:<command>,
+	// file:<path> (opened read-write, e.g. a serial device) or fifo:<read path>,<write path>.
+	Endpoints map[string]string `yaml:"endpoints"`
+}
+
+var defaultConfig = Config{
+	Listen:    defaultListen,
+	Endpoints: map[string]string{},
+}
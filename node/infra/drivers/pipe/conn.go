@@ -0,0 +1,56 @@
+package pipe
+
+import (
+	"github.com/cryptopunkscc/astrald/net"
+	"io"
+	"sync"
+)
+
+var _ net.Conn = &Conn{}
+
+// Conn turns a pair of streams into a net.Conn
+type Conn struct {
+	io.Reader
+	io.Writer
+	closers  []io.Closer
+	outbound bool
+	local    net.Endpoint
+	remote   net.Endpoint
+	close    sync.Once
+	err      error
+}
+
+func newConn(r io.Reader, w io.Writer, outbound bool, local net.Endpoint, remote net.Endpoint, closers ...io.Closer) *Conn {
+	return &Conn{
+		Reader:   r,
+		Writer:   w,
+		closers:  closers,
+		outbound: outbound,
+		local:    local,
+		remote:   remote,
+	}
+}
+
+// Close runs all closers in order and returns the first error
+func (conn *Conn) Close() error {
+	conn.close.Do(func() {
+		for _, c := range conn.closers {
+			if err := c.Close(); err != nil && conn.err == nil {
+				conn.err = err
+			}
+		}
+	})
+	return conn.err
+}
+
+func (conn *Conn) LocalEndpoint() net.Endpoint {
+	return conn.local
+}
+
+func (conn *Conn) RemoteEndpoint() net.Endpoint {
+	return conn.remote
+}
+
+func (conn *Conn) Outbound() bool {
+	return conn.outbound
+}
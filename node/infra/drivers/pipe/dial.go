@@ -0,0 +1,155 @@
+package pipe
+
+import (
+	"bitbucket.org/creachadair/shell"
+	"bufio"
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const killTimeout = 3 * time.Second
+
+var (
+	ErrUnknownEndpoint = errors.New("endpoint not configured")
+	ErrInvalidTarget   = errors.New("invalid endpoint target")
+)
+
+var _ infra.Dialer = &Driver{}
+
+func (drv *Driver) Dial(ctx context.Context, endpoint net.Endpoint) (net.Conn, error) {
+	endpoint, err := drv.Unpack(endpoint.Network(), endpoint.Pack())
+	if err != nil {
+		return nil, err
+	}
+
+	pipeEndpoint := endpoint.(Endpoint)
+
+	target, found := drv.config.Endpoints[pipeEndpoint.name]
+	if !found {
+		return nil, ErrUnknownEndpoint
+	}
+
+	kind, arg, _ := strings.Cut(target, ":")
+
+	switch kind {
+	case "exec":
+		return drv.dialExec(pipeEndpoint, arg)
+	case "file":
+		return dialFile(pipeEndpoint, expandHome(arg))
+	case "fifo":
+		in, out, found := strings.Cut(arg, ",")
+		if !found {
+			return nil, ErrInvalidTarget
+		}
+		return dialFIFO(pipeEndpoint, expandHome(in), expandHome(out))
+	}
+
+	return nil, ErrInvalidTarget
+}
+
+// dialExec starts the command and uses its stdin and stdout as the connection
+func (drv *Driver) dialExec(endpoint Endpoint, command string) (net.Conn, error) {
+	args, valid := shell.Split(command)
+	if !valid || len(args) == 0 {
+		return nil, ErrInvalidTarget
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	drv.log.Logv(1, "started %s (pid %d)", endpoint.name, cmd.Process.Pid)
+
+	// pass the command's diagnostics to the log
+	go func() {
+		var scanner = bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			drv.log.Logv(1, "%s: %s", endpoint.name, scanner.Text())
+		}
+	}()
+
+	return newConn(stdout, stdin, true, endpoint, endpoint, stdin, &process{cmd: cmd}), nil
+}
+
+func dialFile(endpoint Endpoint, path string) (net.Conn, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(file, file, true, endpoint, endpoint, file), nil
+}
+
+func dialFIFO(endpoint Endpoint, in string, out string) (net.Conn, error) {
+	// opening a FIFO read-write doesn't block until the other side opens it
+	r, err := os.OpenFile(in, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := os.OpenFile(out, os.O_RDWR, 0)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return newConn(r, w, true, endpoint, endpoint, w, r), nil
+}
+
+var _ io.Closer = &process{}
+
+// process waits for the command to exit after its stdin was closed and kills it if it takes too long
+type process struct {
+	cmd *exec.Cmd
+}
+
+func (p *process) Close() error {
+	var done = make(chan error, 1)
+	go func() {
+		done <- p.cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil
+		}
+		return err
+	case <-time.After(killTimeout):
+		p.cmd.Process.Kill()
+		<-done
+		return nil
+	}
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,32 @@
+package pipe
+
+import (
+	"bytes"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+var _ net.Endpoint = Endpoint{}
+
+// Endpoint is the name of a byte stream defined in the driver config
+type Endpoint struct {
+	name string
+}
+
+func NewEndpoint(name string) Endpoint {
+	return Endpoint{name: name}
+}
+
+func (e Endpoint) Pack() []byte {
+	var b = &bytes.Buffer{}
+	cslq.Encode(b, "[c]c", e.name)
+	return b.Bytes()
+}
+
+func (e Endpoint) String() string {
+	return e.name
+}
+
+func (e Endpoint) Network() string {
+	return DriverName
+}
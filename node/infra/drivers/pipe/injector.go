@@ -0,0 +1,56 @@
+package pipe
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/infra"
+)
+
+var _ infra.DriverInjector = &Injector{}
+var _ infra.FormatInjector = &Injector{}
+
+type Injector struct{}
+
+func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error {
+	drv := &Driver{
+		config: defaultConfig,
+		infra:  i,
+		log:    l,
+	}
+
+	if assets != nil {
+		if err := assets.LoadYAML(DriverName, &drv.config); err != nil {
+			l.Errorv(2, "error reading config: %s", err)
+		}
+	}
+
+	for name := range drv.config.Endpoints {
+		if _, err := Parse(name); err != nil {
+			l.Error("invalid endpoint name '%s'", name)
+		}
+	}
+
+	return i.AddDriver(DriverName, drv)
+}
+
+// InjectFormats adds a log formatter for the driver's endpoints
+func (*Injector) InjectFormats(l *log.Logger) {
+	l.PushFormatFunc(func(v any) ([]log.Op, bool) {
+		e, ok := v.(Endpoint)
+		if !ok {
+			return nil, false
+		}
+
+		return []log.Op{
+			log.OpColor{Color: log.Cyan},
+			log.OpText{Text: e.String()},
+			log.OpReset{},
+		}, true
+	})
+}
+
+func init() {
+	if err := infra.RegisterDriver(DriverName, &Injector{}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,60 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	_net "net"
+	"os"
+)
+
+var _ infra.Listener = &Driver{}
+
+// stdioEndpoint describes the remote side of connections accepted from astrald-pipe
+var stdioEndpoint = Endpoint{name: "stdio"}
+
+// Listen accepts connections from astrald-pipe, which bridges its own stdin and stdout to the socket
+func (drv *Driver) Listen(ctx context.Context) (<-chan net.Conn, error) {
+	if drv.config.Listen == "" {
+		return nil, errors.New("listening disabled")
+	}
+
+	var path = expandHome(drv.config.Listen)
+
+	// remove a stale socket left by a previous run
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+
+	listener, err := _net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var output = make(chan net.Conn)
+	go func() {
+		<-ctx.Done()
+		drv.log.Logv(1, "stop listen pipe %s", path)
+		listener.Close()
+	}()
+
+	drv.log.Logv(1, "listen pipe %s", path)
+
+	go func() {
+		defer close(output)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, _net.ErrClosed) {
+					drv.log.Errorv(1, "accept: %s", err)
+				}
+				return
+			}
+
+			output <- newConn(conn, conn, false, NewEndpoint(path), stdioEndpoint, conn)
+		}
+	}()
+
+	return output, nil
+}
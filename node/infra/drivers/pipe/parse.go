@@ -0,0 +1,22 @@
+package pipe
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"strings"
+)
+
+var _ infra.Parser = &Driver{}
+
+func (drv *Driver) Parse(network string, address string) (net.Endpoint, error) {
+	return Parse(address)
+}
+
+func Parse(s string) (Endpoint, error) {
+	if s == "" || strings.ContainsAny(s, " \t\r\n") {
+		return Endpoint{}, errors.New("invalid endpoint name")
+	}
+
+	return Endpoint{name: s}, nil
+}
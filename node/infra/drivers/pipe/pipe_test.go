@@ -0,0 +1,90 @@
+package pipe
+
+import (
+	"bytes"
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"io"
+	_net "net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testDriver(config Config) *Driver {
+	return &Driver{
+		config: config,
+		log:    log.NewLogger(log.NewPrinterSplitter()),
+	}
+}
+
+func TestDialExec(t *testing.T) {
+	var drv = testDriver(Config{Endpoints: map[string]string{"echo": "exec:cat"}})
+
+	conn, err := drv.Dial(context.Background(), NewEndpoint("echo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var data = []byte("hello astral")
+	if _, err := conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf = make([]byte, len(data))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Fatalf("expected %q, got %q", data, buf)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDialUnknown(t *testing.T) {
+	var drv = testDriver(Config{Endpoints: map[string]string{}})
+
+	if _, err := drv.Dial(context.Background(), NewEndpoint("missing")); err != ErrUnknownEndpoint {
+		t.Fatalf("expected %v, got %v", ErrUnknownEndpoint, err)
+	}
+}
+
+func TestListen(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "pipe.sock")
+	var drv = testDriver(Config{Listen: path})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conns, err := drv.Listen(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := _net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var conn = <-conns
+	defer conn.Close()
+
+	if conn.Outbound() {
+		t.Fatal("accepted conn is outbound")
+	}
+
+	client.Write([]byte("ping"))
+
+	var buf = make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("unexpected data %q", buf)
+	}
+}
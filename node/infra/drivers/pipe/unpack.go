@@ -0,0 +1,28 @@
+package pipe
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+)
+
+var _ infra.Unpacker = &Driver{}
+
+func (drv *Driver) Unpack(network string, data []byte) (net.Endpoint, error) {
+	if network != DriverName {
+		return nil, errors.New("invalid network")
+	}
+	return Unpack(data)
+}
+
+func Unpack(buf []byte) (Endpoint, error) {
+	var s string
+
+	if err := cslq.Decode(bytes.NewReader(buf), "[c]c", &s); err != nil {
+		return Endpoint{}, err
+	}
+
+	return Parse(s)
+}
@@ -0,0 +1,582 @@
+package udp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"io"
+	_net "net"
+	"sync"
+	"time"
+)
+
+const (
+	tickInterval     = 20 * time.Millisecond
+	keepaliveTimeout = 15 * time.Second
+	idleTimeout      = 60 * time.Second
+	lingerTimeout    = 10 * time.Second
+	initialRTO       = time.Second
+	minRTO           = 200 * time.Millisecond
+	maxRTO           = 10 * time.Second
+	maxRetransmits   = 10
+	initialCwnd      = 10
+	initialSsthresh  = 1 << 16
+	maxRecvBuffer    = 1 << 20
+	dupAckThreshold  = 3
+)
+
+var (
+	ErrConnClosed  = errors.New("connection closed")
+	ErrConnReset   = errors.New("connection reset by peer")
+	ErrIdleTimeout = errors.New("idle timeout")
+	ErrRetransmits = errors.New("too many retransmits")
+)
+
+var _ net.Conn = &Conn{}
+
+// Conn is a reliable, ordered byte stream carried over UDP. It uses selective acknowledgements,
+// retransmission timeouts with RTT estimation and AIMD congestion control.
+type Conn struct {
+	socket   *socket
+	remote   *_net.UDPAddr
+	id       uint32
+	outbound bool
+
+	established chan struct{}
+	done        chan struct{}
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	open   bool  // handshake completed
+	closed bool  // closed locally
+	err    error // set when the connection is gone
+
+	// send state
+	sndNext      uint32
+	sndUna       uint32
+	unacked      map[uint32]*segment
+	inflight     int // bytes in flight
+	outstanding  int // segments in flight
+	cwnd         float64
+	ssthresh     float64
+	peerWindow   int
+	dupAcks      int
+	recoverUntil uint32 // losses before this sequence number belong to the current recovery
+	srtt         time.Duration
+	rttvar       time.Duration
+	rto          time.Duration
+	finSeq       uint32
+	finAcked     bool
+	closedAt     time.Time
+
+	// receive state
+	rcvNext    uint32
+	ooo        map[uint32]*segment
+	oooBytes   int
+	readBuf    bytes.Buffer
+	finRecv    bool
+	advertised int
+
+	lastRecv time.Time
+	lastSend time.Time
+}
+
+type segment struct {
+	seq     uint32
+	flags   byte
+	payload []byte
+	sentAt  time.Time
+	retries int
+	sacked  bool
+}
+
+func newConn(s *socket, remote *_net.UDPAddr, id uint32, outbound bool) *Conn {
+	var now = time.Now()
+	c := &Conn{
+		socket:      s,
+		remote:      remote,
+		id:          id,
+		outbound:    outbound,
+		established: make(chan struct{}),
+		done:        make(chan struct{}),
+		unacked:     make(map[uint32]*segment),
+		ooo:         make(map[uint32]*segment),
+		cwnd:        initialCwnd,
+		ssthresh:    initialSsthresh,
+		peerWindow:  maxRecvBuffer,
+		rto:         initialRTO,
+		advertised:  maxRecvBuffer,
+		lastRecv:    now,
+		lastSend:    now,
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	if !outbound {
+		c.open = true
+		close(c.established)
+	}
+
+	go c.tickLoop()
+
+	return c
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.readBuf.Len() == 0 {
+		switch {
+		case c.closed:
+			return 0, ErrConnClosed
+		case c.finRecv:
+			return 0, io.EOF
+		case c.err != nil:
+			return 0, c.err
+		}
+		c.cond.Wait()
+	}
+
+	n, _ = c.readBuf.Read(p)
+
+	// let the peer know if the window reopened
+	if c.advertised < maxRecvBuffer/4 && c.window() >= maxRecvBuffer/2 {
+		c.sendAck(nil)
+	}
+
+	return n, nil
+}
+
+func (c *Conn) Write(p []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(p) > 0 {
+		var avail int
+		for {
+			if c.closed {
+				return n, ErrConnClosed
+			}
+			if c.err != nil {
+				return n, c.err
+			}
+			avail = c.peerWindow - c.inflight
+			if c.open && c.outstanding < int(c.cwnd) && avail > 0 {
+				break
+			}
+			c.cond.Wait()
+		}
+
+		var size = min(len(p), maxPayload, avail)
+		var seg = &segment{
+			seq:     c.sndNext,
+			payload: append([]byte(nil), p[:size]...),
+		}
+		c.sndNext++
+		c.unacked[seg.seq] = seg
+		c.inflight += size
+		c.outstanding++
+		c.transmit(seg)
+
+		p = p[size:]
+		n += size
+	}
+
+	return n, nil
+}
+
+// Close closes the connection gracefully. Data written before Close will still be delivered.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.closedAt = time.Now()
+	c.cond.Broadcast()
+
+	if c.err != nil {
+		return nil
+	}
+
+	if !c.open {
+		c.terminate(ErrConnClosed)
+		return nil
+	}
+
+	var fin = &segment{seq: c.sndNext, flags: flagFin}
+	c.sndNext++
+	c.finSeq = fin.seq
+	c.unacked[fin.seq] = fin
+	c.outstanding++
+	c.transmit(fin)
+
+	return nil
+}
+
+func (c *Conn) Outbound() bool {
+	return c.outbound
+}
+
+func (c *Conn) LocalEndpoint() net.Endpoint {
+	addr, ok := c.socket.conn.LocalAddr().(*_net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	return NewEndpoint(addr)
+}
+
+func (c *Conn) RemoteEndpoint() net.Endpoint {
+	return NewEndpoint(c.remote)
+}
+
+// Done returns a channel that will be closed when the connection is gone
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason the connection is gone or nil if it's still active
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *Conn) key() connKey {
+	return connKey{c.remote.String(), c.id}
+}
+
+// abort terminates the connection immediately and notifies the peer
+func (c *Conn) abort(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	c.send(&packet{typ: pktReset})
+	c.terminate(err)
+}
+
+func (c *Conn) handle(pkt *packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	c.lastRecv = time.Now()
+
+	switch pkt.typ {
+	case pktSyn:
+		if !c.outbound {
+			c.send(&packet{typ: pktSynAck})
+		}
+
+	case pktSynAck:
+		c.establish()
+
+	case pktData:
+		// a data packet implies the peer received our syn even if the ack got lost
+		c.establish()
+		c.handleData(pkt)
+
+	case pktAck:
+		c.establish()
+		c.handleAck(pkt)
+
+	case pktPing:
+		c.sendAck(nil)
+
+	case pktReset:
+		c.terminate(ErrConnReset)
+	}
+}
+
+func (c *Conn) establish() {
+	if c.open || !c.outbound {
+		return
+	}
+	c.open = true
+	close(c.established)
+	c.cond.Broadcast()
+}
+
+func (c *Conn) handleData(pkt *packet) {
+	defer c.sendAck(&pkt.seq)
+
+	// drop duplicates and segments beyond the receive window
+	if seqLess(pkt.seq, c.rcvNext) {
+		return
+	}
+	if int(pkt.seq-c.rcvNext)*maxPayload >= 2*maxRecvBuffer {
+		return
+	}
+	if _, found := c.ooo[pkt.seq]; found {
+		return
+	}
+	if len(pkt.payload) > c.window() {
+		return
+	}
+
+	c.ooo[pkt.seq] = &segment{seq: pkt.seq, flags: pkt.flags, payload: pkt.payload}
+	c.oooBytes += len(pkt.payload)
+
+	// move in-order segments to the read buffer
+	for {
+		seg, found := c.ooo[c.rcvNext]
+		if !found {
+			break
+		}
+		delete(c.ooo, c.rcvNext)
+		c.oooBytes -= len(seg.payload)
+		c.readBuf.Write(seg.payload)
+		c.rcvNext++
+
+		if seg.flags&flagFin != 0 {
+			c.finRecv = true
+		}
+	}
+
+	c.cond.Broadcast()
+}
+
+func (c *Conn) handleAck(pkt *packet) {
+	var now = time.Now()
+
+	c.peerWindow = int(pkt.window)
+
+	// sample the RTT using the segment that triggered the ack, unless it was retransmitted
+	if pkt.flags&flagEcho != 0 {
+		if seg, found := c.unacked[pkt.echo]; found && seg.retries == 0 {
+			c.updateRTT(now.Sub(seg.sentAt))
+		}
+	}
+
+	if seqLess(c.sndUna, pkt.ack) && !seqLess(c.sndNext, pkt.ack) {
+		for seq := c.sndUna; seq != pkt.ack; seq++ {
+			seg, found := c.unacked[seq]
+			if !found {
+				continue
+			}
+			c.release(seg)
+
+			if c.cwnd < c.ssthresh {
+				c.cwnd++
+			} else {
+				c.cwnd += 1 / c.cwnd
+			}
+		}
+		c.sndUna = pkt.ack
+		c.dupAcks = 0
+
+		// progress was made, so drop any timeout backoff
+		if c.srtt > 0 {
+			c.rto = min(max(c.srtt+4*c.rttvar, minRTO), maxRTO)
+		}
+	} else if pkt.ack == c.sndUna && len(c.unacked) > 0 {
+		c.dupAcks++
+	}
+
+	// selective acks
+	var highest = pkt.ack
+	for i := uint32(0); i < 64; i++ {
+		if pkt.sack&(1<<i) == 0 {
+			continue
+		}
+		highest = pkt.ack + 1 + i
+		if seg, found := c.unacked[highest]; found && !seg.sacked {
+			seg.sacked = true
+			c.inflight -= len(seg.payload)
+			c.outstanding--
+		}
+	}
+
+	// without selective acks, duplicate acks still signal a missing first segment
+	if c.dupAcks >= dupAckThreshold && !seqLess(c.sndUna, highest) {
+		highest = c.sndUna + 1
+	}
+
+	// fast retransmit segments that are missing while later ones arrived
+	for seq := c.sndUna; seqLess(seq, highest); seq++ {
+		seg, found := c.unacked[seq]
+		if !found || seg.sacked {
+			continue
+		}
+		var lost = highest-seq >= dupAckThreshold || (seq == c.sndUna && c.dupAcks >= dupAckThreshold)
+		if !lost || now.Sub(seg.sentAt) < max(c.srtt, minRTO/4) {
+			continue
+		}
+		c.congestion()
+		seg.retries++
+		c.transmit(seg)
+	}
+
+	if c.closed && !c.finAcked && !seqLess(pkt.ack, c.finSeq+1) {
+		c.finAcked = true
+	}
+
+	c.cond.Broadcast()
+}
+
+// congestion halves the congestion window once per round trip of losses
+func (c *Conn) congestion() {
+	if seqLess(c.sndUna, c.recoverUntil) {
+		return
+	}
+	c.ssthresh = max(c.cwnd/2, 2)
+	c.cwnd = c.ssthresh
+	c.recoverUntil = c.sndNext
+}
+
+func (c *Conn) release(seg *segment) {
+	delete(c.unacked, seg.seq)
+	if !seg.sacked {
+		c.inflight -= len(seg.payload)
+		c.outstanding--
+	}
+}
+
+func (c *Conn) updateRTT(rtt time.Duration) {
+	if c.srtt == 0 {
+		c.srtt = rtt
+		c.rttvar = rtt / 2
+	} else {
+		var delta = c.srtt - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		c.rttvar = (3*c.rttvar + delta) / 4
+		c.srtt = (7*c.srtt + rtt) / 8
+	}
+	c.rto = min(max(c.srtt+4*c.rttvar, minRTO), maxRTO)
+}
+
+func (c *Conn) tickLoop() {
+	var ticker = time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.tick()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Conn) tick() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil || !c.open {
+		return
+	}
+
+	var now = time.Now()
+
+	if now.Sub(c.lastRecv) > idleTimeout {
+		c.terminate(ErrIdleTimeout)
+		return
+	}
+
+	// the retransmission timer runs off the oldest segment in flight
+	if oldest := c.oldest(); oldest != nil && now.Sub(oldest.sentAt) >= c.rto {
+		if oldest.retries >= maxRetransmits {
+			c.send(&packet{typ: pktReset})
+			c.terminate(ErrRetransmits)
+			return
+		}
+
+		// retransmit expired segments in order, limited by the congestion window
+		var budget = max(int(c.cwnd), 1)
+		for seq := c.sndUna; seq != c.sndNext && budget > 0; seq++ {
+			seg, found := c.unacked[seq]
+			if !found || seg.sacked || now.Sub(seg.sentAt) < c.rto {
+				continue
+			}
+			seg.retries++
+			c.transmit(seg)
+			budget--
+		}
+
+		c.ssthresh = max(c.cwnd/2, 2)
+		c.cwnd = 1
+		c.rto = min(2*c.rto, maxRTO)
+		c.recoverUntil = c.sndNext
+	}
+
+	switch {
+	case c.peerWindow <= c.inflight && now.Sub(c.lastSend) > c.rto:
+		// probe a closed window
+		c.send(&packet{typ: pktPing})
+	case now.Sub(c.lastSend) > keepaliveTimeout:
+		c.send(&packet{typ: pktPing})
+	}
+
+	if c.closed && c.finAcked && (c.finRecv || now.Sub(c.closedAt) > lingerTimeout) {
+		c.terminate(ErrConnClosed)
+	}
+}
+
+// oldest returns the oldest segment that still awaits an ack
+func (c *Conn) oldest() (oldest *segment) {
+	for seq := c.sndUna; seq != c.sndNext; seq++ {
+		seg, found := c.unacked[seq]
+		if !found || seg.sacked {
+			continue
+		}
+		if oldest == nil || seg.sentAt.Before(oldest.sentAt) {
+			oldest = seg
+		}
+	}
+	return
+}
+
+func (c *Conn) transmit(seg *segment) {
+	seg.sentAt = time.Now()
+	c.send(&packet{typ: pktData, seq: seg.seq, flags: seg.flags, payload: seg.payload})
+}
+
+func (c *Conn) sendAck(echo *uint32) {
+	var pkt = &packet{typ: pktAck, ack: c.rcvNext, window: uint32(c.window())}
+	if echo != nil {
+		pkt.echo = *echo
+		pkt.flags |= flagEcho
+	}
+	for i := uint32(0); i < 64; i++ {
+		if _, found := c.ooo[c.rcvNext+1+i]; found {
+			pkt.sack |= 1 << i
+		}
+	}
+	c.advertised = int(pkt.window)
+	c.send(pkt)
+}
+
+func (c *Conn) send(pkt *packet) {
+	pkt.connID = c.id
+	c.lastSend = time.Now()
+	c.socket.send(pkt, c.remote)
+}
+
+// window returns free space in the receive buffer
+func (c *Conn) window() int {
+	return max(maxRecvBuffer-c.readBuf.Len()-c.oooBytes, 0)
+}
+
+// terminate marks the connection as gone and releases its resources. Must be called with the lock held.
+func (c *Conn) terminate(err error) {
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+	c.cond.Broadcast()
+	go c.socket.remove(c)
+}
@@ -0,0 +1,134 @@
+package udp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	_net "net"
+	"testing"
+	"time"
+)
+
+func listenLoopback(t *testing.T) *socket {
+	udpConn, err := _net.ListenUDP("udp", &_net.UDPAddr{IP: _net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSocket(udpConn, true, false)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// lossyProxy forwards packets between a client and the target, dropping every nth packet
+func lossyProxy(t *testing.T, target *_net.UDPAddr, n int) *_net.UDPAddr {
+	proxy, err := _net.ListenUDP("udp", &_net.UDPAddr{IP: _net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { proxy.Close() })
+
+	go func() {
+		var client *_net.UDPAddr
+		var buf = make([]byte, maxPacketSize*2)
+		for i := 1; ; i++ {
+			size, addr, err := proxy.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if i%n == 0 {
+				continue
+			}
+			if addr.String() == target.String() {
+				if client != nil {
+					proxy.WriteToUDP(buf[:size], client)
+				}
+			} else {
+				client = addr
+				proxy.WriteToUDP(buf[:size], target)
+			}
+		}
+	}()
+
+	return proxy.LocalAddr().(*_net.UDPAddr)
+}
+
+func testTransfer(t *testing.T, server *socket, addr *_net.UDPAddr, size int) {
+	var data = make([]byte, size)
+	rand.Read(data)
+
+	client := listenLoopback(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var received = make(chan []byte, 1)
+	go func() {
+		conn, ok := <-server.accept
+		if !ok {
+			received <- nil
+			return
+		}
+		buf, _ := io.ReadAll(conn)
+		conn.Close()
+		received <- buf
+	}()
+
+	conn, err := client.Dial(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn.Write(data)
+		conn.Close()
+	}()
+
+	select {
+	case buf := <-received:
+		if !bytes.Equal(buf, data) {
+			t.Fatalf("server received %d bytes, expected %d", len(buf), len(data))
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("transfer timed out")
+	}
+}
+
+func TestConnTransfer(t *testing.T) {
+	server := listenLoopback(t)
+
+	testTransfer(t, server, server.conn.LocalAddr().(*_net.UDPAddr), 4<<20)
+}
+
+func TestConnTransferLossy(t *testing.T) {
+	server := listenLoopback(t)
+	proxy := lossyProxy(t, server.conn.LocalAddr().(*_net.UDPAddr), 7)
+
+	testTransfer(t, server, proxy, 256<<10)
+}
+
+func TestConnReset(t *testing.T) {
+	server := listenLoopback(t)
+	client := listenLoopback(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := client.Dial(ctx, server.conn.LocalAddr().(*_net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inbound := <-server.accept
+	inbound.abort(ErrConnClosed)
+
+	select {
+	case <-conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("reset not received")
+	}
+
+	if _, err := conn.Read(make([]byte, 1)); err != ErrConnReset {
+		t.Fatalf("expected %v, got %v", ErrConnReset, err)
+	}
+}
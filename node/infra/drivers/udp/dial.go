@@ -0,0 +1,59 @@
+package udp
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	_net "net"
+	"time"
+)
+
+const dialTimeout = 10 * time.Second
+const dialTimeoutPrivate = 3 * time.Second
+
+var _ infra.Dialer = &Driver{}
+
+func (drv *Driver) Dial(ctx context.Context, endpoint net.Endpoint) (net.Conn, error) {
+	endpoint, err := drv.Unpack(endpoint.Network(), endpoint.Pack())
+	if err != nil {
+		return nil, err
+	}
+
+	udpEndpoint := endpoint.(Endpoint)
+
+	// for LAN dials we can use a shorter timeout
+	var timeout = dialTimeout
+	if udpEndpoint.IsPrivate() {
+		timeout = dialTimeoutPrivate
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// dial from the listening socket if possible, so that the remote party sees the same
+	// address we advertise and any NAT mapping created for it gets reused
+	if s := drv.listeningSocket(); s != nil {
+		conn, err := s.Dial(ctx, udpEndpoint.UDPAddr())
+		if err == nil {
+			return conn, nil
+		}
+		if err != ErrSocketClosed {
+			return nil, err
+		}
+	}
+
+	udpConn, err := _net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSocket(udpConn, false, true)
+
+	conn, err := s.Dial(ctx, udpEndpoint.UDPAddr())
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,35 @@
+package udp
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"sync"
+)
+
+var _ infra.Driver = &Driver{}
+
+const DriverName = "udp"
+
+type Driver struct {
+	config      Config
+	infra       infra.Infra
+	log         *log.Logger
+	publicAddrs []net.Endpoint
+	socket      *socket // listening socket, also used for dialing so that peers see a single address
+	mu          sync.Mutex
+}
+
+func (drv *Driver) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// listeningSocket returns the socket bound to the listen port or nil if the driver isn't listening
+func (drv *Driver) listeningSocket() *socket {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+
+	return drv.socket
+}
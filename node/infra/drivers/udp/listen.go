@@ -0,0 +1,64 @@
+package udp
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	_net "net"
+	"strconv"
+)
+
+var _ infra.Listener = &Driver{}
+
+func (drv *Driver) Listen(ctx context.Context) (<-chan net.Conn, error) {
+	var addrStr = ":" + strconv.Itoa(drv.ListenPort())
+
+	addr, err := _net.ResolveUDPAddr("udp", addrStr)
+	if err != nil {
+		return nil, err
+	}
+
+	udpConn, err := _net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var s = newSocket(udpConn, true, false)
+
+	drv.mu.Lock()
+	drv.socket = s
+	drv.mu.Unlock()
+
+	drv.log.Logv(1, "listen udp %s", addrStr)
+
+	go func() {
+		<-ctx.Done()
+		drv.log.Logv(1, "stop listen udp %s", addrStr)
+
+		drv.mu.Lock()
+		if drv.socket == s {
+			drv.socket = nil
+		}
+		drv.mu.Unlock()
+
+		s.Close()
+	}()
+
+	var output = make(chan net.Conn)
+	go func() {
+		defer close(output)
+		for conn := range s.accept {
+			select {
+			case output <- conn:
+			case <-ctx.Done():
+				conn.Close()
+			}
+		}
+	}()
+
+	return output, nil
+}
+
+func (drv *Driver) ListenPort() int {
+	return drv.config.ListenPort
+}
@@ -0,0 +1,123 @@
+package udp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// packet types
+const (
+	pktSyn    byte = iota + 1 // opens a connection
+	pktSynAck                 // confirms an opened connection
+	pktData                   // carries a sequenced segment
+	pktAck                    // acknowledges received segments
+	pktReset                  // aborts the connection
+	pktPing                   // requests an ack
+)
+
+// data flags
+const (
+	flagFin byte = 0x01 // last segment of the stream
+)
+
+// ack flags
+const (
+	flagEcho byte = 0x01 // the ack was triggered by the data segment in the echo field
+)
+
+const (
+	headerLen     = 5                             // conn id (4) + type (1)
+	dataHeaderLen = headerLen + 5                 // seq (4) + flags (1)
+	ackLen        = headerLen + 4 + 8 + 4 + 4 + 1 // ack (4) + sack (8) + window (4) + echo (4) + flags (1)
+	maxPacketSize = 1232                          // fits in the minimum IPv6 MTU
+	maxPayload    = maxPacketSize - dataHeaderLen // max payload of a single data packet
+)
+
+var errInvalidPacket = errors.New("invalid packet")
+
+type packet struct {
+	connID uint32
+	typ    byte
+
+	// data
+	seq     uint32
+	flags   byte
+	payload []byte
+
+	// ack
+	ack    uint32 // next expected sequence number
+	sack   uint64 // bit i is set if segment ack+1+i was received
+	window uint32 // free space in the receive buffer in bytes
+	echo   uint32 // sequence number of the segment that triggered the ack, used for RTT sampling
+}
+
+func (p *packet) marshal() []byte {
+	var buf []byte
+
+	switch p.typ {
+	case pktData:
+		buf = make([]byte, dataHeaderLen+len(p.payload))
+		binary.BigEndian.PutUint32(buf[headerLen:], p.seq)
+		buf[headerLen+4] = p.flags
+		copy(buf[dataHeaderLen:], p.payload)
+
+	case pktAck:
+		buf = make([]byte, ackLen)
+		binary.BigEndian.PutUint32(buf[headerLen:], p.ack)
+		binary.BigEndian.PutUint64(buf[headerLen+4:], p.sack)
+		binary.BigEndian.PutUint32(buf[headerLen+12:], p.window)
+		binary.BigEndian.PutUint32(buf[headerLen+16:], p.echo)
+		buf[headerLen+20] = p.flags
+
+	default:
+		buf = make([]byte, headerLen)
+	}
+
+	binary.BigEndian.PutUint32(buf, p.connID)
+	buf[4] = p.typ
+
+	return buf
+}
+
+func unmarshalPacket(buf []byte) (*packet, error) {
+	if len(buf) < headerLen {
+		return nil, errInvalidPacket
+	}
+
+	var p = &packet{
+		connID: binary.BigEndian.Uint32(buf),
+		typ:    buf[4],
+	}
+
+	switch p.typ {
+	case pktData:
+		if len(buf) < dataHeaderLen {
+			return nil, errInvalidPacket
+		}
+		p.seq = binary.BigEndian.Uint32(buf[headerLen:])
+		p.flags = buf[headerLen+4]
+		p.payload = buf[dataHeaderLen:]
+
+	case pktAck:
+		if len(buf) < ackLen {
+			return nil, errInvalidPacket
+		}
+		p.ack = binary.BigEndian.Uint32(buf[headerLen:])
+		p.sack = binary.BigEndian.Uint64(buf[headerLen+4:])
+		p.window = binary.BigEndian.Uint32(buf[headerLen+12:])
+		p.echo = binary.BigEndian.Uint32(buf[headerLen+16:])
+		p.flags = buf[headerLen+20]
+
+	case pktSyn, pktSynAck, pktReset, pktPing:
+
+	default:
+		return nil, errInvalidPacket
+	}
+
+	return p, nil
+}
+
+// seqLess returns true if sequence number a precedes b, taking wraparound into account
+func seqLess(a, b uint32) bool {
+	return int32(a-b) < 0
+}
@@ -0,0 +1,209 @@
+package udp
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	_net "net"
+	"sync"
+	"time"
+)
+
+const (
+	synInterval    = 250 * time.Millisecond
+	synIntervalMax = 2 * time.Second
+	acceptQueueLen = 16
+	socketBuffer   = 4 << 20
+)
+
+var ErrSocketClosed = errors.New("socket closed")
+
+type connKey struct {
+	addr string
+	id   uint32
+}
+
+// socket multiplexes many connections over a single UDP socket
+type socket struct {
+	conn   *_net.UDPConn
+	accept chan *Conn // nil if the socket doesn't accept inbound connections
+	owned  bool       // if true, the socket closes itself after its last connection is gone
+
+	mu     sync.Mutex
+	conns  map[connKey]*Conn
+	closed bool
+}
+
+func newSocket(conn *_net.UDPConn, accept bool, owned bool) *socket {
+	// the kernel may cap these, so errors are not fatal
+	conn.SetReadBuffer(socketBuffer)
+	conn.SetWriteBuffer(socketBuffer)
+
+	s := &socket{
+		conn:  conn,
+		owned: owned,
+		conns: make(map[connKey]*Conn),
+	}
+	if accept {
+		s.accept = make(chan *Conn, acceptQueueLen)
+	}
+
+	go s.readLoop()
+
+	return s
+}
+
+// Dial opens a new connection to the remote address over the socket
+func (s *socket) Dial(ctx context.Context, addr *_net.UDPAddr) (*Conn, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrSocketClosed
+	}
+
+	var c *Conn
+	for {
+		id := rand.Uint32()
+		if _, found := s.conns[connKey{addr.String(), id}]; !found {
+			c = newConn(s, addr, id, true)
+			s.conns[c.key()] = c
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	var interval = synInterval
+	var timer = time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			s.send(&packet{connID: c.id, typ: pktSyn}, addr)
+			timer.Reset(interval)
+			interval = min(2*interval, synIntervalMax)
+
+		case <-c.established:
+			return c, nil
+
+		case <-c.done:
+			return nil, c.Err()
+
+		case <-ctx.Done():
+			c.abort(ctx.Err())
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close closes the socket and aborts all its connections
+func (s *socket) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	return s.conn.Close()
+}
+
+func (s *socket) readLoop() {
+	defer s.shutdown()
+
+	var buf = make([]byte, maxPacketSize*2)
+
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr _net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return
+		}
+
+		pkt, err := unmarshalPacket(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		// copy the payload since the buffer will be reused
+		if len(pkt.payload) > 0 {
+			pkt.payload = append([]byte(nil), pkt.payload...)
+		}
+
+		s.handle(pkt, addr)
+	}
+}
+
+func (s *socket) handle(pkt *packet, addr *_net.UDPAddr) {
+	var key = connKey{addr.String(), pkt.connID}
+
+	s.mu.Lock()
+	c, found := s.conns[key]
+
+	if !found && pkt.typ == pktSyn && s.accept != nil && !s.closed {
+		// accept queue is full, refuse the connection before starting a new conn.
+		// only handle sends to the queue and it does so under s.mu, so the
+		// queue cannot fill up between this check and the send below.
+		if len(s.accept) == cap(s.accept) {
+			s.mu.Unlock()
+			s.send(&packet{connID: pkt.connID, typ: pktReset}, addr)
+			return
+		}
+
+		c = newConn(s, addr, pkt.connID, false)
+		s.conns[key] = c
+		s.accept <- c
+	}
+	s.mu.Unlock()
+
+	if c == nil {
+		if pkt.typ != pktReset {
+			s.send(&packet{connID: pkt.connID, typ: pktReset}, addr)
+		}
+		return
+	}
+
+	c.handle(pkt)
+}
+
+func (s *socket) send(pkt *packet, addr *_net.UDPAddr) error {
+	_, err := s.conn.WriteToUDP(pkt.marshal(), addr)
+	return err
+}
+
+// remove removes a closed connection from the socket
+func (s *socket) remove(c *Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conns[c.key()] == c {
+		delete(s.conns, c.key())
+	}
+
+	if s.owned && len(s.conns) == 0 && !s.closed {
+		s.closed = true
+		s.conn.Close()
+	}
+}
+
+func (s *socket) shutdown() {
+	s.mu.Lock()
+	s.closed = true
+	var conns = make([]*Conn, 0, len(s.conns))
+	for _, c := range s.conns {
+		conns = append(conns, c)
+	}
+	if s.accept != nil {
+		close(s.accept)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.abort(ErrSocketClosed)
+	}
+	s.conn.Close()
+}
@@ -0,0 +1,20 @@
+package unix
+
+const (
+	defaultSocketDir    = "/tmp/astrald"
+	defaultScanInterval = 30
+)
+
+type Config struct {
+	// SocketDir is a directory shared by all nodes on the host. Every node listens on a socket named after its
+	// identity and discovers other nodes by the sockets they created.
+	SocketDir string `yaml:"socket_dir"`
+
+	// ScanInterval is the number of seconds between scans of the socket directory
+	ScanInterval int `yaml:"scan_interval"`
+}
+
+var defaultConfig = Config{
+	SocketDir:    defaultSocketDir,
+	ScanInterval: defaultScanInterval,
+}
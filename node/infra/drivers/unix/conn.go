@@ -0,0 +1,32 @@
+package unix
+
+import (
+	"github.com/cryptopunkscc/astrald/net"
+	_net "net"
+)
+
+var _ net.Conn = Conn{}
+
+type Conn struct {
+	_net.Conn
+	outbound bool
+}
+
+func newConn(conn _net.Conn, outbound bool) Conn {
+	return Conn{
+		Conn:     conn,
+		outbound: outbound,
+	}
+}
+
+func (conn Conn) LocalEndpoint() net.Endpoint {
+	return Endpoint{path: conn.Conn.LocalAddr().String()}
+}
+
+func (conn Conn) RemoteEndpoint() net.Endpoint {
+	return Endpoint{path: conn.Conn.RemoteAddr().String()}
+}
+
+func (conn Conn) Outbound() bool {
+	return conn.outbound
+}
@@ -0,0 +1,40 @@
+package unix
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	_net "net"
+	"path/filepath"
+	"time"
+)
+
+const dialTimeout = 3 * time.Second
+
+var ErrOutsideSocketDir = errors.New("socket outside of the socket directory")
+
+var dialConfig = _net.Dialer{Timeout: dialTimeout}
+
+var _ infra.Dialer = &Driver{}
+
+func (drv *Driver) Dial(ctx context.Context, endpoint net.Endpoint) (net.Conn, error) {
+	endpoint, err := drv.Unpack(endpoint.Network(), endpoint.Pack())
+	if err != nil {
+		return nil, err
+	}
+
+	unixEndpoint := endpoint.(Endpoint)
+
+	// never connect to sockets of other services, even if someone gave us their path
+	if filepath.Dir(unixEndpoint.path) != drv.SocketDir() {
+		return nil, ErrOutsideSocketDir
+	}
+
+	conn, err := dialConfig.DialContext(ctx, "unix", unixEndpoint.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(conn, true), nil
+}
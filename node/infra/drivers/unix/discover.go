@@ -0,0 +1,79 @@
+package unix
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// discover adds sockets of other nodes found in the socket directory to the tracker
+func (drv *Driver) discover() {
+	peers, err := drv.scan()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			drv.log.Errorv(1, "scan %s: %s", drv.SocketDir(), err)
+			return
+		}
+		peers = nil
+	}
+
+	// forget sockets that disappeared, so that they're added again if their node comes back
+	for hex := range drv.seen {
+		if _, found := peers[hex]; !found {
+			delete(drv.seen, hex)
+		}
+	}
+
+	var node = drv.infra.Node()
+
+	for hex, endpoint := range peers {
+		if _, found := drv.seen[hex]; found {
+			continue
+		}
+
+		identity, _ := id.ParsePublicKeyHex(hex)
+		if identity.IsEqual(node.Identity()) {
+			continue
+		}
+
+		if err := node.Tracker().AddEndpoint(identity, endpoint); err != nil {
+			drv.log.Errorv(1, "error adding %s: %s", endpoint, err)
+			continue
+		}
+
+		drv.seen[hex] = struct{}{}
+		drv.log.Logv(1, "%s found at %s", identity, endpoint)
+	}
+}
+
+// scan returns sockets in the socket directory indexed by the hex public key of their node
+func (drv *Driver) scan() (map[string]Endpoint, error) {
+	var dir = drv.SocketDir()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var peers = make(map[string]Endpoint)
+
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSocket == 0 {
+			continue
+		}
+
+		hex, found := strings.CutSuffix(entry.Name(), socketExt)
+		if !found {
+			continue
+		}
+
+		if _, err := id.ParsePublicKeyHex(hex); err != nil {
+			continue
+		}
+
+		peers[hex] = Endpoint{path: filepath.Join(dir, entry.Name())}
+	}
+
+	return peers, nil
+}
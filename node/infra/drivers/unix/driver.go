@@ -0,0 +1,51 @@
+package unix
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"path/filepath"
+	"time"
+)
+
+var _ infra.Driver = &Driver{}
+
+const DriverName = "unix"
+
+// Driver links nodes on the same host through unix domain sockets. Endpoints are never advertised to the
+// network. Instead, nodes find each other by scanning a socket directory they share.
+type Driver struct {
+	config Config
+	infra  infra.Infra
+	log    *log.Logger
+	seen   map[string]struct{}
+}
+
+func (drv *Driver) Run(ctx context.Context) error {
+	var interval = time.Duration(drv.config.ScanInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultScanInterval * time.Second
+	}
+
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		drv.discover()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// SocketDir returns the absolute path of the socket directory
+func (drv *Driver) SocketDir() string {
+	dir, err := filepath.Abs(expandHome(drv.config.SocketDir))
+	if err != nil {
+		return filepath.Clean(drv.config.SocketDir)
+	}
+	return dir
+}
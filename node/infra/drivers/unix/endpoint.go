@@ -0,0 +1,36 @@
+package unix
+
+import (
+	"bytes"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+var _ net.Endpoint = Endpoint{}
+
+// Endpoint is the path of a unix domain socket
+type Endpoint struct {
+	path string
+}
+
+func NewEndpoint(path string) Endpoint {
+	return Endpoint{path: path}
+}
+
+func (e Endpoint) Pack() []byte {
+	var b = &bytes.Buffer{}
+	cslq.Encode(b, "[c]c", e.path)
+	return b.Bytes()
+}
+
+func (e Endpoint) String() string {
+	return e.path
+}
+
+func (e Endpoint) Network() string {
+	return DriverName
+}
+
+func (e Endpoint) Path() string {
+	return e.path
+}
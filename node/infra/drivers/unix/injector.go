@@ -0,0 +1,58 @@
+package unix
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"os"
+	"strings"
+)
+
+var _ infra.DriverInjector = &Injector{}
+
+type Injector struct{}
+
+func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error {
+	drv := &Driver{
+		config: defaultConfig,
+		infra:  i,
+		log:    l,
+		seen:   make(map[string]struct{}),
+	}
+
+	if assets != nil {
+		if err := assets.LoadYAML(DriverName, &drv.config); err != nil {
+			l.Errorv(2, "error reading config: %s", err)
+		}
+	}
+
+	l.Root().PushFormatFunc(func(v any) ([]log.Op, bool) {
+		e, ok := v.(Endpoint)
+		if !ok {
+			return nil, false
+		}
+
+		return []log.Op{
+			log.OpColor{Color: log.Cyan},
+			log.OpText{Text: e.String()},
+			log.OpReset{},
+		}, true
+	})
+
+	return i.AddDriver(DriverName, drv)
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
+
+func init() {
+	if err := infra.RegisterDriver(DriverName, &Injector{}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,65 @@
+package unix
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	_net "net"
+	"os"
+	"path/filepath"
+)
+
+const socketExt = ".sock"
+
+var _ infra.Listener = &Driver{}
+
+func (drv *Driver) Listen(ctx context.Context) (<-chan net.Conn, error) {
+	var dir = drv.SocketDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	var path = drv.socketPath()
+
+	// remove a stale socket left by a previous run
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+
+	listener, err := _net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var output = make(chan net.Conn)
+	go func() {
+		<-ctx.Done()
+		drv.log.Logv(1, "stop listen unix %s", path)
+		listener.Close()
+	}()
+
+	drv.log.Logv(1, "listen unix %s", path)
+
+	go func() {
+		defer close(output)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, _net.ErrClosed) {
+					drv.log.Errorv(1, "accept: %s", err)
+				}
+				return
+			}
+
+			output <- newConn(conn, false)
+		}
+	}()
+
+	return output, nil
+}
+
+// socketPath returns the path of the socket named after the local identity
+func (drv *Driver) socketPath() string {
+	return filepath.Join(drv.SocketDir(), drv.infra.Node().Identity().PublicKeyHex()+socketExt)
+}
@@ -0,0 +1,22 @@
+package unix
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"path/filepath"
+)
+
+var _ infra.Parser = &Driver{}
+
+func (drv *Driver) Parse(network string, address string) (net.Endpoint, error) {
+	return Parse(address)
+}
+
+func Parse(s string) (Endpoint, error) {
+	if !filepath.IsAbs(s) {
+		return Endpoint{}, errors.New("path not absolute")
+	}
+
+	return Endpoint{path: filepath.Clean(s)}, nil
+}
@@ -0,0 +1,69 @@
+package unix
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	_net "net"
+	"path/filepath"
+	"testing"
+)
+
+func testDriver(dir string) *Driver {
+	return &Driver{
+		config: Config{SocketDir: dir},
+		log:    log.NewLogger(log.NewPrinterSplitter()),
+		seen:   make(map[string]struct{}),
+	}
+}
+
+func TestScanAndDial(t *testing.T) {
+	var dir = t.TempDir()
+	var drv = testDriver(dir)
+
+	identity, err := id.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var path = filepath.Join(dir, identity.PublicKeyHex()+socketExt)
+	l, err := _net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// sockets not named after an identity are ignored
+	other, err := _net.Listen("unix", filepath.Join(dir, "other.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	peers, err := drv.scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[identity.PublicKeyHex()].path != path {
+		t.Fatalf("unexpected scan result %v", peers)
+	}
+
+	conn, err := drv.Dial(context.Background(), peers[identity.PublicKeyHex()])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if !conn.Outbound() || conn.RemoteEndpoint().String() != path {
+		t.Fatalf("unexpected remote endpoint %s", conn.RemoteEndpoint())
+	}
+}
+
+func TestDialOutsideSocketDir(t *testing.T) {
+	var drv = testDriver(t.TempDir())
+
+	var endpoint = NewEndpoint(filepath.Join(t.TempDir(), "x.sock"))
+	if _, err := drv.Dial(context.Background(), endpoint); err != ErrOutsideSocketDir {
+		t.Fatalf("expected %v, got %v", ErrOutsideSocketDir, err)
+	}
+}
@@ -0,0 +1,18 @@
+package ws
+
+const (
+	defaultListenPort = 1793
+	defaultListenPath = "/astral"
+)
+
+type Config struct {
+	ListenPort int      `yaml:"listen_port"`
+	ListenPath string   `yaml:"listen_path"`
+	PublicURL  []string `yaml:"public_url"` // URLs under which the node is reachable, e.g. behind a reverse proxy
+	Proxy      string   `yaml:"proxy"`      // socks5://, socks5h:// or http:// proxy for dials, overrides HTTP_PROXY
+}
+
+var defaultConfig = Config{
+	ListenPort: defaultListenPort,
+	ListenPath: defaultListenPath,
+}
@@ -0,0 +1,50 @@
+package ws
+
+import (
+	"github.com/cryptopunkscc/astrald/net"
+	"golang.org/x/net/websocket"
+	"sync"
+)
+
+var _ net.Conn = &Conn{}
+
+type Conn struct {
+	*websocket.Conn
+	outbound bool
+	local    net.Endpoint
+	remote   net.Endpoint
+	done     chan struct{}
+	close    sync.Once
+}
+
+// newConn wraps a WebSocket connection into astral's net.Conn. Frames are sent as binary.
+func newConn(conn *websocket.Conn, outbound bool, local net.Endpoint, remote net.Endpoint) *Conn {
+	conn.PayloadType = websocket.BinaryFrame
+
+	return &Conn{
+		Conn:     conn,
+		outbound: outbound,
+		local:    local,
+		remote:   remote,
+		done:     make(chan struct{}),
+	}
+}
+
+func (conn *Conn) Close() error {
+	conn.close.Do(func() {
+		close(conn.done)
+	})
+	return conn.Conn.Close()
+}
+
+func (conn *Conn) LocalEndpoint() net.Endpoint {
+	return conn.local
+}
+
+func (conn *Conn) RemoteEndpoint() net.Endpoint {
+	return conn.remote
+}
+
+func (conn *Conn) Outbound() bool {
+	return conn.outbound
+}
@@ -0,0 +1,87 @@
+package ws
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"github.com/cryptopunkscc/astrald/node/infra/proxy"
+	"golang.org/x/net/websocket"
+	_net "net"
+	"time"
+)
+
+const dialTimeout = 15 * time.Second
+
+var _ infra.Dialer = &Driver{}
+
+func (drv *Driver) Dial(ctx context.Context, endpoint net.Endpoint) (net.Conn, error) {
+	endpoint, err := drv.Unpack(endpoint.Network(), endpoint.Pack())
+	if err != nil {
+		return nil, err
+	}
+
+	wsEndpoint := endpoint.(Endpoint)
+
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	tcpConn, err := drv.dialTCP(ctx, wsEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// the websocket handshake doesn't take a context, so bound it with a deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		tcpConn.SetDeadline(deadline)
+	}
+
+	var rwc _net.Conn = tcpConn
+	if wsEndpoint.secure {
+		tlsConn := tls.Client(tcpConn, &tls.Config{ServerName: wsEndpoint.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			tcpConn.Close()
+			return nil, err
+		}
+		rwc = tlsConn
+	}
+
+	config, err := websocket.NewConfig(wsEndpoint.String(), wsEndpoint.Origin())
+	if err != nil {
+		tcpConn.Close()
+		return nil, err
+	}
+
+	wsConn, err := websocket.NewClient(config, rwc)
+	if err != nil {
+		tcpConn.Close()
+		return nil, err
+	}
+
+	tcpConn.SetDeadline(time.Time{})
+
+	var local = Endpoint{secure: wsEndpoint.secure, host: tcpConn.LocalAddr().String(), path: "/"}
+
+	return newConn(wsConn, true, local, wsEndpoint), nil
+}
+
+// dialTCP opens a TCP connection to the endpoint, going through the configured proxy or the HTTP proxy from
+// the environment if one is set
+func (drv *Driver) dialTCP(ctx context.Context, endpoint Endpoint) (_net.Conn, error) {
+	var dialer _net.Dialer
+
+	if drv.proxy != nil {
+		return drv.proxy.DialContext(ctx, "tcp", endpoint.Address())
+	}
+
+	proxyURL, err := proxyFor(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, "tcp", endpoint.Address())
+	}
+
+	return proxy.NewHTTPConnect(proxyURL, &dialer).DialContext(ctx, "tcp", endpoint.Address())
+}
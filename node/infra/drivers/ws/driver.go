@@ -0,0 +1,26 @@
+package ws
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"github.com/cryptopunkscc/astrald/node/infra/proxy"
+)
+
+var _ infra.Driver = &Driver{}
+
+const DriverName = "ws"
+
+type Driver struct {
+	config     Config
+	infra      infra.Infra
+	log        *log.Logger
+	publicURLs []net.Endpoint
+	proxy      proxy.Dialer
+}
+
+func (drv *Driver) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,64 @@
+package ws
+
+import (
+	"bytes"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	_net "net"
+)
+
+var _ net.Endpoint = Endpoint{}
+
+type Endpoint struct {
+	secure bool   // use TLS (wss)
+	host   string // host with an optional port
+	path   string
+}
+
+func (e Endpoint) Pack() []byte {
+	var b = &bytes.Buffer{}
+	cslq.Encode(b, "[c]c", e.String())
+	return b.Bytes()
+}
+
+func (e Endpoint) String() string {
+	return e.scheme() + "://" + e.host + e.path
+}
+
+func (e Endpoint) Network() string {
+	return DriverName
+}
+
+// Address returns the host and port to dial
+func (e Endpoint) Address() string {
+	if _, _, err := _net.SplitHostPort(e.host); err == nil {
+		return e.host
+	}
+	if e.secure {
+		return _net.JoinHostPort(e.host, "443")
+	}
+	return _net.JoinHostPort(e.host, "80")
+}
+
+// Hostname returns the host without the port
+func (e Endpoint) Hostname() string {
+	if host, _, err := _net.SplitHostPort(e.host); err == nil {
+		return host
+	}
+	return e.host
+}
+
+// Origin returns the HTTP origin matching the endpoint
+func (e Endpoint) Origin() string {
+	if e.secure {
+		return "https://" + e.host
+	}
+	return "http://" + e.host
+}
+
+func (e Endpoint) scheme() string {
+	if e.secure {
+		return "wss"
+	}
+	return "ws"
+}
@@ -0,0 +1,47 @@
+package ws
+
+import (
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	_net "net"
+	"strconv"
+)
+
+var _ infra.EndpointLister = &Driver{}
+
+func (drv *Driver) Endpoints() []net.Endpoint {
+	list := make([]net.Endpoint, 0)
+
+	// Public URLs go first, since they usually work through proxies
+	list = append(list, drv.publicURLs...)
+
+	ifaceAddrs, err := _net.InterfaceAddrs()
+	if err != nil {
+		return list
+	}
+
+	for _, a := range ifaceAddrs {
+		ipnet, ok := a.(*_net.IPNet)
+		if !ok {
+			continue
+		}
+
+		ipv4 := ipnet.IP.To4()
+		if ipv4 == nil {
+			continue
+		}
+
+		if ipv4.IsLoopback() {
+			continue
+		}
+
+		if ipv4.IsGlobalUnicast() || ipv4.IsPrivate() {
+			list = append(list, Endpoint{
+				host: _net.JoinHostPort(ipv4.String(), strconv.Itoa(drv.ListenPort())),
+				path: drv.config.ListenPath,
+			})
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,79 @@
+package ws
+
+import (
+	"fmt"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"github.com/cryptopunkscc/astrald/node/infra/proxy"
+	"strings"
+)
+
+var _ infra.DriverInjector = &Injector{}
+var _ infra.FormatInjector = &Injector{}
+
+type Injector struct{}
+
+func (*Injector) Inject(i infra.Infra, assets assets.Store, l *log.Logger) error {
+	drv := &Driver{
+		config:     defaultConfig,
+		infra:      i,
+		log:        l,
+		publicURLs: make([]net.Endpoint, 0),
+	}
+
+	if assets != nil {
+		if err := assets.LoadYAML(DriverName, &drv.config); err != nil {
+			l.Errorv(2, "error reading config: %s", err)
+		}
+	}
+
+	if !strings.HasPrefix(drv.config.ListenPath, "/") {
+		drv.config.ListenPath = "/" + drv.config.ListenPath
+	}
+
+	// Add public URLs
+	for _, s := range drv.config.PublicURL {
+		e, err := Parse(s)
+		if err != nil {
+			l.Error("error parsing '%s': %s", s, err)
+			continue
+		}
+
+		drv.publicURLs = append(drv.publicURLs, e)
+	}
+
+	// Set up the proxy. Never fall back to direct dials if the configured proxy is invalid.
+	if drv.config.Proxy != "" {
+		p, err := proxy.New(drv.config.Proxy, nil)
+		if err != nil {
+			return fmt.Errorf("invalid proxy '%s': %w", drv.config.Proxy, err)
+		}
+		drv.proxy = p
+	}
+
+	return i.AddDriver(DriverName, drv)
+}
+
+// InjectFormats adds a log formatter for the driver's endpoints
+func (*Injector) InjectFormats(l *log.Logger) {
+	l.PushFormatFunc(func(v any) ([]log.Op, bool) {
+		e, ok := v.(Endpoint)
+		if !ok {
+			return nil, false
+		}
+
+		return []log.Op{
+			log.OpColor{Color: log.Cyan},
+			log.OpText{Text: e.String()},
+			log.OpReset{},
+		}, true
+	})
+}
+
+func init() {
+	if err := infra.RegisterDriver(DriverName, &Injector{}); err != nil {
+		panic(err)
+	}
+}
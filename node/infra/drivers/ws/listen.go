@@ -0,0 +1,98 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"golang.org/x/net/websocket"
+	_net "net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+var _ infra.Listener = &Driver{}
+
+func (drv *Driver) Listen(ctx context.Context) (<-chan net.Conn, error) {
+	var addrStr = ":" + strconv.Itoa(drv.ListenPort())
+
+	listener, err := _net.Listen("tcp", addrStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var output = make(chan net.Conn)
+	var mu sync.Mutex
+	var closed bool
+
+	// push passes the conn to the output unless the listener is done
+	var push = func(conn net.Conn) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if closed {
+			return false
+		}
+
+		select {
+		case output <- conn:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var mux = http.NewServeMux()
+	mux.Handle(drv.config.ListenPath, websocket.Server{
+		Handler: func(wsConn *websocket.Conn) {
+			var req = wsConn.Request()
+			var local, remote Endpoint
+
+			if addr, ok := req.Context().Value(http.LocalAddrContextKey).(_net.Addr); ok {
+				local = Endpoint{host: addr.String(), path: drv.config.ListenPath}
+			}
+			remote = Endpoint{host: req.RemoteAddr, path: "/"}
+
+			conn := newConn(wsConn, false, local, remote)
+			if !push(conn) {
+				conn.Close()
+				return
+			}
+
+			// the connection is closed when the handler returns
+			select {
+			case <-conn.done:
+			case <-ctx.Done():
+			}
+		},
+	})
+
+	var server = &http.Server{Handler: mux}
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			drv.log.Errorv(1, "serve: %s", err)
+		}
+	}()
+
+	drv.log.Logv(1, "listen ws %s%s", addrStr, drv.config.ListenPath)
+
+	go func() {
+		<-ctx.Done()
+		drv.log.Logv(1, "stop listen ws %s", addrStr)
+		server.Close()
+
+		mu.Lock()
+		closed = true
+		close(output)
+		mu.Unlock()
+	}()
+
+	return output, nil
+}
+
+func (drv *Driver) ListenPort() int {
+	return drv.config.ListenPort
+}
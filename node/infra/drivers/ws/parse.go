@@ -0,0 +1,47 @@
+package ws
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"net/url"
+	"strings"
+)
+
+var _ infra.Parser = &Driver{}
+
+func (drv *Driver) Parse(network string, address string) (net.Endpoint, error) {
+	return Parse(address)
+}
+
+// Parse parses a WebSocket URL. Addresses without a scheme are treated as ws:// URLs.
+func Parse(s string) (endpoint Endpoint, err error) {
+	if !strings.Contains(s, "://") {
+		s = "ws://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return
+	}
+
+	switch u.Scheme {
+	case "ws":
+	case "wss":
+		endpoint.secure = true
+	default:
+		return endpoint, errors.New("invalid scheme")
+	}
+
+	if u.Host == "" {
+		return endpoint, errors.New("missing host")
+	}
+
+	endpoint.host = u.Host
+	endpoint.path = u.EscapedPath()
+	if endpoint.path == "" {
+		endpoint.path = "/"
+	}
+
+	return
+}
@@ -0,0 +1,18 @@
+package ws
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// proxyFor returns the URL of the proxy that should be used to reach the endpoint or nil if there's none
+func proxyFor(endpoint Endpoint) (*url.URL, error) {
+	var scheme = "http"
+	if endpoint.secure {
+		scheme = "https"
+	}
+
+	return http.ProxyFromEnvironment(&http.Request{
+		URL: &url.URL{Scheme: scheme, Host: endpoint.host},
+	})
+}
@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"io"
+	_net "net"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	var tests = map[string]string{
+		"ws://10.0.0.1:1793/astral":     "ws://10.0.0.1:1793/astral",
+		"wss://example.com/astral/link": "wss://example.com/astral/link",
+		"10.0.0.1:1793":                 "ws://10.0.0.1:1793/",
+	}
+
+	for in, out := range tests {
+		e, err := Parse(in)
+		if err != nil {
+			t.Fatalf("parse %s: %v", in, err)
+		}
+		if e.String() != out {
+			t.Fatalf("parse %s: expected %s, got %s", in, out, e.String())
+		}
+
+		u, err := Unpack(e.Pack())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != e {
+			t.Fatalf("unpack: expected %v, got %v", e, u)
+		}
+	}
+
+	if _, err := Parse("http://example.com/"); err == nil {
+		t.Fatal("expected an error for an invalid scheme")
+	}
+}
+
+func TestDialListen(t *testing.T) {
+	// find a free port
+	l, err := _net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var port = l.Addr().(*_net.TCPAddr).Port
+	l.Close()
+
+	var drv = &Driver{
+		config: Config{ListenPort: port, ListenPath: defaultListenPath},
+		log:    log.NewLogger(log.NewPrinterSplitter()),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conns, err := drv.Listen(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, err := Parse((&_net.TCPAddr{IP: _net.IPv4(127, 0, 0, 1), Port: port}).String() + defaultListenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := drv.Dial(ctx, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	var in = <-conns
+	defer in.Close()
+
+	if !out.Outbound() || in.Outbound() {
+		t.Fatal("invalid boundness")
+	}
+
+	var data = bytes.Repeat([]byte("astral"), 10000)
+	go func() {
+		out.Write(data)
+		out.Close()
+	}()
+
+	buf, err := io.ReadAll(io.LimitReader(in, int64(len(data))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data) {
+		t.Fatal("data mismatch")
+	}
+}
@@ -7,11 +7,7 @@ func (i *CoreInfra) Endpoints() []net.Endpoint {
 	var endpoints = make([]net.Endpoint, 0)
 
 	// collect addresses from all enabled drivers
-	for name, drv := range i.networkDrivers {
-		if !i.config.driversContain(name) {
-			continue
-		}
-
+	for _, drv := range i.Drivers() {
 		if lister, ok := drv.(EndpointLister); ok {
 			endpoints = append(endpoints, lister.Endpoints()...)
 		}
@@ -6,3 +6,6 @@ var ErrUnsupportedNetwork = errors.New("unsupported network")
 var ErrInvalidAddress = errors.New("invalid address")
 var ErrConnectionRefused = errors.New("connection refused")
 var ErrDialUnsupported = errors.New("dial unsupported")
+var ErrDriverNotFound = errors.New("driver not found")
+var ErrDriverEnabled = errors.New("driver already enabled")
+var ErrDriverDisabled = errors.New("driver not enabled")
@@ -4,7 +4,9 @@ import (
 	"context"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/events"
 	"github.com/cryptopunkscc/astrald/node/resolver"
+	"github.com/cryptopunkscc/astrald/node/tracker"
 )
 
 // Infra is an interface for infrastructural networks
@@ -16,6 +18,11 @@ type Infra interface {
 	Parse(network string, address string) (net.Endpoint, error)
 	AddDriver(name string, driver Driver) error
 	Drivers() map[string]Driver
+	LoadedDrivers() map[string]Driver
+	EnableDriver(name string) error
+	DisableDriver(name string) error
+	ConfigureDriver(name string, settings map[string]string) error
+	ReloadDriver(name string) error
 }
 
 // Node is a subset of node.Node interface with methods that should be exposed to the network drivers
@@ -23,6 +30,8 @@ type Node interface {
 	Resolver() resolver.Resolver
 	Identity() id.Identity
 	Router() net.Router
+	Tracker() tracker.Tracker
+	Events() *events.Queue
 }
 
 // Dialer wraps the Dial method. Dial opens an unicast connection with the provided address.
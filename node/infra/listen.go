@@ -9,41 +9,90 @@ import (
 
 var _ Listener = &CoreInfra{}
 
+// listener forwards connections from listeners of all running drivers to a single channel
+type listener struct {
+	ctx    context.Context
+	output chan net.Conn
+	wg     sync.WaitGroup
+}
+
+// Listen accepts connections from all running drivers. Drivers started later are added to the output and
+// stopped drivers close their listeners, so the output stays open until the context is done.
 func (i *CoreInfra) Listen(ctx context.Context) (<-chan net.Conn, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
 	if len(i.networkDrivers) == 0 {
 		return nil, errors.New("no drivers available")
 	}
 
-	var output = make(chan net.Conn)
-	var wg = sync.WaitGroup{}
+	if i.listener != nil {
+		return nil, errors.New("already listening")
+	}
 
-	for _, network := range i.Drivers() {
-		listener, ok := network.(Listener)
-		if !ok {
-			continue
-		}
+	var l = &listener{
+		ctx:    ctx,
+		output: make(chan net.Conn),
+	}
+	i.listener = l
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+	for name, r := range i.running {
+		i.startListener(name, r.ctx)
+	}
 
-			// start listening
-			listenCh, err := listener.Listen(ctx)
-			if err != nil {
-				return
-			}
+	// keep the output open until the context is done
+	l.wg.Add(1)
+	go func() {
+		<-ctx.Done()
 
-			// forward connections to the collective output channel
-			for conn := range listenCh {
-				output <- conn
-			}
-		}()
-	}
+		i.mu.Lock()
+		i.listener = nil
+		i.mu.Unlock()
+
+		l.wg.Done()
+	}()
 
 	go func() {
-		wg.Wait()
-		close(output)
+		l.wg.Wait()
+		close(l.output)
 	}()
 
-	return output, nil
+	return l.output, nil
+}
+
+// startListener starts the driver's listener for as long as both the driver and the infra listener run.
+// Caller must hold the lock.
+func (i *CoreInfra) startListener(name string, driverCtx context.Context) {
+	network, ok := i.networkDrivers[name].(Listener)
+	if !ok {
+		return
+	}
+
+	var l = i.listener
+
+	ctx, cancel := context.WithCancel(driverCtx)
+	stop := context.AfterFunc(l.ctx, cancel)
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer cancel()
+		defer stop()
+
+		// start listening
+		listenCh, err := network.Listen(ctx)
+		if err != nil {
+			i.log.Errorv(1, "network %s listen error: %s", name, err)
+			return
+		}
+
+		// forward connections to the collective output channel
+		for conn := range listenCh {
+			select {
+			case l.output <- conn:
+			case <-l.ctx.Done():
+				conn.Close()
+			}
+		}
+	}()
 }
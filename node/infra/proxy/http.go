@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var _ Dialer = &HTTPConnect{}
+
+// HTTPConnect tunnels connections through an HTTP proxy using the CONNECT method
+type HTTPConnect struct {
+	url     *url.URL
+	forward *net.Dialer
+}
+
+func NewHTTPConnect(proxyURL *url.URL, forward *net.Dialer) *HTTPConnect {
+	if forward == nil {
+		forward = &net.Dialer{}
+	}
+
+	return &HTTPConnect{url: proxyURL, forward: forward}
+}
+
+func (p *HTTPConnect) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	var proxyAddr = p.url.Host
+	if p.url.Port() == "" {
+		proxyAddr = net.JoinHostPort(p.url.Hostname(), "80")
+	}
+
+	conn, err := p.forward.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var req = fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if user := p.url.User; user != nil {
+		password, _ := user.Password()
+		var auth = base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req += "Proxy-Authorization: Basic " + auth + "\r\n"
+	}
+	req += "\r\n"
+
+	if _, err := conn.Write([]byte(req)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// the proxy won't send anything past the response before we do, so the buffered reader can be dropped
+	res, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: %s", res.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// echoServer accepts a single connection and echoes everything back
+func echoServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	return l.Addr().String()
+}
+
+// fakeProxy accepts a single connection, runs the handshake and pipes the client to the requested address
+func fakeProxy(t *testing.T, handshake func(conn net.Conn, r *bufio.Reader) (string, error)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var r = bufio.NewReader(conn)
+		addr, err := handshake(conn, r)
+		if err != nil {
+			return
+		}
+
+		target, err := net.Dial("tcp", addr)
+		if err != nil {
+			return
+		}
+		defer target.Close()
+
+		go io.Copy(target, r)
+		io.Copy(conn, target)
+	}()
+
+	return l.Addr().String()
+}
+
+func httpHandshake(conn net.Conn, r *bufio.Reader) (string, error) {
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		return "", err
+	}
+
+	if user, pass, ok := parseBasicAuth(req.Header.Get("Proxy-Authorization")); !ok || user != "user" || pass != "pass" {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		return "", io.EOF
+	}
+
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	return req.Host, nil
+}
+
+func parseBasicAuth(header string) (string, string, bool) {
+	var req = http.Request{Header: http.Header{"Authorization": {header}}}
+	return req.BasicAuth()
+}
+
+func socksHandshake(conn net.Conn, r *bufio.Reader) (string, error) {
+	var buf = make([]byte, 256)
+
+	// greeting: version, method count, methods
+	if _, err := io.ReadFull(r, buf[:2]); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(r, buf[:buf[1]]); err != nil {
+		return "", err
+	}
+	conn.Write([]byte{5, 2}) // username/password
+
+	// auth: version, username, password
+	if _, err := io.ReadFull(r, buf[:2]); err != nil {
+		return "", err
+	}
+	var user = make([]byte, buf[1])
+	io.ReadFull(r, user)
+	io.ReadFull(r, buf[:1])
+	var pass = make([]byte, buf[0])
+	io.ReadFull(r, pass)
+	if string(user) != "user" || string(pass) != "pass" {
+		conn.Write([]byte{1, 1})
+		return "", io.EOF
+	}
+	conn.Write([]byte{1, 0})
+
+	// request: version, command, reserved, ipv4 address and port
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return "", err
+	}
+	if buf[3] != 1 {
+		return "", io.EOF
+	}
+	if _, err := io.ReadFull(r, buf[:6]); err != nil {
+		return "", err
+	}
+	var addr = net.JoinHostPort(net.IP(buf[:4]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(buf[4:]))))
+
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	return addr, nil
+}
+
+func testDial(t *testing.T, proxyURL string) {
+	var target = echoServer(t)
+
+	d, err := New(proxyURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := d.DialContext(ctx, "tcp", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var data = "hello astral"
+	io.WriteString(conn, data)
+
+	var buf = make([]byte, len(data))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != data {
+		t.Fatalf("expected %q, got %q", data, buf)
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+	testDial(t, "http://user:pass@"+fakeProxy(t, httpHandshake))
+}
+
+func TestHTTPConnectUnauthorized(t *testing.T) {
+	d, err := New("http://user:wrong@"+fakeProxy(t, httpHandshake), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.DialContext(context.Background(), "tcp", "127.0.0.1:1"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSOCKS5(t *testing.T) {
+	testDial(t, "socks5://user:pass@"+fakeProxy(t, socksHandshake))
+}
+
+func TestUnsupportedScheme(t *testing.T) {
+	if _, err := New("ftp://127.0.0.1:21", nil); err != ErrUnsupportedScheme {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedScheme, err)
+	}
+}
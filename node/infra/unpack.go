@@ -6,7 +6,11 @@ import (
 )
 
 func (i *CoreInfra) Unpack(network string, data []byte) (net.Endpoint, error) {
-	if n, found := i.networkDrivers[network]; found {
+	i.mu.RLock()
+	n, found := i.networkDrivers[network]
+	i.mu.RUnlock()
+
+	if found {
 		if unpacker, ok := n.(Unpacker); ok {
 			return unpacker.Unpack(network, data)
 		}
@@ -16,7 +20,11 @@ func (i *CoreInfra) Unpack(network string, data []byte) (net.Endpoint, error) {
 }
 
 func (i *CoreInfra) Parse(network string, address string) (net.Endpoint, error) {
-	if n, found := i.networkDrivers[network]; found {
+	i.mu.RLock()
+	n, found := i.networkDrivers[network]
+	i.mu.RUnlock()
+
+	if found {
 		if unpacker, ok := n.(Parser); ok {
 			return unpacker.Parse(network, address)
 		}
@@ -0,0 +1,29 @@
+package link
+
+import (
+	"strconv"
+	"time"
+)
+
+// closeTimeout is the maximum time a party closing the link waits for its pending frames to be written
+const closeTimeout = time.Second
+const drainInterval = 10 * time.Millisecond
+
+// CloseReason tells the remote party why a link is going away
+type CloseReason int
+
+const (
+	CloseNormal   CloseReason = iota // the link is no longer needed
+	CloseShutdown                    // the node is shutting down
+)
+
+func (r CloseReason) String() string {
+	switch r {
+	case CloseNormal:
+		return "normal"
+	case CloseShutdown:
+		return "shutdown"
+	default:
+		return "reason " + strconv.Itoa(int(r))
+	}
+}
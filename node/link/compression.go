@@ -0,0 +1,100 @@
+package link
+
+import (
+	"bytes"
+	"compress/flate"
+	"github.com/cryptopunkscc/astrald/mux"
+	"io"
+	"sync"
+)
+
+// compressMinSize is the size below which frames are not worth compressing
+const compressMinSize = 128
+
+// data frames of links using FeatureDeflate start with one of these markers
+const (
+	frameRaw = iota
+	frameDeflate
+)
+
+// frameCodec compresses and decompresses data frames. Every frame is compressed independently, so that frames
+// can be decoded in any order and a lost port doesn't affect the others.
+type frameCodec struct {
+	wmu sync.Mutex
+	rmu sync.Mutex
+	w   *flate.Writer
+	r   io.ReadCloser
+	buf bytes.Buffer
+}
+
+func newFrameCodec() *frameCodec {
+	w, err := flate.NewWriter(nil, flate.BestSpeed)
+	if err != nil {
+		panic(err)
+	}
+
+	return &frameCodec{
+		w: w,
+		r: flate.NewReader(bytes.NewReader(nil)),
+	}
+}
+
+// encode returns the frame in its wire format. Frames that don't shrink are sent uncompressed.
+func (c *frameCodec) encode(frame []byte) ([]byte, error) {
+	if len(frame) >= compressMinSize {
+		c.wmu.Lock()
+		defer c.wmu.Unlock()
+
+		c.buf.Reset()
+		c.buf.WriteByte(frameDeflate)
+		c.w.Reset(&c.buf)
+		if _, err := c.w.Write(frame); err != nil {
+			return nil, err
+		}
+		if err := c.w.Close(); err != nil {
+			return nil, err
+		}
+
+		if c.buf.Len() <= len(frame) {
+			return bytes.Clone(c.buf.Bytes()), nil
+		}
+	}
+
+	if len(frame)+1 > mux.MaxFrameSize {
+		return nil, mux.ErrFrameTooLarge
+	}
+
+	return append([]byte{frameRaw}, frame...), nil
+}
+
+// decode returns the original contents of a frame in wire format
+func (c *frameCodec) decode(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrProtocolError
+	}
+
+	switch data[0] {
+	case frameRaw:
+		return data[1:], nil
+
+	case frameDeflate:
+		c.rmu.Lock()
+		defer c.rmu.Unlock()
+
+		if err := c.r.(flate.Resetter).Reset(bytes.NewReader(data[1:]), nil); err != nil {
+			return nil, err
+		}
+
+		frame, err := io.ReadAll(io.LimitReader(c.r, mux.MaxFrameSize+1))
+		if err != nil {
+			return nil, ErrProtocolError
+		}
+		if len(frame) > mux.MaxFrameSize {
+			return nil, ErrProtocolError
+		}
+		return frame, nil
+
+	default:
+		return nil, ErrProtocolError
+	}
+}
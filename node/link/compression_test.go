@@ -0,0 +1,37 @@
+package link
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFrameCodec(t *testing.T) {
+	var codec = newFrameCodec()
+
+	var frames = [][]byte{
+		[]byte("short"),
+		bytes.Repeat([]byte(`{"key":"value"}`), 500),
+		make([]byte, defaultMaxFrameSize),
+	}
+
+	for _, frame := range frames {
+		data, err := codec.encode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := codec.decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(decoded, frame) {
+			t.Fatalf("decoded frame does not match")
+		}
+	}
+
+	data, _ := codec.encode(frames[1])
+	if len(data) >= len(frames[1]) {
+		t.Fatalf("frame was not compressed")
+	}
+}
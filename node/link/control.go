@@ -4,36 +4,58 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/debug"
 	"github.com/cryptopunkscc/astrald/mux"
 	"github.com/cryptopunkscc/astrald/net"
 	"io"
 	"math/rand"
+	"sync"
 	"time"
 )
 
 const pingTimeout = 15 * time.Second
 const maxConcurrentPings = 10
 
+// datagramQueueLen is the number of incoming datagrams waiting for the handler before new ones get dropped
+const datagramQueueLen = 64
+
+// maxQueuedDatagramBytes is the amount of control data waiting to be written above which outgoing datagrams
+// get dropped instead of queued
+const maxQueuedDatagramBytes = 64 * 1024
+
 type Control struct {
 	*CoreLink
-	notify map[int][]chan struct{}
-	pings  map[int]chan struct{}
-	nonce  int
+	notify    map[int][]chan struct{}
+	pings     map[int]chan struct{}
+	nonce     int
+	queries   map[int]context.CancelFunc // queries being routed indexed by the remote port
+	qmu       sync.Mutex
+	datagrams chan net.Datagram
 }
 
 func NewControl(link *CoreLink) *Control {
 	return &Control{
-		CoreLink: link,
-		notify:   map[int][]chan struct{}{},
-		pings:    map[int]chan struct{}{},
+		CoreLink:  link,
+		notify:    map[int][]chan struct{}{},
+		pings:     map[int]chan struct{}{},
+		queries:   map[int]context.CancelFunc{},
+		datagrams: make(chan net.Datagram, datagramQueueLen),
 	}
 }
 
 func (c *Control) Run(ctx context.Context) error {
-	<-ctx.Done()
-	return nil
+	for {
+		select {
+		case d := <-c.datagrams:
+			if h := c.DatagramHandler(); h != nil {
+				h.HandleDatagram(d)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
 func (c *Control) handleMux(event mux.Event) {
@@ -42,7 +64,12 @@ func (c *Control) handleMux(event mux.Event) {
 		c.handleFrame(event)
 
 	case mux.Unbind:
-		c.CloseWithError(io.EOF)
+		// a party leaving gracefully sends GoAway before closing the transport
+		if c.remoteReason.Load() >= 0 {
+			c.CloseWithError(ErrLinkClosedByPeer)
+		} else {
+			c.CloseWithError(io.EOF)
+		}
 	}
 }
 
@@ -66,6 +93,14 @@ func (c *Control) handleFrame(frame mux.Frame) {
 		cslq.Invoke(r, c.handleReset)
 	case codeQuery:
 		cslq.Invoke(r, c.handleQuery)
+	case codeGoAway:
+		cslq.Invoke(r, c.handleGoAway)
+	case codeExtQuery:
+		cslq.Invoke(r, c.handleExtQuery)
+	case codeCancel:
+		cslq.Invoke(r, c.handleCancel)
+	case codeDatagram:
+		cslq.Invoke(r, c.handleDatagram)
 	default:
 		c.CloseWithError(ErrProtocolError)
 	}
@@ -149,59 +184,238 @@ func (c *Control) handleReset(msg Reset) error {
 	return nil
 }
 
-// Query sends a Query messsage to the remote party
-func (c *Control) Query(query string, localPort int) error {
+// GoAway lets the remote party know that the link is about to close and why
+func (c *Control) GoAway(reason CloseReason) error {
 	var buf = &bytes.Buffer{}
-	cslq.Encode(buf, "cv", codeQuery, Query{
-		Service: query,
-		Port:    localPort,
-		Buffer:  portBufferSize,
+	cslq.Encode(buf, "cv", codeGoAway, GoAway{
+		Reason: int(reason),
+	})
+	return c.mux.Write(mux.Frame{Data: buf.Bytes()})
+}
+
+func (c *Control) handleGoAway(msg GoAway) error {
+	if !c.HasFeature(FeatureGoAway) {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	if !c.remoteReason.CompareAndSwap(-1, int32(msg.Reason)) {
+		return nil
+	}
+	c.closing.Store(true)
+
+	// the remote party closes the transport once its writes are done, don't wait for it forever
+	time.AfterFunc(2*closeTimeout, func() {
+		c.CloseWithError(ErrLinkClosedByPeer)
 	})
+
+	return nil
+}
+
+// Query sends a Query messsage to the remote party. Queries with arguments are sent as ExtQuery.
+func (c *Control) Query(query net.Query, localPort int) error {
+	var buf = &bytes.Buffer{}
+	if c.HasFeature(FeatureQueryArgs) {
+		cslq.Encode(buf, "cv", codeExtQuery, ExtQuery{
+			Service: query.Query(),
+			Args:    query.Args().Pairs(),
+			Port:    localPort,
+			Buffer:  initialPortWindow,
+		})
+	} else {
+		cslq.Encode(buf, "cv", codeQuery, Query{
+			Service: query.Query(),
+			Port:    localPort,
+			Buffer:  initialPortWindow,
+		})
+	}
 	return c.mux.Write(mux.Frame{Data: buf.Bytes()})
 }
 
+// checkQuery returns an error if the query cannot be sent over the link
+func (c *Control) checkQuery(query net.Query) error {
+	if !c.HasFeature(FeatureQueryArgs) {
+		switch {
+		case len(query.Args()) > 0:
+			return ErrQueryArgsUnsupported
+		case len(query.Query()) > 255:
+			return ErrQueryTooLarge
+		}
+		return nil
+	}
+
+	// code, service, args, port and buffer
+	var size = 1 + 2 + len(query.Query()) + 2 + 2 + 4
+	for _, s := range query.Args().Pairs() {
+		size += 2 + len(s)
+	}
+	if size > mux.MaxFrameSize {
+		return ErrQueryTooLarge
+	}
+	return nil
+}
+
 func (c *Control) handleQuery(msg Query) error {
+	var ctx = c.trackQuery(msg.Port)
+
 	// queries can take a long time to finish, so run them in a goroutine
 	go func() {
 		defer debug.SaveLog(func(p any) {
 			c.Close()
 		})
-		c.executeQuery(msg)
+		c.executeQuery(ctx, msg.Service, nil, msg.Port, msg.Buffer)
+	}()
+
+	return nil
+}
+
+func (c *Control) handleExtQuery(msg ExtQuery) error {
+	if !c.HasFeature(FeatureQueryArgs) {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	args, err := net.QueryArgsFromPairs(msg.Args)
+	if err != nil {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	var ctx = c.trackQuery(msg.Port)
+
+	go func() {
+		defer debug.SaveLog(func(p any) {
+			c.Close()
+		})
+		c.executeQuery(ctx, msg.Service, args, msg.Port, msg.Buffer)
 	}()
 
 	return nil
 }
 
+// trackQuery returns a context for routing a query from the remote port, which will be cancelled if the remote
+// party sends a Cancel message for the port
+func (c *Control) trackQuery(remotePort int) context.Context {
+	c.qmu.Lock()
+	defer c.qmu.Unlock()
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	if prev, found := c.queries[remotePort]; found {
+		prev()
+	}
+	c.queries[remotePort] = cancel
+
+	return ctx
+}
+
+// untrackQuery releases the context of a query from the remote port
+func (c *Control) untrackQuery(ctx context.Context, remotePort int) {
+	c.qmu.Lock()
+	defer c.qmu.Unlock()
+
+	if cancel, found := c.queries[remotePort]; found && ctx.Err() == nil {
+		cancel()
+		delete(c.queries, remotePort)
+	}
+}
+
+// Cancel asks the remote party to stop routing a query sent from the local port
+func (c *Control) Cancel(localPort int) error {
+	var buf = &bytes.Buffer{}
+	cslq.Encode(buf, "cv", codeCancel, Cancel{
+		Port: localPort,
+	})
+	return c.mux.Write(mux.Frame{Data: buf.Bytes()})
+}
+
+func (c *Control) handleCancel(msg Cancel) error {
+	c.qmu.Lock()
+	defer c.qmu.Unlock()
+
+	if cancel, found := c.queries[msg.Port]; found {
+		cancel()
+		delete(c.queries, msg.Port)
+	}
+	return nil
+}
+
+// Datagram sends a datagram to a service of the target hosted by the remote party on behalf of the caller. The
+// datagram is dropped if the control port is congested.
+// Errors: ErrDatagramDropped, ...
+func (c *Control) Datagram(caller id.Identity, target id.Identity, service string, data []byte) error {
+	if c.mux.Queued(controlPort) > maxQueuedDatagramBytes {
+		return ErrDatagramDropped
+	}
+
+	var buf = &bytes.Buffer{}
+	if err := cslq.Encode(buf, "cv", codeDatagram, Datagram{
+		Caller:  caller,
+		Target:  target,
+		Service: service,
+		Data:    data,
+	}); err != nil {
+		return err
+	}
+
+	return c.mux.Write(mux.Frame{Data: buf.Bytes()})
+}
+
+func (c *Control) handleDatagram(msg Datagram) error {
+	if !c.HasFeature(FeatureDatagram) {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	// the remote party can only send datagrams on its own behalf or as an authorized relay
+	if !c.isRelayFor(msg.Caller) {
+		return nil
+	}
+
+	var d = net.Datagram{
+		Caller:  msg.Caller,
+		Target:  msg.Target,
+		Service: msg.Service,
+		Data:    msg.Data,
+	}
+
+	// drop the datagram if the handler can't keep up
+	select {
+	case c.datagrams <- d:
+	default:
+	}
+
+	return nil
+}
+
 // executeQuery executes an incoming query
-func (c *Control) executeQuery(msg Query) error {
-	var query = net.NewQuery(c.RemoteIdentity(), c.LocalIdentity(), msg.Service)
+func (c *Control) executeQuery(ctx context.Context, service string, args net.QueryArgs, remotePort int, remoteBuffer int) error {
+	defer c.untrackQuery(ctx, remotePort)
+
+	var query = net.NewQueryWithArgs(c.RemoteIdentity(), c.LocalIdentity(), service, args)
 
-	var caller = NewPortWriter(c.CoreLink, msg.Port)
+	var caller = NewPortWriter(c.CoreLink, remotePort)
 
 	// lock the port writer so that the target cannot write to it before we get a chance to send the query response
 	caller.Lock()
 	defer caller.Unlock()
 
 	// route the query upstream
-	target, err := c.uplink.RouteQuery(c.ctx, query, caller, net.Hints{Origin: net.OriginNetwork})
+	target, err := c.uplink.RouteQuery(ctx, query, caller, net.Hints{Origin: net.OriginNetwork})
 	if err != nil {
 		var code = errRejected
 		if errors.Is(err, &net.ErrRouteNotFound{}) {
 			code = errRouteNotFound
 		}
-		return c.WriteResponse(msg.Port, &Response{Error: code})
+		return c.WriteResponse(remotePort, &Response{Error: code})
 	}
 
-	c.remoteBuffers.grow(msg.Port, msg.Buffer)
+	c.remoteBuffers.grow(remotePort, remoteBuffer)
 
 	// asign a local port to the target
 	binding, err := c.BindAny(target)
 	if err != nil {
 		target.Close()
-		return c.WriteResponse(msg.Port, &Response{Error: errUnexpected})
+		return c.WriteResponse(remotePort, &Response{Error: errUnexpected})
 	}
+	c.ports.pair(binding, remotePort, service)
 
-	return c.WriteResponse(msg.Port, &Response{Port: int(binding.port.Load()), Buffer: portBufferSize})
+	return c.WriteResponse(remotePort, &Response{Port: int(binding.port.Load()), Buffer: initialPortWindow})
 }
 
 func (c *Control) WriteResponse(port int, r *Response) error {
@@ -8,6 +8,7 @@ import (
 	"github.com/cryptopunkscc/astrald/sig"
 	"github.com/cryptopunkscc/astrald/tasks"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,31 +16,52 @@ var _ net.Link = &CoreLink{}
 
 var DefaultMuxHandler = func(event mux.Event) {}
 
-const portBufferSize = 4 * 1024 * 1024
-const controlPort = 0
+const controlPort = mux.ControlPort
 
 type CoreLink struct {
 	sig.Activity
 	transport     net.SecureConn
 	uplink        net.Router
+	datagrams     atomic.Pointer[datagramHandler]
+	relays        atomic.Pointer[relayChecker]
 	mux           *mux.FrameMux
 	control       *Control
 	remoteBuffers *remoteBuffers
+	windows       *windowBudget
+	ports         *portTable
 	ctx           context.Context
 	cancelCtx     context.CancelFunc
 	mu            sync.Mutex
-	err           error
+	err           atomic.Pointer[error] // the error that closed the link
 	health        *health
+	features      []string
+	codec         *frameCodec
+	closing       atomic.Bool
+	remoteReason  atomic.Int32 // reason sent by the remote party in GoAway or -1
 	running       chan struct{}
 }
 
-func NewCoreLink(transport net.SecureConn) *CoreLink {
+// NewCoreLink returns a new CoreLink over the transport using the agreed link features. If no features are
+// provided, the link will only use the base mux protocol.
+func NewCoreLink(transport net.SecureConn, features ...string) *CoreLink {
+	if len(features) == 0 {
+		features = []string{FeatureMux}
+	}
+
 	link := &CoreLink{
 		transport: transport,
+		features:  features,
 		running:   make(chan struct{}),
 	}
+	link.remoteReason.Store(-1)
+
+	if link.HasFeature(FeatureDeflate) {
+		link.codec = newFrameCodec()
+	}
 
 	link.remoteBuffers = newRemoteBuffers(link)
+	link.windows = newWindowBudget(linkWindowCap)
+	link.ports = newPortTable()
 	link.mux = mux.NewFrameMux(transport, DefaultMuxHandler)
 	link.control = NewControl(link)
 	link.health = newHealth(link)
@@ -51,14 +73,16 @@ func NewCoreLink(transport net.SecureConn) *CoreLink {
 }
 
 func (link *CoreLink) Run(ctx context.Context) error {
+	link.mu.Lock()
 	link.ctx, link.cancelCtx = context.WithCancel(ctx)
+	link.mu.Unlock()
 
 	var group = tasks.Group(link.mux, link.control, link.health)
 
 	close(link.running)
 	group.Run(link.ctx)
 
-	return link.err
+	return link.Err()
 }
 
 // CloseWithError closes the link with provided error as the reason.
@@ -66,12 +90,12 @@ func (link *CoreLink) CloseWithError(e error) error {
 	link.mu.Lock()
 	defer link.mu.Unlock()
 
-	if link.err != nil {
+	if !link.err.CompareAndSwap(nil, &e) {
 		return nil
 	}
-	link.err = e
 
 	defer link.remoteBuffers.reset(0)
+	defer link.mux.Stop()
 	if link.cancelCtx != nil {
 		defer link.cancelCtx()
 	}
@@ -99,11 +123,76 @@ func (link *CoreLink) Unbind(port int) error {
 	return link.mux.Unbind(port)
 }
 
-// Close closes the link.
+// Close closes the link gracefully.
 func (link *CoreLink) Close() error {
+	return link.CloseWithReason(CloseNormal)
+}
+
+// CloseWithReason lets the remote party know that the link is going away, waits for pending frames to be
+// written and closes the link. The reason is only sent if the parties agreed on FeatureGoAway.
+func (link *CoreLink) CloseWithReason(reason CloseReason) error {
+	if !link.closing.CompareAndSwap(false, true) || link.Err() != nil {
+		return link.CloseWithError(ErrLinkClosed)
+	}
+
+	// frames are only written while the link is running
+	select {
+	case <-link.running:
+	default:
+		return link.CloseWithError(ErrLinkClosed)
+	}
+
+	var deadline = time.After(closeTimeout)
+
+	if !link.HasFeature(FeatureGoAway) {
+		link.drain(deadline)
+		return link.CloseWithError(ErrLinkClosed)
+	}
+
+	var sent = make(chan error, 1)
+	go func() {
+		sent <- link.control.GoAway(reason)
+	}()
+
+	select {
+	case err := <-sent:
+		if err == nil {
+			link.drain(deadline)
+		}
+	case <-deadline:
+	}
+
 	return link.CloseWithError(ErrLinkClosed)
 }
 
+// drain waits until all scheduled frames are written or the deadline passes
+func (link *CoreLink) drain(deadline <-chan time.Time) {
+	for link.mux.Pending() > 0 {
+		select {
+		case <-time.After(drainInterval):
+		case <-deadline:
+			return
+		case <-link.ctx.Done():
+			return
+		}
+	}
+}
+
+// Closing returns true if either party started closing the link
+func (link *CoreLink) Closing() bool {
+	return link.closing.Load()
+}
+
+// RemoteCloseReason returns the reason sent by the remote party when it started closing the link. Returns false
+// if the remote party didn't announce closing the link.
+func (link *CoreLink) RemoteCloseReason() (CloseReason, bool) {
+	var reason = link.remoteReason.Load()
+	if reason < 0 {
+		return 0, false
+	}
+	return CloseReason(reason), true
+}
+
 // LocalIdentity returns the identity of the local party.
 func (link *CoreLink) LocalIdentity() id.Identity {
 	return link.transport.LocalIdentity()
@@ -130,10 +219,76 @@ func (link *CoreLink) SetUplink(uplink net.Router) {
 	link.uplink = uplink
 }
 
+// DatagramHandler returns the handler of incoming datagrams
+func (link *CoreLink) DatagramHandler() net.DatagramHandler {
+	if h := link.datagrams.Load(); h != nil {
+		return h.DatagramHandler
+	}
+	return nil
+}
+
+// SetDatagramHandler sets the handler of incoming datagrams. Datagrams received without a handler are dropped.
+func (link *CoreLink) SetDatagramHandler(handler net.DatagramHandler) {
+	link.datagrams.Store(&datagramHandler{handler})
+}
+
+// SetRelayChecker sets the checker of callers other than the remote party. Without it, datagrams sent on behalf
+// of other identities are dropped.
+func (link *CoreLink) SetRelayChecker(checker RelayChecker) {
+	link.relays.Store(&relayChecker{checker})
+}
+
+// isRelayFor returns true if the remote party may send datagrams on behalf of the identity
+func (link *CoreLink) isRelayFor(identity id.Identity) bool {
+	if identity.IsEqual(link.RemoteIdentity()) {
+		return true
+	}
+	if c := link.relays.Load(); c != nil {
+		return c.IsRelayFor(identity, link.RemoteIdentity())
+	}
+	return false
+}
+
+// SendDatagram sends an unreliable datagram to a service of the remote party. The target can be the remote party
+// or an identity hosted by it.
+// Errors: net.ErrDatagramsUnsupported, net.ErrDatagramTooLarge, ErrDatagramDropped, ErrLinkClosing, ...
+func (link *CoreLink) SendDatagram(d net.Datagram) error {
+	switch {
+	case !link.HasFeature(FeatureDatagram):
+		return net.ErrDatagramsUnsupported
+	case len(d.Data) > net.MaxDatagramSize, len(d.Service) > 255:
+		return net.ErrDatagramTooLarge
+	case link.closing.Load():
+		return ErrLinkClosing
+	case link.Err() != nil:
+		return ErrLinkClosed
+	}
+
+	var caller, target = d.Caller, d.Target
+	if caller.IsZero() {
+		caller = link.LocalIdentity()
+	}
+	if target.IsZero() {
+		target = link.RemoteIdentity()
+	}
+
+	return link.control.Datagram(caller, target, d.Service, d.Data)
+}
+
 func (link *CoreLink) Transport() net.SecureConn {
 	return link.transport
 }
 
+// Features returns a copy of the list of features agreed upon with the remote party
+func (link *CoreLink) Features() []string {
+	return append([]string{}, link.features...)
+}
+
+// HasFeature returns true if the feature was agreed upon with the remote party
+func (link *CoreLink) HasFeature(feature string) bool {
+	return containsFeature(link.features, feature)
+}
+
 // Ping sends a new ping request and returns its roundtrip time
 func (link *CoreLink) Ping() (time.Duration, error) {
 	return link.control.Ping()
@@ -153,31 +308,70 @@ func (link *CoreLink) Done() <-chan struct{} {
 
 // Err returns the error that caused the link to close or nil if the link is open
 func (link *CoreLink) Err() error {
-	return link.err
+	if err := link.err.Load(); err != nil {
+		return *err
+	}
+	return nil
 }
 
+// write sends a data frame to the remote port. The remote buffer is reserved before the frame is queued, so that
+// frames of other ports can be queued while this one waits to be transmitted.
 func (link *CoreLink) write(port int, frame []byte) error {
+	data, err := link.prepare(port, frame)
+	if err != nil {
+		return err
+	}
+
+	err = link.mux.Write(mux.Frame{
+		Port: port,
+		Data: data,
+	})
+	if err != nil {
+		link.remoteBuffers.release(port, len(frame))
+		return err
+	}
+
+	if binding := link.ports.byRemote(port); binding != nil {
+		binding.sent.add(len(frame))
+	}
+
+	return nil
+}
+
+// prepare reserves space in the remote buffer and returns the frame in its wire format
+func (link *CoreLink) prepare(port int, frame []byte) ([]byte, error) {
 	link.mu.Lock()
 	defer link.mu.Unlock()
 
-	bufferSize, open := link.remoteBuffers.size(port)
-	if !open {
-		return ErrRemoteBufferOverflow
+	if err := link.remoteBuffers.reserve(port, len(frame)); err != nil {
+		return nil, err
 	}
 
-	if len(frame) > bufferSize {
-		return ErrRemoteBufferOverflow
+	if link.codec == nil {
+		return frame, nil
 	}
 
-	err := link.mux.Write(mux.Frame{
-		Port: port,
-		Data: frame,
-	})
+	data, err := link.codec.encode(frame)
 	if err != nil {
-		return err
+		link.remoteBuffers.release(port, len(frame))
+		return nil, err
 	}
 
-	link.remoteBuffers.grow(port, -len(frame))
+	return data, nil
+}
 
-	return nil
+// datagramHandler wraps a net.DatagramHandler so that it can be stored atomically
+type datagramHandler struct {
+	net.DatagramHandler
+}
+
+// RelayChecker wraps the IsRelayFor method. IsRelayFor returns true if the relay is authorized to act on behalf
+// of the identity.
+type RelayChecker interface {
+	IsRelayFor(identity id.Identity, relay id.Identity) bool
+}
+
+// relayChecker wraps a RelayChecker so that it can be stored atomically
+type relayChecker struct {
+	RelayChecker
 }
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"github.com/cryptopunkscc/astrald/auth"
+	"github.com/cryptopunkscc/astrald/auth/brontide"
 	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/streams"
 	"io"
@@ -72,8 +75,8 @@ func TestCoreLink(t *testing.T) {
 	var id2, _ = id.GenerateIdentity()
 
 	var id1conn, id2conn = streams.Pipe()
-	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn))
-	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn))
+	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn), FeatureMux, FeatureGoAway)
+	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn), FeatureMux, FeatureGoAway)
 	var wg sync.WaitGroup
 
 	id1link.SetUplink(&TestRouter{t})
@@ -167,8 +170,449 @@ func TestOpenAccept(t *testing.T) {
 			t.Fatal(err)
 			return
 		}
+		if !link.HasFeature(FeatureMux) {
+			t.Error("mux feature not negotiated")
+		}
 		link.Close()
 	}()
 
 	wg.Wait()
 }
+
+func TestOpenIK(t *testing.T) {
+	var left, right = streams.Pipe()
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+	var ctx = context.Background()
+
+	var accepted = make(chan *CoreLink, 1)
+	go func() {
+		link, err := Accept(ctx, &FakeConn{ReadWriteCloser: left}, leftID)
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- link
+	}()
+
+	link, err := OpenIK(ctx, &FakeConn{ReadWriteCloser: right}, leftID, rightID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer link.Close()
+
+	remote := <-accepted
+	if remote == nil {
+		t.FailNow()
+	}
+	defer remote.Close()
+
+	if p := link.Transport().(*auth.NoiseConn).Pattern(); p != brontide.PatternIK {
+		t.Fatalf("handshake used %s pattern, expected %s", p, brontide.PatternIK)
+	}
+	if !remote.RemoteIdentity().IsEqual(rightID) {
+		t.Fatal("remote identity mismatch")
+	}
+	if !link.HasFeature(FeatureMux) || !remote.HasFeature(FeatureIK) {
+		t.Fatal("features not negotiated")
+	}
+}
+
+func TestOpenLegacyAccept(t *testing.T) {
+	var wg sync.WaitGroup
+	var left, right = streams.Pipe()
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+	var ctx = context.Background()
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+
+		conn, err := auth.HandshakeInbound(ctx, &FakeConn{ReadWriteCloser: left}, leftID)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		// legacy parties advertise mux only and expect a single feature in return
+		if err := cslq.Encode(conn, featureListFormat, []string{FeatureMux}); err != nil {
+			t.Error(err)
+			return
+		}
+
+		var feature string
+		if err := cslq.Decode(conn, featureFormat, &feature); err != nil {
+			t.Error(err)
+			return
+		}
+		if feature != FeatureMux {
+			t.Errorf("requested feature %s, expected %s", feature, FeatureMux)
+		}
+		cslq.Encode(conn, "c", featureAccepted)
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		link, err := Open(ctx, &FakeConn{ReadWriteCloser: right}, leftID, rightID)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer link.Close()
+
+		if f := link.Features(); len(f) != 1 || f[0] != FeatureMux {
+			t.Errorf("negotiated features %v, expected [%s]", f, FeatureMux)
+		}
+	}()
+
+	wg.Wait()
+}
+
+type ArgsRouter struct{}
+
+func (ArgsRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		conn.Write([]byte(query.Args().Get("msg")))
+		conn.Close()
+	})
+}
+
+func TestQueryArgs(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var id1, _ = id.GenerateIdentity()
+	var id2, _ = id.GenerateIdentity()
+
+	var id1conn, id2conn = streams.Pipe()
+	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn), Features()...)
+	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn), Features()...)
+	id1link.SetUplink(ArgsRouter{})
+	id2link.SetUplink(ArgsRouter{})
+
+	go id1link.Run(ctx)
+	go id2link.Run(ctx)
+
+	var query = net.NewQueryWithArgs(id2, id1, "testing", net.QueryArgs{"msg": msg})
+	conn, err := net.Route(ctx, id2link, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf = &bytes.Buffer{}
+	io.Copy(buf, conn)
+
+	if buf.String() != msg {
+		t.Fatalf("received '%s', expected '%s'", buf.String(), msg)
+	}
+
+	var legacy = NewCoreLink(NewSecureConn(id2, id1, id2conn))
+	var _, caller = net.SecurePipe(id2)
+	if _, err := legacy.RouteQuery(ctx, query, caller, net.Hints{}); !errors.Is(err, ErrQueryArgsUnsupported) {
+		t.Fatalf("legacy link returned %v, expected %v", err, ErrQueryArgsUnsupported)
+	}
+}
+
+type BlockingRouter struct {
+	cancelled chan struct{}
+}
+
+func (r *BlockingRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	<-ctx.Done()
+	close(r.cancelled)
+	return nil, ctx.Err()
+}
+
+func TestQueryCancel(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var id1, _ = id.GenerateIdentity()
+	var id2, _ = id.GenerateIdentity()
+
+	var id1conn, id2conn = streams.Pipe()
+	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn), Features()...)
+	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn), Features()...)
+	var router = &BlockingRouter{cancelled: make(chan struct{})}
+	id1link.SetUplink(router)
+	id2link.SetUplink(router)
+
+	go id1link.Run(ctx)
+	go id2link.Run(ctx)
+
+	queryCtx, queryCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer queryCancel()
+
+	_, err := net.Route(queryCtx, id2link, net.NewQuery(id2, id1, "testing"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("query returned %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	select {
+	case <-router.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("query was not cancelled on the remote party")
+	}
+}
+
+func TestDatagram(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var id1, _ = id.GenerateIdentity()
+	var id2, _ = id.GenerateIdentity()
+
+	var id1conn, id2conn = streams.Pipe()
+	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn), Features()...)
+	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn), Features()...)
+
+	var received = make(chan net.Datagram, 1)
+	id1link.SetDatagramHandler(net.DatagramHandlerFunc(func(d net.Datagram) {
+		received <- d
+	}))
+
+	go id1link.Run(ctx)
+	go id2link.Run(ctx)
+
+	err := id2link.SendDatagram(net.Datagram{Service: "presence", Data: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case d := <-received:
+		if d.Service != "presence" || string(d.Data) != "hello" {
+			t.Fatalf("received %s:%q", d.Service, d.Data)
+		}
+		if !d.Caller.IsEqual(id2) || !d.Target.IsEqual(id1) {
+			t.Fatal("datagram identities mismatch")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("datagram not received")
+	}
+
+	// datagrams on behalf of other identities are dropped unless the sender is their relay
+	var appID, _ = id.GenerateIdentity()
+	err = id2link.SendDatagram(net.Datagram{Caller: appID, Service: "presence", Data: []byte("spoofed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// datagrams are handled in order, so the next one arrives only after the spoofed one was dropped
+	if err = id2link.SendDatagram(net.Datagram{Service: "presence", Data: []byte("ok")}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case d := <-received:
+		if string(d.Data) != "ok" {
+			t.Fatalf("received %q", d.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("datagram not received")
+	}
+
+	var relays = relayList{appID.PublicKeyHex() + id2.PublicKeyHex(): true}
+	id1link.SetRelayChecker(relays)
+
+	// datagrams carry the identities of the apps on both sides
+	var targetID, _ = id.GenerateIdentity()
+	err = id2link.SendDatagram(net.Datagram{Caller: appID, Target: targetID, Service: "presence", Data: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case d := <-received:
+		if string(d.Data) != "hello" {
+			t.Fatalf("received %q", d.Data)
+		}
+		if !d.Caller.IsEqual(appID) || !d.Target.IsEqual(targetID) {
+			t.Fatal("datagram identities mismatch")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("datagram not received")
+	}
+
+	err = id2link.SendDatagram(net.Datagram{Service: "presence", Data: make([]byte, net.MaxDatagramSize+1)})
+	if !errors.Is(err, net.ErrDatagramTooLarge) {
+		t.Fatalf("sending large datagram returned %v, expected %v", err, net.ErrDatagramTooLarge)
+	}
+}
+
+// relayList authorizes relays listed by the hex keys of the identity and the relay
+type relayList map[string]bool
+
+func (l relayList) IsRelayFor(identity id.Identity, relay id.Identity) bool {
+	return l[identity.PublicKeyHex()+relay.PublicKeyHex()]
+}
+
+type EchoRouter struct{}
+
+func (EchoRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		io.Copy(conn, conn)
+		conn.Close()
+	})
+}
+
+func TestLinkStats(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var id1, _ = id.GenerateIdentity()
+	var id2, _ = id.GenerateIdentity()
+
+	var id1conn, id2conn = streams.Pipe()
+	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn), Features()...)
+	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn), Features()...)
+	id1link.SetUplink(EchoRouter{})
+	id2link.SetUplink(EchoRouter{})
+
+	go id1link.Run(ctx)
+	go id2link.Run(ctx)
+
+	conn, err := net.Route(ctx, id2link, net.NewQuery(id2, id1, "echo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	var buf = make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats = id2link.Stats()
+	if len(stats.Ports) != 1 {
+		t.Fatalf("link has %d ports, expected 1", len(stats.Ports))
+	}
+	var p = stats.Ports[0]
+	if p.Query != "echo" {
+		t.Fatalf("port query is '%s', expected 'echo'", p.Query)
+	}
+	if p.BytesSent != uint64(len(msg)) || p.BytesReceived != uint64(len(msg)) {
+		t.Fatalf("port sent %d and received %d bytes, expected %d", p.BytesSent, p.BytesReceived, len(msg))
+	}
+	if stats.BytesSent < p.BytesSent || stats.FramesReceived == 0 {
+		t.Fatal("link counters don't include port traffic")
+	}
+}
+
+func TestCloseWithoutGoAway(t *testing.T) {
+	var ctx = context.Background()
+	var id1, _ = id.GenerateIdentity()
+	var id2, _ = id.GenerateIdentity()
+
+	var id1conn, id2conn = streams.Pipe()
+	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn))
+	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn))
+
+	go id1link.Run(ctx)
+	go id2link.Run(ctx)
+
+	// links without FeatureGoAway close the transport without announcing it
+	id2link.Close()
+	<-id1link.Done()
+
+	if _, ok := id1link.RemoteCloseReason(); ok {
+		t.Fatal("unexpected close reason from a legacy link")
+	}
+	if errors.Is(id1link.Err(), ErrProtocolError) {
+		t.Fatal("legacy close treated as a protocol error")
+	}
+}
+
+// recordingConn keeps a copy of the first write
+type recordingConn struct {
+	io.ReadWriteCloser
+	first chan []byte
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	select {
+	case c.first <- bytes.Clone(p):
+	default:
+	}
+	return c.ReadWriteCloser.Write(p)
+}
+
+func TestAcceptIKReplay(t *testing.T) {
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+	var ctx = context.Background()
+
+	// record the first handshake message of a genuine IK handshake
+	var left, right = streams.Pipe()
+	var recorder = &recordingConn{ReadWriteCloser: right, first: make(chan []byte, 1)}
+	go func() {
+		if l, err := Accept(ctx, &FakeConn{ReadWriteCloser: left}, leftID); err == nil {
+			l.Close()
+		}
+	}()
+	l, err := OpenIK(ctx, &FakeConn{ReadWriteCloser: recorder}, leftID, rightID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	var actOne = <-recorder.first
+
+	// replay it to the responder
+	left, right = streams.Pipe()
+	var accepted = make(chan error, 1)
+	go func() {
+		l, err := Accept(ctx, &FakeConn{ReadWriteCloser: left}, leftID)
+		if err == nil {
+			l.Close()
+		}
+		accepted <- err
+	}()
+
+	if _, err := right.Write(actOne); err != nil {
+		t.Fatal(err)
+	}
+	go io.Copy(io.Discard, right)
+
+	select {
+	case err := <-accepted:
+		t.Fatalf("replayed handshake accepted with %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	right.Close()
+	if err := <-accepted; err == nil {
+		t.Fatal("replayed handshake produced a link")
+	}
+}
+
+func TestOpenIKFailure(t *testing.T) {
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+	var ctx = context.Background()
+
+	// a responder that answers with a packet of another handshake pattern rejects IK
+	var left, right = streams.Pipe()
+	go func() {
+		var buf = make([]byte, 4096)
+		left.Read(buf)
+		left.Write(make([]byte, 50))
+	}()
+
+	_, err := OpenIK(ctx, &FakeConn{ReadWriteCloser: right}, leftID, rightID)
+	if !errors.Is(err, ErrIKFailed) {
+		t.Fatalf("rejected handshake returned %v, expected %v", err, ErrIKFailed)
+	}
+
+	// a connection lost during the handshake says nothing about IK support
+	left, right = streams.Pipe()
+	go func() {
+		var buf = make([]byte, 4096)
+		left.Read(buf)
+		left.Close()
+	}()
+
+	_, err = OpenIK(ctx, &FakeConn{ReadWriteCloser: right}, leftID, rightID)
+	if err == nil || errors.Is(err, ErrIKFailed) {
+		t.Fatalf("interrupted handshake returned %v", err)
+	}
+}
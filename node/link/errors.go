@@ -12,3 +12,8 @@ var ErrPortClosed = errors.New("port closed")
 var ErrPingTimeout = errors.New("ping timeout")
 var ErrTooManyPings = errors.New("too many pings in progress")
 var ErrInvalidNonce = errors.New("invalid ping nonce")
+var ErrLinkClosing = errors.New("link closing")
+var ErrQueryArgsUnsupported = errors.New("query arguments not supported by the remote party")
+var ErrQueryTooLarge = errors.New("query too large")
+var ErrDatagramDropped = errors.New("datagram dropped")
+var ErrIKFailed = errors.New("IK handshake failed")
@@ -0,0 +1,64 @@
+package link
+
+// FeatureMux is the base multiplexing protocol every link has to support
+const FeatureMux = "mux"
+
+// FeatureSessions marks links able to carry resumable sessions between the parties
+const FeatureSessions = "sessions"
+
+// FeatureMultipath marks links able to join a session carried by other links
+const FeatureMultipath = "multipath"
+
+// FeatureDeflate compresses data frames with DEFLATE
+const FeatureDeflate = "deflate"
+
+// FeatureQueryArgs allows queries with arguments and service names longer than 255 bytes
+const FeatureQueryArgs = "query-args"
+
+// FeatureCancel allows cancelling queries that are still being routed by the remote party
+const FeatureCancel = "cancel"
+
+// FeatureDatagram allows sending unreliable datagrams to services of the remote party
+const FeatureDatagram = "datagram"
+
+// FeatureRekey periodically replaces the session keys of the transport with keys mixed with a fresh ephemeral key
+const FeatureRekey = "rekey"
+
+// FeatureIK marks parties that accept the single round trip IK handshake. Agreeing on it tells the initiator that
+// it can use OpenIK for the next links with the party.
+const FeatureIK = "ik"
+
+// FeatureGoAway lets a party announce that it's closing the link and why before closing the transport
+const FeatureGoAway = "goaway"
+
+// featureNegotiate is advertised by parties that can agree on a set of features instead of a single one. Nodes that
+// don't advertise it only understand the legacy single-feature request.
+const featureNegotiate = "negotiate"
+
+// localFeatures holds the list of features supported by this implementation in order of preference
+var localFeatures = []string{FeatureMux, FeatureSessions, FeatureMultipath, FeatureDeflate, FeatureQueryArgs, FeatureCancel, FeatureDatagram, FeatureRekey, FeatureIK, FeatureGoAway}
+
+// Features returns a copy of the list of link features supported locally
+func Features() []string {
+	return append([]string{}, localFeatures...)
+}
+
+// intersectFeatures returns features present in both lists, preserving the order of the first list
+func intersectFeatures(a []string, b []string) []string {
+	var list = make([]string, 0)
+	for _, f := range a {
+		if containsFeature(b, f) {
+			list = append(list, f)
+		}
+	}
+	return list
+}
+
+func containsFeature(list []string, feature string) bool {
+	for _, f := range list {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
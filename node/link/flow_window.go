@@ -0,0 +1,140 @@
+package link
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	minPortWindow     = 64 * 1024
+	initialPortWindow = 256 * 1024
+	maxPortWindow     = 16 * 1024 * 1024
+
+	// linkWindowCap limits how much all port windows of a link can grow above their initial size
+	linkWindowCap = 64 * 1024 * 1024
+
+	defaultRTT        = 100 * time.Millisecond
+	minSampleInterval = 50 * time.Millisecond
+)
+
+// windowBudget tracks memory reserved for receive windows of a link
+type windowBudget struct {
+	mu   sync.Mutex
+	cap  int
+	used int
+}
+
+func newWindowBudget(cap int) *windowBudget {
+	return &windowBudget{cap: cap}
+}
+
+// reserve reserves up to n bytes and returns the number of bytes actually reserved
+func (b *windowBudget) reserve(n int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n = max(0, min(n, b.cap-b.used))
+	b.used += n
+	return n
+}
+
+func (b *windowBudget) release(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= n
+}
+
+// Used returns the number of reserved bytes
+func (b *windowBudget) Used() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.used
+}
+
+// portWindow is the receive window of a port. The window adapts to the rate at which the consumer reads data and
+// to the round trip time of the link, so that it holds about two bandwidth-delay products.
+type portWindow struct {
+	mu       sync.Mutex
+	link     *CoreLink
+	size     int
+	reserved int // part of the window reserved from the link's budget
+	debt     int // number of bytes the window still has to shrink by
+	consumed int // bytes consumed in the current sample
+	sampleAt time.Time
+}
+
+func newPortWindow(link *CoreLink) *portWindow {
+	return &portWindow{
+		link:     link,
+		size:     initialPortWindow,
+		sampleAt: time.Now(),
+	}
+}
+
+// consume records that n bytes were consumed from the port's buffer and returns the credit for the sender
+// along with the new size of the window. The window grows by adding extra credit and shrinks by withholding it.
+func (w *portWindow) consume(n int) (credit int, size int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.consumed += n
+	credit = n
+
+	var rtt = w.link.Latency()
+	if rtt <= 0 {
+		rtt = defaultRTT
+	}
+
+	if elapsed := time.Since(w.sampleAt); elapsed >= max(rtt, minSampleInterval) {
+		var rate = float64(w.consumed) / elapsed.Seconds()
+		var target = int(2 * rate * rtt.Seconds())
+		target = max(minPortWindow, min(target, maxPortWindow))
+
+		switch {
+		case target > w.size:
+			var grant = w.link.windows.reserve(target - w.size)
+			w.reserved += grant
+			w.size += grant
+			w.debt = 0
+			credit += grant
+
+		case target < w.size:
+			w.debt = w.size - target
+		}
+
+		w.consumed = 0
+		w.sampleAt = time.Now()
+	}
+
+	if w.debt > 0 {
+		var d = min(w.debt, credit)
+		credit -= d
+		w.debt -= d
+		w.size -= d
+
+		var r = min(d, w.reserved)
+		w.reserved -= r
+		w.link.windows.release(r)
+	}
+
+	return credit, w.size
+}
+
+// Size returns the current size of the window
+func (w *portWindow) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.size
+}
+
+// close releases the memory reserved by the window
+func (w *portWindow) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.link.windows.release(w.reserved)
+	w.reserved = 0
+}
@@ -0,0 +1,58 @@
+package link
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/streams"
+	"testing"
+	"time"
+)
+
+func TestPortWindow(t *testing.T) {
+	var id1, _ = id.GenerateIdentity()
+	var id2, _ = id.GenerateIdentity()
+	var conn, _ = streams.Pipe()
+	var link = NewCoreLink(NewSecureConn(id1, id2, conn))
+	var window = newPortWindow(link)
+
+	// a fast consumer makes the window grow
+	window.sampleAt = time.Now().Add(-time.Second)
+	credit, size := window.consume(10 * 1024 * 1024)
+	if size <= initialPortWindow {
+		t.Fatalf("window did not grow (size %d)", size)
+	}
+	if credit != 10*1024*1024+size-initialPortWindow {
+		t.Fatalf("unexpected credit %d for window size %d", credit, size)
+	}
+	if link.windows.Used() != size-initialPortWindow {
+		t.Fatalf("budget used %d, expected %d", link.windows.Used(), size-initialPortWindow)
+	}
+
+	// a slow consumer makes it shrink
+	window.sampleAt = time.Now().Add(-time.Second)
+	credit, _ = window.consume(1024)
+	if credit != 0 {
+		t.Fatalf("credit %d was not withheld", credit)
+	}
+	for window.debt > 0 {
+		_, size = window.consume(64 * 1024)
+	}
+	if size != minPortWindow {
+		t.Fatalf("window size %d, expected %d", size, minPortWindow)
+	}
+	if link.windows.Used() != 0 {
+		t.Fatalf("budget used %d, expected 0", link.windows.Used())
+	}
+
+	// growth is limited by the link's budget
+	link.windows = newWindowBudget(1024)
+	window.sampleAt = time.Now().Add(-time.Second)
+	_, size = window.consume(10 * 1024 * 1024)
+	if size != minPortWindow+1024 {
+		t.Fatalf("window size %d, expected %d", size, minPortWindow+1024)
+	}
+
+	window.close()
+	if link.windows.Used() != 0 {
+		t.Fatalf("budget used %d after close, expected 0", link.windows.Used())
+	}
+}
@@ -2,40 +2,47 @@ package link
 
 import (
 	"context"
+	"github.com/cryptopunkscc/astrald/net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type health struct {
 	link    *CoreLink
 	sig     chan struct{}
-	latency time.Duration
+	latency atomic.Int64 // last measured latency in nanoseconds or -1 if unknown
+	history []net.RTTSample
+	mu      sync.Mutex
 }
 
 func newHealth(link *CoreLink) *health {
-	return &health{
-		link:    link,
-		sig:     make(chan struct{}, 1),
-		latency: -1,
+	var h = &health{
+		link: link,
+		sig:  make(chan struct{}, 1),
 	}
+	h.latency.Store(-1)
+	return h
 }
 
 func (h *health) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-h.sig:
-			var err error
-			h.latency, err = h.link.control.Ping()
+			latency, err := h.link.control.Ping()
 			if err != nil {
 				h.link.CloseWithError(err)
 				return err
 			}
+			h.latency.Store(int64(latency))
+			h.record(latency)
 
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 
 			case <-h.link.Done():
-				return h.link.err
+				return h.link.Err()
 
 			case <-time.After(time.Second):
 			}
@@ -44,7 +51,7 @@ func (h *health) Run(ctx context.Context) error {
 			return ctx.Err()
 
 		case <-h.link.Done():
-			return h.link.err
+			return h.link.Err()
 		}
 	}
 }
@@ -57,5 +64,23 @@ func (h *health) Check() {
 }
 
 func (h *health) Latency() time.Duration {
-	return h.latency
+	return time.Duration(h.latency.Load())
+}
+
+// History returns recent round trip time samples, oldest first
+func (h *health) History() []net.RTTSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]net.RTTSample{}, h.history...)
+}
+
+func (h *health) record(rtt time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.history = append(h.history, net.RTTSample{At: time.Now(), RTT: rtt})
+	if len(h.history) > rttHistoryLen {
+		h.history = h.history[len(h.history)-rttHistoryLen:]
+	}
 }
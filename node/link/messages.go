@@ -1,11 +1,17 @@
 package link
 
+import "github.com/cryptopunkscc/astrald/auth/id"
+
 const (
 	codeQuery = iota
 	codeGrowBuffer
 	codeReset
 	codePing
 	codePong
+	codeGoAway
+	codeExtQuery
+	codeCancel
+	codeDatagram
 )
 
 const (
@@ -36,12 +42,39 @@ type Pong struct {
 	Nonce int `cslq:"l"`
 }
 
+type GoAway struct {
+	Reason int `cslq:"c"`
+}
+
 type Query struct {
 	Service string `cslq:"[c]c"`
 	Port    int    `cslq:"s"`
 	Buffer  int    `cslq:"l"`
 }
 
+// ExtQuery is a Query with key/value arguments and a longer service name. Args is a flat list of keys and values.
+type ExtQuery struct {
+	Service string   `cslq:"[s]c"`
+	Args    []string `cslq:"[s][s]c"`
+	Port    int      `cslq:"s"`
+	Buffer  int      `cslq:"l"`
+}
+
+// Cancel aborts routing of a query sent from the port
+type Cancel struct {
+	Port int `cslq:"s"`
+}
+
+// Datagram carries an unreliable message to a service of the remote party. Caller is the identity on whose
+// behalf the sending party sent the datagram and Target is the identity hosted by the receiving party that the
+// datagram is addressed to.
+type Datagram struct {
+	Caller  id.Identity `cslq:"v"`
+	Target  id.Identity `cslq:"v"`
+	Service string      `cslq:"[c]c"`
+	Data    []byte      `cslq:"[s]c"`
+}
+
 type Response struct {
 	Error  int `cslq:"c"`
 	Port   int `cslq:"s"`
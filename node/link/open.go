@@ -1,16 +1,28 @@
 package link
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/cryptopunkscc/astrald/auth"
+	"github.com/cryptopunkscc/astrald/auth/brontide"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/net"
+	"time"
 )
 
-const featureMux = "mux"
 const featureListFormat = "[s][c]c"
+const featureFormat = "[c]c"
+
+const (
+	featureAccepted = iota
+	featureRejected
+)
+
+// confirmTimeout is how long the responder of an IK handshake waits for the initiator to confirm it
+const confirmTimeout = 5 * time.Second
 
 func Open(ctx context.Context, conn net.Conn, remoteID id.Identity, localID id.Identity) (link *CoreLink, err error) {
 	defer func() {
@@ -24,36 +36,98 @@ func Open(ctx context.Context, conn net.Conn, remoteID id.Identity, localID id.I
 		return
 	}
 
-	var linkFeatures []string
+	var remoteFeatures []string
 
-	err = cslq.Decode(secureConn, featureListFormat, &linkFeatures)
+	err = cslq.Decode(secureConn, featureListFormat, &remoteFeatures)
 	if err != nil {
 		return
 	}
 
-	var muxFound bool
-	for _, f := range linkFeatures {
-		if f == featureMux {
-			muxFound = true
-		}
-	}
-	if !muxFound {
+	if !containsFeature(remoteFeatures, FeatureMux) {
 		return nil, errors.New("remote party does not support mux")
 	}
 
-	err = cslq.Encode(secureConn, "[c]c", featureMux)
+	var features = []string{FeatureMux}
+
+	if containsFeature(remoteFeatures, featureNegotiate) {
+		features = intersectFeatures(localFeatures, remoteFeatures)
+
+		err = cslq.Encode(secureConn, featureFormat+featureListFormat, featureNegotiate, features)
+	} else {
+		// legacy parties accept only a single feature
+		err = cslq.Encode(secureConn, featureFormat, FeatureMux)
+	}
 	if err != nil {
 		return
 	}
 
 	var errCode int
 	err = cslq.Decode(secureConn, "c", &errCode)
-	if errCode != 0 {
+	if err != nil {
+		return
+	}
+	if errCode != featureAccepted {
 		err = errors.New("link feature negotation error")
 		return
 	}
 
-	return NewCoreLink(secureConn), nil
+	// rekey records may follow the accept code
+	return newLink(secureConn, features), nil
+}
+
+// OpenIK opens a link using the IK handshake, which together with feature negotiation takes a single round trip.
+// The remote party has to support FeatureIK, otherwise the handshake fails and the connection is closed. If the
+// remote party rejected the handshake, the error wraps ErrIKFailed.
+func OpenIK(ctx context.Context, conn net.Conn, remoteID id.Identity, localID id.Identity) (link *CoreLink, err error) {
+	defer func() {
+		if err != nil {
+			conn.Close()
+		}
+	}()
+
+	var offer = &bytes.Buffer{}
+	if err = cslq.Encode(offer, featureListFormat, localFeatures); err != nil {
+		return
+	}
+
+	secureConn, response, err := auth.HandshakeOutboundIK(ctx, conn, remoteID, localID, offer.Bytes())
+	if err != nil {
+		if errors.Is(err, brontide.ErrAuthFailed) {
+			err = fmt.Errorf("%w: %w", ErrIKFailed, err)
+		}
+		return
+	}
+
+	var features []string
+	if err = cslq.Decode(bytes.NewReader(response), featureListFormat, &features); err != nil {
+		return
+	}
+
+	if !containsFeature(features, FeatureMux) {
+		return nil, errors.New("remote party does not support mux")
+	}
+
+	for _, f := range features {
+		if !containsFeature(localFeatures, f) {
+			return nil, errors.New("unsupported feature accepted by the remote party")
+		}
+	}
+
+	// confirm the handshake, the responder doesn't use the link before it hears from us
+	if err = cslq.Encode(secureConn, "c", featureAccepted); err != nil {
+		return
+	}
+
+	return newLink(secureConn, features), nil
+}
+
+// newLink returns a new link over the authenticated connection and turns on transport features of the link
+func newLink(secureConn *auth.NoiseConn, features []string) *CoreLink {
+	if containsFeature(features, FeatureRekey) {
+		secureConn.EnableRekey(brontide.DefaultRekeyPolicy)
+	}
+
+	return NewCoreLink(secureConn, features...)
 }
 
 func Accept(ctx context.Context, conn net.Conn, localID id.Identity) (link *CoreLink, err error) {
@@ -63,33 +137,101 @@ func Accept(ctx context.Context, conn net.Conn, localID id.Identity) (link *Core
 		}
 	}()
 
-	secureConn, err := auth.HandshakeInbound(ctx, conn, localID)
+	// features of an IK handshake are agreed upon during the handshake
+	var ikFeatures []string
+	secureConn, err := auth.HandshakeInboundIK(ctx, conn, localID, func(payload []byte) ([]byte, error) {
+		var offered []string
+		if err := cslq.Decode(bytes.NewReader(payload), featureListFormat, &offered); err != nil {
+			return nil, err
+		}
+		if !containsFeature(offered, FeatureMux) {
+			return nil, errors.New("remote party does not support mux")
+		}
+
+		ikFeatures = intersectFeatures(offered, localFeatures)
+
+		var buf = &bytes.Buffer{}
+		err := cslq.Encode(buf, featureListFormat, ikFeatures)
+		return buf.Bytes(), err
+	})
 	if err != nil {
 		return
 	}
 
-	var linkFeatures = []string{featureMux}
+	if secureConn.Pattern() == brontide.PatternIK {
+		// a replayed first message completes the IK handshake too, so wait for the initiator to prove that it
+		// holds the session keys before the link is used
+		if err = readConfirmation(ctx, secureConn); err != nil {
+			return
+		}
+		return newLink(secureConn, ikFeatures), nil
+	}
 
-	err = cslq.Encode(secureConn, featureListFormat, linkFeatures)
+	err = cslq.Encode(secureConn, featureListFormat, append(Features(), featureNegotiate))
 	if err != nil {
 		return
 	}
 
-	for {
-		var feature string
-		err = cslq.Decode(secureConn, "[c]c", &feature)
+	var feature string
+	err = cslq.Decode(secureConn, featureFormat, &feature)
+	if err != nil {
+		return
+	}
+
+	switch feature {
+	case FeatureMux:
+		cslq.Encode(secureConn, "c", featureAccepted)
+		return NewCoreLink(secureConn, FeatureMux), nil
+
+	case featureNegotiate:
+		var features []string
+		err = cslq.Decode(secureConn, featureListFormat, &features)
 		if err != nil {
 			return
 		}
 
-		switch feature {
-		case featureMux:
-			cslq.Encode(secureConn, "c", 0)
-			return NewCoreLink(secureConn), nil
+		if !containsFeature(features, FeatureMux) {
+			cslq.Encode(secureConn, "c", featureRejected)
+			return nil, errors.New("remote party does not support mux")
+		}
+
+		for _, f := range features {
+			if !containsFeature(localFeatures, f) {
+				cslq.Encode(secureConn, "c", featureRejected)
+				return nil, errors.New("unsupported feature requested by the remote party")
+			}
+		}
+
+		cslq.Encode(secureConn, "c", featureAccepted)
+
+		// the remote party expects rekey records only after the accept code
+		return newLink(secureConn, features), nil
+
+	default:
+		cslq.Encode(secureConn, "c", featureRejected)
+		return nil, errors.New("unsupported feature requested by the remote party")
+	}
+}
+
+// readConfirmation reads the first transport message of an IK handshake sent by the initiator
+func readConfirmation(ctx context.Context, conn net.SecureConn) error {
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
 
-		default:
-			cslq.Encode(secureConn, "c", 1)
-			return nil, errors.New("unsupported feature requested by the remote party")
+	var stop = context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	var code int
+	if err := cslq.Decode(conn, "c", &code); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
+		return err
+	}
+	if code != featureAccepted {
+		return errors.New("invalid handshake confirmation")
 	}
+	return nil
 }
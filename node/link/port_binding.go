@@ -9,21 +9,39 @@ import (
 
 type PortBinding struct {
 	*net.OutputField
-	async *streams.AsyncWriter
-	link  *CoreLink
-	port  atomic.Int32
+	async      *streams.AsyncWriter
+	window     *portWindow
+	link       *CoreLink
+	port       atomic.Int32
+	remotePort atomic.Int32 // remote port the binding exchanges data with or -1
+	query      atomic.Pointer[string]
+	sent       counters
+	received   counters
 }
 
 func NewPortBinding(output net.SecureWriteCloser, link *CoreLink) *PortBinding {
 	binding := &PortBinding{
-		link:  link,
-		async: streams.NewAsyncWriter(output, portBufferSize),
+		link:   link,
+		async:  streams.NewAsyncWriter(output, initialPortWindow),
+		window: newPortWindow(link),
 	}
 	binding.OutputField = net.NewOutputField(binding, output)
+	binding.remotePort.Store(-1)
 
 	binding.async.SetAfterFlush(func(bytes []byte) {
-		if p := binding.port.Load(); p != 0 {
-			link.control.GrowBuffer(int(p), len(bytes))
+		credit, size := binding.window.consume(len(bytes))
+
+		// the buffer has to hold the whole window before the sender learns about the extra credit
+		if size > binding.async.BufferSize() {
+			binding.async.SetBufferSize(size)
+		}
+
+		if p := binding.port.Load(); p != 0 && credit > 0 {
+			link.control.GrowBuffer(int(p), credit)
+		}
+
+		if size < binding.async.BufferSize() {
+			binding.async.SetBufferSize(size)
 		}
 	})
 
@@ -34,13 +52,17 @@ func (binding *PortBinding) HandleMux(event mux.Event) {
 	switch event := event.(type) {
 	case mux.Bind:
 		binding.port.Store(int32(event.Port))
+		binding.link.ports.bind(event.Port, binding)
 
 	case mux.Frame:
 		binding.handleFrame(event)
 
 	case mux.Unbind:
-		binding.link.control.Reset(int(binding.port.Swap(0)))
+		var port = int(binding.port.Swap(0))
+		binding.link.ports.unbind(port)
+		binding.link.control.Reset(port)
 		binding.async.Close()
+		binding.window.close()
 	}
 }
 
@@ -79,8 +101,19 @@ func (binding *PortBinding) handleFrame(frame mux.Frame) {
 		return
 	}
 
+	var data = frame.Data
+	if codec := binding.link.codec; codec != nil {
+		var err error
+		if data, err = codec.decode(data); err != nil {
+			binding.link.CloseWithError(err)
+			return
+		}
+	}
+
+	binding.received.add(len(data))
+
 	// add chunk to the buffer
-	if _, err := binding.async.Write(frame.Data); err != nil {
+	if _, err := binding.async.Write(data); err != nil {
 		binding.link.CloseWithError(err)
 	}
 }
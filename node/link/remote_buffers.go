@@ -42,6 +42,33 @@ func (buffers *remoteBuffers) grow(port int, size int) {
 	buffers.cond.Broadcast()
 }
 
+// reserve takes size bytes from port's buffer. It fails if the port is closed or its buffer is too small.
+func (buffers *remoteBuffers) reserve(port int, size int) error {
+	buffers.cond.L.Lock()
+	defer buffers.cond.L.Unlock()
+
+	s, open := buffers.sizes[port]
+	if !open || s < size {
+		return ErrRemoteBufferOverflow
+	}
+
+	buffers.sizes[port] = s - size
+	return nil
+}
+
+// release returns bytes taken by reserve to port's buffer unless the port was closed in the meantime
+func (buffers *remoteBuffers) release(port int, size int) {
+	buffers.cond.L.Lock()
+	defer buffers.cond.L.Unlock()
+
+	if _, open := buffers.sizes[port]; !open {
+		return
+	}
+
+	buffers.sizes[port] += size
+	buffers.cond.Broadcast()
+}
+
 // wait waits for port's buffer to be at least size bytes and returns nil. If the link closes while wait is waiting,
 // it will return the error with which the link was closed.
 func (buffers *remoteBuffers) wait(port int, size int) error {
@@ -49,8 +76,8 @@ func (buffers *remoteBuffers) wait(port int, size int) error {
 	defer buffers.cond.L.Unlock()
 
 	for {
-		if buffers.link.err != nil {
-			return buffers.link.err // should this be simply ErrLinkClosed?
+		if err := buffers.link.Err(); err != nil {
+			return err // should this be simply ErrLinkClosed?
 		}
 		s, ok := buffers.sizes[port]
 		if !ok {
@@ -18,6 +18,15 @@ func (link *CoreLink) RouteQuery(ctx context.Context, query net.Query, caller ne
 		return nil, errors.New("caller/writer identity mismatch")
 	}
 
+	// don't start new queries over a link that's going away
+	if link.Closing() {
+		return nil, ErrLinkClosing
+	}
+
+	if err := link.control.checkQuery(query); err != nil {
+		return nil, err
+	}
+
 	// request a health check to make sure the link is responsive
 	link.health.Check()
 
@@ -28,56 +37,66 @@ func (link *CoreLink) RouteQuery(ctx context.Context, query net.Query, caller ne
 		return nil, err
 	}
 
-	// set up response handler
+	// set up response handler. The results are only read after done is closed, because the caller may give up
+	// on the query before the response arrives.
 	var done = make(chan struct{})
+	var resTarget net.SecureWriteCloser
+	var resErr error
 	responseHandler.Func = func(res Response, herr error) {
 		defer close(done)
 
 		// we have the response, so unbind the port so that it can be bound to the caller
 		link.Unbind(localPort)
 
-		if err = herr; err != nil {
-			link.CloseWithError(err)
+		if resErr = herr; resErr != nil {
+			link.CloseWithError(resErr)
 			return
 		}
 
 		// check error response
-		err = codeToError(res.Error)
-		if err != nil {
+		resErr = codeToError(res.Error)
+		if resErr != nil {
 			if res.Error == errRouteNotFound {
-				err = &net.ErrRouteNotFound{Router: link}
+				resErr = &net.ErrRouteNotFound{Router: link}
 			}
 			return
 		}
 
 		// rebind the port to the caller
-		_, err = link.Bind(localPort, caller)
-		if err != nil {
+		var binding *PortBinding
+		binding, resErr = link.Bind(localPort, caller)
+		if resErr != nil {
 			return
 		}
+		link.ports.pair(binding, res.Port, query.Query())
 
 		// grow the remote buffer for the port
 		link.remoteBuffers.grow(res.Port, res.Buffer)
 
 		// prepare the target
-		target = NewPortWriter(link, res.Port)
+		resTarget = NewPortWriter(link, res.Port)
 	}
 
 	// send the query to the remote peer
-	if err := link.control.Query(query.Query(), localPort); err != nil {
+	if err := link.control.Query(query, localPort); err != nil {
 		link.CloseWithError(err)
 		return nil, err
 	}
 
 	select {
 	case <-done:
-		return
+		return resTarget, resErr
 
 	case <-ctx.Done():
 		go func() {
+			// let the remote party stop routing the query
+			if link.HasFeature(FeatureCancel) {
+				link.control.Cancel(localPort)
+			}
+
 			<-done
-			if target != nil {
-				target.Close()
+			if resTarget != nil {
+				resTarget.Close()
 			}
 		}()
 
@@ -0,0 +1,138 @@
+package link
+
+import (
+	"github.com/cryptopunkscc/astrald/net"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// rttHistoryLen is the number of round trip time samples kept by the link
+const rttHistoryLen = 16
+
+// counters counts bytes and frames going one way
+type counters struct {
+	bytes  atomic.Uint64
+	frames atomic.Uint64
+}
+
+func (c *counters) add(n int) {
+	c.bytes.Add(uint64(n))
+	c.frames.Add(1)
+}
+
+// portTable keeps track of ports bound on the link and the remote ports they exchange data with
+type portTable struct {
+	mu     sync.Mutex
+	local  map[int]*PortBinding
+	remote map[int]*PortBinding
+}
+
+func newPortTable() *portTable {
+	return &portTable{
+		local:  map[int]*PortBinding{},
+		remote: map[int]*PortBinding{},
+	}
+}
+
+func (t *portTable) bind(port int, binding *PortBinding) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.local[port] = binding
+}
+
+// pair records that the binding exchanges data with the remote port
+func (t *portTable) pair(binding *PortBinding, remotePort int, query string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	binding.remotePort.Store(int32(remotePort))
+	binding.query.Store(&query)
+	t.remote[remotePort] = binding
+}
+
+func (t *portTable) unbind(port int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	binding, found := t.local[port]
+	if !found {
+		return
+	}
+	delete(t.local, port)
+
+	if r := int(binding.remotePort.Load()); r >= 0 && t.remote[r] == binding {
+		delete(t.remote, r)
+	}
+}
+
+// byRemote returns the binding paired with the remote port or nil
+func (t *portTable) byRemote(remotePort int) *PortBinding {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.remote[remotePort]
+}
+
+func (t *portTable) all() []*PortBinding {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var list = make([]*PortBinding, 0, len(t.local))
+	for _, b := range t.local {
+		list = append(list, b)
+	}
+	return list
+}
+
+// Stats returns a snapshot of link's traffic counters
+func (link *CoreLink) Stats() net.LinkStats {
+	var m = link.mux.Stats()
+
+	var stats = net.LinkStats{
+		BytesSent:      m.BytesSent,
+		BytesReceived:  m.BytesReceived,
+		FramesSent:     m.FramesSent,
+		FramesReceived: m.FramesReceived,
+		Queued:         m.Queued,
+		RTT:            link.health.History(),
+	}
+
+	for _, binding := range link.ports.all() {
+		var p = binding.Stats()
+		stats.Window += p.Window
+		stats.Ports = append(stats.Ports, p)
+	}
+
+	sort.Slice(stats.Ports, func(i, j int) bool {
+		return stats.Ports[i].Port < stats.Ports[j].Port
+	})
+
+	return stats
+}
+
+// Stats returns a snapshot of port's traffic counters
+func (binding *PortBinding) Stats() net.PortStats {
+	var stats = net.PortStats{
+		Port:           binding.Port(),
+		RemotePort:     int(binding.remotePort.Load()),
+		BytesSent:      binding.sent.bytes.Load(),
+		BytesReceived:  binding.received.bytes.Load(),
+		FramesSent:     binding.sent.frames.Load(),
+		FramesReceived: binding.received.frames.Load(),
+		Window:         binding.window.Size(),
+		Buffered:       binding.Used(),
+	}
+
+	if q := binding.query.Load(); q != nil {
+		stats.Query = *q
+	}
+
+	if stats.RemotePort >= 0 {
+		stats.RemoteWindow, _ = binding.link.remoteBuffers.size(stats.RemotePort)
+		stats.Queued = binding.link.mux.Queued(stats.RemotePort)
+	}
+
+	return stats
+}
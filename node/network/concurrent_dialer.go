@@ -4,65 +4,184 @@ import (
 	"context"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/infra"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// staggerDelay is how long the dialer waits for better endpoints before it starts dialing the next tier
+	staggerDelay = 250 * time.Millisecond
+
+	// slowStaggerDelay is used instead of staggerDelay before dialing networks with a score below slowPriority
+	slowStaggerDelay = 2 * time.Second
+	slowPriority     = 250
+)
+
+// ConcurrentDialer dials endpoints in tiers of equal network score, best first. The next tier starts after
+// a delay or as soon as all dials started so far have failed. Cancel the context once a link is established
+// to stop dialing the remaining endpoints.
 type ConcurrentDialer struct {
 	dialer      infra.Dialer
 	concurrency int
+	score       func(network string) int
 }
 
 func NewConcurrentDialer(dialer infra.Dialer, concurrency int) *ConcurrentDialer {
 	return &ConcurrentDialer{
 		dialer:      dialer,
 		concurrency: concurrency,
+		score:       getNetworkPriority,
 	}
 }
 
+// SetScoreFunc sets the function used to score networks. By default, networks are scored by their priority.
+func (d *ConcurrentDialer) SetScoreFunc(fn func(network string) int) *ConcurrentDialer {
+	d.score = fn
+	return d
+}
+
 func (d *ConcurrentDialer) Dial(ctx context.Context, endpoints <-chan net.Endpoint) <-chan net.Conn {
 	out := make(chan net.Conn)
 
-	// spawn workers
-	var wg sync.WaitGroup
-	wg.Add(d.concurrency)
-	for i := 0; i < d.concurrency; i++ {
-		go func() {
+	go func() {
+		defer close(out)
+
+		var wg sync.WaitGroup
+		var sem = make(chan struct{}, d.concurrency)
+		var active atomic.Int32
+		var launching atomic.Bool
+		var idle = make(chan struct{}, 1)
+
+		// signalIdle lets the next tier start early
+		var signalIdle = func() {
+			select {
+			case idle <- struct{}{}:
+			default:
+			}
+		}
+
+		var dial = func(endpoint net.Endpoint) {
 			defer wg.Done()
-			for {
+
+			conn, err := d.dialer.Dial(ctx, endpoint)
+			<-sem
+
+			if err != nil {
+				// let the next tier start early if nothing else is being dialed
+				if active.Add(-1) == 0 && !launching.Load() {
+					signalIdle()
+				}
+				return
+			}
+			active.Add(-1)
+
+			select {
+			case <-ctx.Done():
+				conn.Close()
+
+			case <-time.After(HandshakeTimeout):
+				conn.Close()
+
+			case out <- conn:
+			}
+		}
+
+	tiers:
+		for i, tier := range dialTiers(collectEndpoints(ctx, endpoints), d.score) {
+			if i > 0 {
+				var delay = staggerDelay
+				if tier.score < slowPriority {
+					delay = slowStaggerDelay
+				}
+
+				var timer = time.NewTimer(delay)
 				select {
 				case <-ctx.Done():
-					return
-
-				case endpoint, ok := <-endpoints:
-					// channel closed?
-					if !ok {
-						return
-					}
-
-					conn, err := d.dialer.Dial(ctx, endpoint)
-					if err != nil {
-						return
-					}
-					select {
-					case <-ctx.Done():
-						return
-
-					case <-time.After(HandshakeTimeout):
-						conn.Close()
-
-					case out <- conn:
-					}
+					timer.Stop()
+					break tiers
+				case <-idle:
+					timer.Stop()
+				case <-timer.C:
 				}
 			}
-		}()
-	}
 
-	// close output channel once all workers are done
-	go func() {
+			// only failures of the whole tier may start the next one
+			launching.Store(true)
+			select {
+			case <-idle:
+			default:
+			}
+
+			for _, endpoint := range tier.endpoints {
+				select {
+				case <-ctx.Done():
+					break tiers
+				case sem <- struct{}{}:
+				}
+
+				active.Add(1)
+				wg.Add(1)
+				go dial(endpoint)
+			}
+
+			launching.Store(false)
+			if active.Load() == 0 {
+				signalIdle()
+			}
+		}
+
+		// wait for all dials to finish before closing the output channel
 		wg.Wait()
-		close(out)
 	}()
 
 	return out
 }
+
+// dialTier is a group of endpoints of networks with equal score
+type dialTier struct {
+	score     int
+	endpoints []net.Endpoint
+}
+
+// dialTiers groups endpoints by network score, highest first
+func dialTiers(endpoints []net.Endpoint, score func(network string) int) []dialTier {
+	var scores = make(map[string]int)
+	for _, e := range endpoints {
+		if _, found := scores[e.Network()]; !found {
+			scores[e.Network()] = score(e.Network())
+		}
+	}
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return scores[endpoints[i].Network()] > scores[endpoints[j].Network()]
+	})
+
+	var tiers []dialTier
+	for _, e := range endpoints {
+		var s = scores[e.Network()]
+		if len(tiers) == 0 || tiers[len(tiers)-1].score != s {
+			tiers = append(tiers, dialTier{score: s})
+		}
+		tiers[len(tiers)-1].endpoints = append(tiers[len(tiers)-1].endpoints, e)
+	}
+
+	return tiers
+}
+
+// collectEndpoints reads endpoints until the channel closes or the context is done
+func collectEndpoints(ctx context.Context, endpoints <-chan net.Endpoint) []net.Endpoint {
+	var list []net.Endpoint
+	for {
+		select {
+		case <-ctx.Done():
+			return list
+		case e, ok := <-endpoints:
+			if !ok {
+				return list
+			}
+			list = append(list, e)
+		}
+	}
+}
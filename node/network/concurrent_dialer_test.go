@@ -0,0 +1,143 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testConn struct {
+	endpoint net.Endpoint
+}
+
+func (c *testConn) Read(p []byte) (int, error)   { return 0, errors.New("not implemented") }
+func (c *testConn) Write(p []byte) (int, error)  { return len(p), nil }
+func (c *testConn) Close() error                 { return nil }
+func (c *testConn) Outbound() bool               { return true }
+func (c *testConn) LocalEndpoint() net.Endpoint  { return nil }
+func (c *testConn) RemoteEndpoint() net.Endpoint { return c.endpoint }
+
+// testDialer fails dials to networks listed in fail and records which networks were dialed
+type testDialer struct {
+	fail   map[string]bool
+	mu     sync.Mutex
+	dialed []string
+}
+
+func (d *testDialer) Dial(ctx context.Context, e net.Endpoint) (net.Conn, error) {
+	d.mu.Lock()
+	d.dialed = append(d.dialed, e.Network())
+	d.mu.Unlock()
+
+	if d.fail[e.Network()] {
+		return nil, errors.New("unreachable")
+	}
+	return &testConn{endpoint: e}, nil
+}
+
+func (d *testDialer) networks() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.dialed...)
+}
+
+func endpointChan(networks ...string) <-chan net.Endpoint {
+	var ch = make(chan net.Endpoint, len(networks))
+	for _, n := range networks {
+		ch <- net.NewGenericEndpoint(n, nil)
+	}
+	close(ch)
+	return ch
+}
+
+func TestDialerPriority(t *testing.T) {
+	var d = &testDialer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var out = NewConcurrentDialer(d, workers).Dial(ctx, endpointChan("tor", "gw", "inet"))
+
+	select {
+	case conn := <-out:
+		if conn.RemoteEndpoint().Network() != "inet" {
+			t.Fatalf("expected inet first, got %s", conn.RemoteEndpoint().Network())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// a link was established, so the rest is not needed
+	cancel()
+	for range out {
+	}
+
+	if dialed := d.networks(); len(dialed) != 1 {
+		t.Fatalf("expected only inet to be dialed, got %v", dialed)
+	}
+}
+
+func TestDialerFallback(t *testing.T) {
+	var d = &testDialer{fail: map[string]bool{"inet": true}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var start = time.Now()
+	var out = NewConcurrentDialer(d, workers).Dial(ctx, endpointChan("inet", "tor"))
+
+	select {
+	case conn := <-out:
+		if conn.RemoteEndpoint().Network() != "tor" {
+			t.Fatalf("expected tor, got %s", conn.RemoteEndpoint().Network())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	// failed dials start the next tier without waiting for the delay
+	if time.Since(start) >= slowStaggerDelay {
+		t.Fatal("fallback waited for the stagger delay")
+	}
+
+	for range out {
+	}
+}
+
+func TestDialerScores(t *testing.T) {
+	var d = &testDialer{}
+	var peer, _ = id.GenerateIdentity()
+
+	// inet to this peer has been congested for a while
+	var lrn = newLearner()
+	lrn.penalties[learnKey{peer: peer.PublicKeyHex(), network: "inet"}] = 350
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var out = NewConcurrentDialer(d, workers).SetScoreFunc(func(network string) int {
+		score, _ := lrn.networkScore(peer, network)
+		return score
+	}).Dial(ctx, endpointChan("inet", "tor"))
+
+	select {
+	case conn := <-out:
+		if conn.RemoteEndpoint().Network() != "tor" {
+			t.Fatalf("expected tor first, got %s", conn.RemoteEndpoint().Network())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	cancel()
+	for range out {
+	}
+
+	if dialed := d.networks(); len(dialed) != 1 {
+		t.Fatalf("expected only tor to be dialed, got %v", dialed)
+	}
+}
@@ -2,11 +2,9 @@ package network
 
 import (
 	"context"
-	"errors"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/link"
-	"io"
 	"sync"
 )
 
@@ -14,12 +12,27 @@ type ConcurrentHandshake struct {
 	localID  id.Identity
 	remoteID id.Identity
 	workers  int
+	ik       bool
+	onError  func(conn net.Conn, err error)
 }
 
 func NewConcurrentHandshake(localID id.Identity, remoteID id.Identity, workers int) *ConcurrentHandshake {
 	return &ConcurrentHandshake{localID: localID, remoteID: remoteID, workers: workers}
 }
 
+// SetIK makes the handshake use the single round trip IK pattern. The remote party has to support it.
+func (h *ConcurrentHandshake) SetIK(ik bool) *ConcurrentHandshake {
+	h.ik = ik
+	return h
+}
+
+// SetErrorHandler sets a function called from handshake workers with every connection that failed the handshake.
+// The connection is closed after the handler returns.
+func (h *ConcurrentHandshake) SetErrorHandler(fn func(conn net.Conn, err error)) *ConcurrentHandshake {
+	h.onError = fn
+	return h
+}
+
 func (h *ConcurrentHandshake) Outbound(ctx context.Context, conns <-chan net.Conn) <-chan net.Link {
 	var ch = make(chan net.Link, h.workers)
 	var wg sync.WaitGroup
@@ -40,16 +53,16 @@ func (h *ConcurrentHandshake) Outbound(ctx context.Context, conns <-chan net.Con
 					}
 
 					hctx, _ := context.WithTimeout(ctx, HandshakeTimeout)
-					l, err := link.Open(hctx, conn, h.remoteID, h.localID)
+					var open = link.Open
+					if h.ik {
+						open = link.OpenIK
+					}
+					l, err := open(hctx, conn, h.remoteID, h.localID)
 
 					// if handshake failed, try next connection
 					if err != nil {
-						switch {
-						case errors.Is(err, io.EOF):
-						case errors.Is(err, context.DeadlineExceeded):
-						case errors.Is(err, context.Canceled):
-						default:
-							//TODO: add injectable error handler?
+						if h.onError != nil {
+							h.onError(conn, err)
 						}
 						conn.Close()
 						continue
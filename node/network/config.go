@@ -0,0 +1,9 @@
+package network
+
+type Config struct {
+	// Priorities overrides the default priorities of networks, e.g. {"gw": 450} to prefer gateway links
+	Priorities map[string]int `yaml:"priorities"`
+
+	// LearnPriorities adjusts link selection by latency and backlog measured on links with each peer
+	LearnPriorities bool `yaml:"learn_priorities"`
+}
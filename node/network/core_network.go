@@ -3,6 +3,7 @@ package network
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/debug"
 	"github.com/cryptopunkscc/astrald/log"
@@ -25,6 +26,7 @@ var _ net.Router = &CoreNetwork{}
 
 type CoreNetwork struct {
 	links     *LinkSet
+	sessions  *SessionSet
 	server    *Server
 	events    events.Queue
 	log       *log.Logger
@@ -32,22 +34,36 @@ type CoreNetwork struct {
 	tasks     *tasks.FIFOScheduler
 	linkTasks map[string]*tasks.Task[net.Link]
 	ctx       context.Context
+	linkCtx   context.Context
 	running   atomic.Bool
 	mu        sync.Mutex
 	linkMu    sync.Mutex
+	datagrams atomic.Pointer[datagramHandler]
+	relays    sync.Map // expiry time of relay authorizations indexed by relayKey
+	relinks   sync.Map // relinkEntry of nodes that lost links to address changes and still need relinking
+	learner   *learner // nil unless priorities are learned
 }
 
-func NewCoreNetwork(node Node, eventParent *events.Queue, log *log.Logger) (*CoreNetwork, error) {
+func NewCoreNetwork(node Node, config Config, eventParent *events.Queue, log *log.Logger) (*CoreNetwork, error) {
 	var err error
 
 	m := &CoreNetwork{
 		node:      node,
 		log:       log.Tag(logTag),
 		links:     NewLinkSet(),
+		sessions:  NewSessionSet(),
 		tasks:     tasks.NewFIFOScheduler(workers, queueSize),
 		linkTasks: make(map[string]*tasks.Task[net.Link]),
 	}
 
+	for name, priority := range config.Priorities {
+		SetNetworkPriority(name, priority)
+	}
+
+	if config.LearnPriorities {
+		m.learner = newLearner()
+	}
+
 	m.events.SetParent(eventParent)
 	m.server, err = newServer(node.Identity(), node.Infra(), m.AddLink, m.log)
 	if err != nil {
@@ -67,6 +83,11 @@ func (n *CoreNetwork) Run(ctx context.Context) error {
 	n.ctx = ctx
 	var wg sync.WaitGroup
 
+	// links outlive the network context, so that they can be closed gracefully
+	var cancelLinks context.CancelFunc
+	n.linkCtx, cancelLinks = context.WithCancel(context.Background())
+	defer cancelLinks()
+
 	wg.Add(1)
 	go func() {
 		defer debug.SaveLog(debug.SigInt)
@@ -82,6 +103,22 @@ func (n *CoreNetwork) Run(ctx context.Context) error {
 
 	}()
 
+	// watch for address changes
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n.watchInterfaces(ctx)
+	}()
+
+	// measure links
+	if n.learner != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.learner.run(ctx, n.links)
+		}()
+	}
+
 	// run the scheduler
 	wg.Add(1)
 	go func() {
@@ -93,10 +130,16 @@ func (n *CoreNetwork) Run(ctx context.Context) error {
 
 	wg.Wait()
 
-	// close all links
+	// close all links letting the peers know we're shutting down
+	var closeWg sync.WaitGroup
 	for _, l := range n.links.All() {
-		l.Close()
+		closeWg.Add(1)
+		go func(l net.Link) {
+			defer closeWg.Done()
+			closeLink(l, link.CloseShutdown)
+		}(l.Link)
 	}
+	closeWg.Wait()
 
 	return nil
 }
@@ -109,8 +152,9 @@ func (n *CoreNetwork) Events() *events.Queue {
 	return &n.events
 }
 
+// AddLink adds the link to the network. Outbound links are reported to the tracker as successful dials.
 func (n *CoreNetwork) AddLink(l net.Link) error {
-	return n.addLink(l)
+	return n.addLink(l, true)
 }
 
 func (n *CoreNetwork) Links() *LinkSet {
@@ -187,7 +231,9 @@ func (n *CoreNetwork) RequestNewLink(nodeID id.Identity, opts LinkOptions) (*tas
 	return t, n.tasks.Add(t)
 }
 
-func (n *CoreNetwork) addLink(l net.Link) error {
+// addLink adds the link to the network and, if report is set and the link is outbound, reports its endpoint
+// to the tracker
+func (n *CoreNetwork) addLink(l net.Link, report bool) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
@@ -200,7 +246,18 @@ func (n *CoreNetwork) addLink(l net.Link) error {
 	}
 
 	if corelink, ok := l.(*link.CoreLink); ok {
-		corelink.SetUplink(n.node.Router())
+		corelink.SetUplink(NewSessionRouter(n, l, n.node.Router()))
+		corelink.SetDatagramHandler(n)
+		corelink.SetRelayChecker(n)
+		switch ik := corelink.HasFeature(link.FeatureIK); {
+		case ik && !n.supportsIK(l.RemoteIdentity()):
+			if err := n.node.Tracker().SetSupportsIK(l.RemoteIdentity(), true); err != nil {
+				n.log.Errorv(1, "error saving IK support of %v: %v", l.RemoteIdentity(), err)
+			}
+		case !ik && n.supportsIK(l.RemoteIdentity()):
+			// the node no longer agrees on IK
+			n.forgetIK(l.RemoteIdentity())
+		}
 		defer corelink.Check()
 	}
 
@@ -212,16 +269,132 @@ func (n *CoreNetwork) addLink(l net.Link) error {
 	go func() {
 		defer debug.SaveLog(debug.SigInt)
 
-		err := l.Run(n.ctx)
+		err := l.Run(n.linkCtx)
 		if e := n.links.Remove(active.ID()); e != nil {
 			panic(e)
 		}
+		var relink bool
+		if l, ok := l.(*link.CoreLink); ok {
+			if reason, ok := l.RemoteCloseReason(); ok {
+				err = fmt.Errorf("%w (%s)", err, reason)
+
+				// the peer is restarting, link again once it's back to keep its sessions alive
+				relink = reason == link.CloseShutdown
+			}
+		}
 		n.log.Logv(2, "removed link %v with %v: %v", active.ID(), l.RemoteIdentity(), err)
 		n.events.Emit(EventLinkRemoved{Link: active})
+
+		if relink {
+			n.relinkAfterShutdown(l.RemoteIdentity())
+		}
 	}()
 
 	n.log.Logv(1, "added link %v with %v", active.ID(), l.RemoteIdentity())
 	n.events.Emit(EventLinkAdded{Link: active})
 
+	if report {
+		go n.reportLinked(l)
+	}
+
+	go n.migrateSessions(l)
+
 	return nil
 }
+
+// reportLinked reports the remote endpoint of an outbound link as successful. The latency is only known if
+// the link measured it already.
+func (n *CoreNetwork) reportLinked(l net.Link) {
+	var t = l.Transport()
+	if t == nil || !t.Outbound() || t.RemoteEndpoint() == nil {
+		return
+	}
+
+	var latency time.Duration
+	if c, ok := l.(latencyChecker); ok && c.Latency() > 0 {
+		latency = c.Latency()
+	}
+
+	n.node.Tracker().ReportSuccess(l.RemoteIdentity(), t.RemoteEndpoint(), latency)
+}
+
+// closeLink closes the link with the reason if the link supports it
+func closeLink(l net.Link, reason link.CloseReason) error {
+	if l, ok := l.(interface {
+		CloseWithReason(link.CloseReason) error
+	}); ok {
+		return l.CloseWithReason(reason)
+	}
+	return l.Close()
+}
+
+// supportsIK returns true if the node agreed to use FeatureIK on a previous link. The tracker keeps this
+// across restarts.
+func (n *CoreNetwork) supportsIK(nodeID id.Identity) bool {
+	return n.node.Tracker().SupportsIK(nodeID)
+}
+
+// forgetIK stops using IK handshakes with the node
+func (n *CoreNetwork) forgetIK(nodeID id.Identity) {
+	if err := n.node.Tracker().SetSupportsIK(nodeID, false); err != nil {
+		n.log.Errorv(1, "error saving IK support of %v: %v", nodeID, err)
+	}
+}
+
+// bestLink selects the better link by learned scores if priorities are learned and both links were sampled,
+// or by network priority otherwise
+func (n *CoreNetwork) bestLink(current net.Link, next net.Link) net.Link {
+	if n.learner == nil || current == nil {
+		return BestQuality(current, next)
+	}
+
+	nextScore, nextLearned := n.learner.score(next)
+	currentScore, currentLearned := n.learner.score(current)
+	if !nextLearned || !currentLearned {
+		return BestQuality(current, next)
+	}
+
+	if nextScore > currentScore {
+		return next
+	}
+
+	return current
+}
+
+// isBetterLink returns true if the link is worth switching to from the current one
+func (n *CoreNetwork) isBetterLink(current net.Link, l net.Link) bool {
+	if n.learner == nil {
+		return BestQuality(current, l) == l
+	}
+
+	score, learned := n.learner.score(l)
+	currentScore, currentLearned := n.learner.score(current)
+	if !learned || !currentLearned {
+		return BestQuality(current, l) == l
+	}
+
+	return score > currentScore+switchMargin
+}
+
+// NetworkScore returns the score of links with the node over the network. It's the learned score if
+// priorities are learned and the pair was sampled, or the network priority otherwise.
+func (n *CoreNetwork) NetworkScore(nodeID id.Identity, network string) int {
+	if n.learner == nil {
+		return getNetworkPriority(network)
+	}
+
+	score, _ := n.learner.networkScore(nodeID, network)
+	return score
+}
+
+// usableLinks returns links that are not in the process of closing
+func usableLinks(links []net.Link) []net.Link {
+	var list = make([]net.Link, 0, len(links))
+	for _, l := range links {
+		if c, ok := l.(interface{ Closing() bool }); ok && c.Closing() {
+			continue
+		}
+		list = append(list, l)
+	}
+	return list
+}
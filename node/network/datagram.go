@@ -0,0 +1,81 @@
+package network
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"time"
+)
+
+var _ net.DatagramHandler = &CoreNetwork{}
+var _ link.RelayChecker = &CoreNetwork{}
+
+// SetDatagramHandler sets the handler of datagrams received over the network's links
+func (n *CoreNetwork) SetDatagramHandler(handler net.DatagramHandler) {
+	n.datagrams.Store(&datagramHandler{handler})
+}
+
+// HandleDatagram passes a datagram received from a link to the datagram handler. Datagrams are dropped if there's
+// no handler.
+func (n *CoreNetwork) HandleDatagram(d net.Datagram) {
+	if h := n.datagrams.Load(); h != nil {
+		h.HandleDatagram(d)
+	}
+}
+
+// SendDatagram sends a datagram over the best link to the target. Datagrams never cause new links to be made.
+// Links are always made by the node, so the caller, which can be an app identity, is carried in the datagram.
+// Errors: net.ErrRouteNotFound, net.ErrDatagramTooLarge, ...
+func (n *CoreNetwork) SendDatagram(d net.Datagram) error {
+	var best net.Link
+	var links = n.links.ByRemoteIdentity(d.Target).ByLocalIdentity(n.node.Identity()).AllRaw()
+
+	for _, l := range usableLinks(links) {
+		if supportsDatagrams(l) {
+			best = n.bestLink(best, l)
+		}
+	}
+
+	if best == nil {
+		return &net.ErrRouteNotFound{Router: n}
+	}
+
+	return best.SendDatagram(d)
+}
+
+// AuthorizeRelay lets the relay send datagrams on behalf of the identity until the authorization expires. It's
+// meant to be called once the relay presented a valid certificate signed by the identity.
+func (n *CoreNetwork) AuthorizeRelay(identity id.Identity, relay id.Identity, expiresAt time.Time) {
+	n.relays.Store(relayKey(identity, relay), expiresAt)
+}
+
+// IsRelayFor returns true if the relay holds an authorization of the identity that hasn't expired yet
+func (n *CoreNetwork) IsRelayFor(identity id.Identity, relay id.Identity) bool {
+	var key = relayKey(identity, relay)
+
+	v, found := n.relays.Load(key)
+	if !found {
+		return false
+	}
+	if time.Now().After(v.(time.Time)) {
+		n.relays.Delete(key)
+		return false
+	}
+
+	return true
+}
+
+func relayKey(identity id.Identity, relay id.Identity) string {
+	return identity.PublicKeyHex() + ":" + relay.PublicKeyHex()
+}
+
+func supportsDatagrams(l net.Link) bool {
+	if l, ok := l.(featureChecker); ok {
+		return l.HasFeature(link.FeatureDatagram)
+	}
+	return false
+}
+
+type datagramHandler struct {
+	net.DatagramHandler
+}
@@ -0,0 +1,63 @@
+package network
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/services"
+	"testing"
+	"time"
+)
+
+func TestDatagramToApp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodeA, _ := id.GenerateIdentity()
+	nodeB, _ := id.GenerateIdentity()
+	appA, _ := id.GenerateIdentity()
+	appB, _ := id.GenerateIdentity()
+	var l = log.NewLogger(log.NewPrinterSplitter())
+
+	// apps of node B register datagram handlers under their own identities
+	var srv = services.NewCoreServices(&events.Queue{}, l)
+	var received = make(chan net.Datagram, 1)
+	err := srv.RegisterDatagram(ctx, appB, "test", net.DatagramHandlerFunc(func(d net.Datagram) {
+		received <- d
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var networkB = &CoreNetwork{ctx: ctx, log: l}
+	networkB.SetDatagramHandler(srv)
+
+	connA, connB := sessionConnPair(nodeA, nodeB)
+	var linkA = link.NewCoreLink(connA, link.FeatureMux, link.FeatureDatagram)
+	var linkB = link.NewCoreLink(connB, link.FeatureMux, link.FeatureDatagram)
+	linkB.SetDatagramHandler(networkB)
+	linkB.SetRelayChecker(networkB)
+
+	go linkA.Run(ctx)
+	go linkB.Run(ctx)
+
+	// node A presented a relay certificate of its app
+	networkB.AuthorizeRelay(appA, nodeA, time.Now().Add(time.Minute))
+
+	err = linkA.SendDatagram(net.Datagram{Caller: appA, Target: appB, Service: "test", Data: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case d := <-received:
+		if !d.Caller.IsEqual(appA) || !d.Target.IsEqual(appB) || string(d.Data) != "hello" {
+			t.Fatalf("unexpected datagram %v:%s %q", d.Caller, d.Service, d.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("datagram not received")
+	}
+}
@@ -0,0 +1,92 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/tracker"
+	"sync"
+	"time"
+)
+
+// dialReporter wraps a dialer and reports dial results of a node's endpoints to the tracker. Failures are
+// reported as soon as a dial or a handshake fails, successes only once the dialed connection becomes a link.
+type dialReporter struct {
+	dialer   infra.Dialer
+	tracker  tracker.Tracker
+	remoteID id.Identity
+
+	mu      sync.Mutex
+	started map[string]time.Time // start times of dials waiting for the handshake
+}
+
+func newDialReporter(dialer infra.Dialer, tracker tracker.Tracker, remoteID id.Identity) *dialReporter {
+	return &dialReporter{
+		dialer:   dialer,
+		tracker:  tracker,
+		remoteID: remoteID,
+		started:  make(map[string]time.Time),
+	}
+}
+
+func (r *dialReporter) Dial(ctx context.Context, e net.Endpoint) (net.Conn, error) {
+	var start = time.Now()
+
+	conn, err := r.dialer.Dial(ctx, e)
+	if err != nil {
+		// dials aborted because another endpoint won don't count as failures
+		if ctx.Err() == nil {
+			r.tracker.ReportFailure(r.remoteID, e)
+		}
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.started[endpointKey(e)] = start
+	r.mu.Unlock()
+
+	return conn, nil
+}
+
+// linked reports the endpoint of the link as successful along with the time it took to dial and handshake
+func (r *dialReporter) linked(l net.Link) {
+	var e = l.Transport().RemoteEndpoint()
+	if e == nil {
+		return
+	}
+
+	r.mu.Lock()
+	start, found := r.started[endpointKey(e)]
+	delete(r.started, endpointKey(e))
+	r.mu.Unlock()
+
+	if found {
+		r.tracker.ReportSuccess(r.remoteID, e, time.Since(start))
+	}
+}
+
+// handshakeFailed reports the endpoint of a connection that failed the handshake. Handshakes aborted by the
+// caller don't count as failures and neither do rejected IK handshakes, since they are retried with XK.
+func (r *dialReporter) handshakeFailed(conn net.Conn, err error) {
+	var e = conn.RemoteEndpoint()
+	if e == nil {
+		return
+	}
+
+	r.mu.Lock()
+	delete(r.started, endpointKey(e))
+	r.mu.Unlock()
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, link.ErrIKFailed) {
+		return
+	}
+
+	r.tracker.ReportFailure(r.remoteID, e)
+}
+
+func endpointKey(e net.Endpoint) string {
+	return e.Network() + ":" + e.String()
+}
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/tracker"
+	"testing"
+	"time"
+)
+
+// reportTracker records the networks of reported endpoints
+type reportTracker struct {
+	tracker.Tracker
+	successes []string
+	failures  []string
+}
+
+func (t *reportTracker) ReportSuccess(identity id.Identity, e net.Endpoint, latency time.Duration) error {
+	t.successes = append(t.successes, e.Network())
+	return nil
+}
+
+func (t *reportTracker) ReportFailure(identity id.Identity, e net.Endpoint) error {
+	t.failures = append(t.failures, e.Network())
+	return nil
+}
+
+func TestDialReporterHandshakeFailure(t *testing.T) {
+	var tr = &reportTracker{}
+	var remoteID, _ = id.GenerateIdentity()
+	var r = newDialReporter(&testDialer{fail: map[string]bool{"udp": true}}, tr, remoteID)
+	var ctx = context.Background()
+
+	for _, network := range []string{"udp", "tcp", "tor", "gw"} {
+		conn, err := r.Dial(ctx, net.NewGenericEndpoint(network, nil))
+		if err != nil {
+			continue
+		}
+
+		switch network {
+		case "tcp":
+			r.handshakeFailed(conn, errors.New("handshake failed"))
+		case "tor":
+			r.handshakeFailed(conn, context.Canceled)
+		case "gw":
+			r.handshakeFailed(conn, link.ErrIKFailed)
+		}
+	}
+
+	if len(tr.successes) != 0 {
+		t.Fatalf("reported successes %v before any link", tr.successes)
+	}
+	if len(tr.failures) != 2 || tr.failures[0] != "udp" || tr.failures[1] != "tcp" {
+		t.Fatalf("reported failures %v, expected [udp tcp]", tr.failures)
+	}
+}
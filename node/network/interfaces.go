@@ -0,0 +1,156 @@
+package network
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra/drivers/ip"
+	_net "net"
+	"time"
+)
+
+// relinkTTL is how long we keep trying to relink with a node after its link was lost to an address change
+const relinkTTL = 15 * time.Minute
+
+const (
+	shutdownRelinkDelay    = 2 * time.Second  // delay before the first relink with a node that shut down
+	shutdownRelinkMaxDelay = 1 * time.Minute  // maximum delay between relinks with a node that shut down
+	shutdownRelinkTTL      = 10 * time.Minute // how long we keep trying to relink with a node that shut down
+)
+
+// relinkEntry is a node waiting to be relinked
+type relinkEntry struct {
+	identity id.Identity
+	lost     time.Time
+}
+
+// watchInterfaces relinks peers whose links were bound to addresses that disappeared
+func (n *CoreNetwork) watchInterfaces(ctx context.Context) {
+	for event := range n.node.Events().Subscribe(ctx) {
+		switch e := event.(type) {
+		case ip.EventAddrRemoved:
+			n.log.Logv(1, "address %s removed from %s", e.Addr, e.Interface)
+			n.dropLinksFrom(e.Addr.IP)
+
+		case ip.EventAddrAdded:
+			n.log.Logv(1, "address %s added to %s", e.Addr, e.Interface)
+			n.retryRelinks()
+		}
+	}
+}
+
+// dropLinksFrom closes links whose local endpoint uses the address and relinks their peers
+func (n *CoreNetwork) dropLinksFrom(addr _net.IP) {
+	for _, l := range n.links.All() {
+		t := l.Transport()
+		if t == nil || !addr.Equal(endpointIP(t.LocalEndpoint())) {
+			continue
+		}
+
+		n.log.Info("link %v with %v lost its local address", l.ID(), l.RemoteIdentity())
+
+		// the address is gone, so there's no point in trying to close the link gracefully
+		t.Close()
+
+		go func(l net.Link) {
+			<-l.Done()
+			n.relink(l.RemoteIdentity())
+		}(l.Link)
+	}
+}
+
+// relink links with the node again unless it's still linked in some other way. If that fails, the node
+// is retried when a new address shows up.
+func (n *CoreNetwork) relink(nodeID id.Identity) {
+	if n.isLinked(nodeID) {
+		return
+	}
+
+	n.relinks.LoadOrStore(nodeID.PublicKeyHex(), relinkEntry{identity: nodeID, lost: time.Now()})
+
+	if n.linkAgain(nodeID) {
+		n.relinks.Delete(nodeID.PublicKeyHex())
+	}
+}
+
+// relinkAfterShutdown links with a node that announced a shutdown once it's back. The node needs time to
+// restart, so linking is retried with an exponential backoff until it succeeds or shutdownRelinkTTL passes.
+func (n *CoreNetwork) relinkAfterShutdown(nodeID id.Identity) {
+	var delay = shutdownRelinkDelay
+	var deadline = time.After(shutdownRelinkTTL)
+
+	for {
+		select {
+		case <-time.After(delay):
+		case <-deadline:
+			n.log.Errorv(1, "gave up relinking with %v", nodeID)
+			return
+		case <-n.ctx.Done():
+			return
+		}
+
+		if n.linkAgain(nodeID) {
+			return
+		}
+
+		delay = min(2*delay, shutdownRelinkMaxDelay)
+	}
+}
+
+// linkAgain links with the node unless it's still linked in some other way. Returns true if the node is linked.
+func (n *CoreNetwork) linkAgain(nodeID id.Identity) bool {
+	if n.isLinked(nodeID) {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, defaultQueryTimeout)
+	defer cancel()
+
+	if _, err := n.Link(ctx, nodeID); err != nil {
+		n.log.Errorv(1, "relink with %v failed: %v", nodeID, err)
+		return false
+	}
+
+	n.log.Info("relinked with %v", nodeID)
+	return true
+}
+
+// retryRelinks retries relinking with all nodes whose links were lost to address changes. Nodes that
+// couldn't be relinked for longer than relinkTTL are forgotten.
+func (n *CoreNetwork) retryRelinks() {
+	n.relinks.Range(func(k, v any) bool {
+		var e = v.(relinkEntry)
+		if time.Since(e.lost) > relinkTTL {
+			n.relinks.Delete(k)
+			return true
+		}
+		go n.relink(e.identity)
+		return true
+	})
+}
+
+// isLinked returns true if the node has a link that is not closed or closing
+func (n *CoreNetwork) isLinked(nodeID id.Identity) bool {
+	for _, l := range usableLinks(n.links.ByRemoteIdentity(nodeID).AllRaw()) {
+		select {
+		case <-l.Done():
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// endpointIP returns the IP address of host:port endpoints and nil for all others
+func endpointIP(e net.Endpoint) _net.IP {
+	if e == nil {
+		return nil
+	}
+
+	host, _, err := _net.SplitHostPort(e.String())
+	if err != nil {
+		return nil
+	}
+
+	return _net.ParseIP(host)
+}
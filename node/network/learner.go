@@ -0,0 +1,153 @@
+package network
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"sync"
+	"time"
+)
+
+const (
+	learnInterval = 10 * time.Second
+
+	// learnWeight is the weight of a new sample in the moving average
+	learnWeight = 0.25
+
+	// one point of priority is taken away for every latencyUnit of latency and queueUnit of backlog
+	latencyUnit = time.Millisecond
+	queueUnit   = 4096
+	maxPenalty  = 300
+
+	// one point of priority is given back for every throughputUnit of bytes per second carried by the link
+	throughputUnit = 64 * 1024
+	maxBonus       = 50
+
+	// switchMargin is the score by which a link has to beat the current one to replace it, so that links
+	// with similar performance don't take turns
+	switchMargin = 25
+)
+
+type latencyChecker interface {
+	Latency() time.Duration
+}
+
+// learner keeps a moving average of the penalty of every network a peer is linked over. The penalty grows
+// with latency and with data queued for sending, which both go up when a path is congested, and shrinks
+// with the throughput the link has shown.
+type learner struct {
+	mu        sync.Mutex
+	penalties map[learnKey]float64
+	traffic   map[net.Link]trafficSample
+}
+
+type learnKey struct {
+	peer    string
+	network string
+}
+
+// trafficSample is the byte count of a link at the time of the previous sample
+type trafficSample struct {
+	bytes uint64
+	at    time.Time
+}
+
+func newLearner() *learner {
+	return &learner{
+		penalties: make(map[learnKey]float64),
+		traffic:   make(map[net.Link]trafficSample),
+	}
+}
+
+// run samples the links periodically until the context is done
+func (lrn *learner) run(ctx context.Context, links *LinkSet) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(learnInterval):
+		}
+
+		var all = links.All()
+		for _, l := range all {
+			lrn.sample(l.Link)
+		}
+		lrn.forgetTraffic(all)
+	}
+}
+
+// forgetTraffic drops traffic samples of links that are gone
+func (lrn *learner) forgetTraffic(active []*ActiveLink) {
+	var keep = make(map[net.Link]struct{}, len(active))
+	for _, l := range active {
+		keep[l.Link] = struct{}{}
+	}
+
+	lrn.mu.Lock()
+	defer lrn.mu.Unlock()
+
+	for l := range lrn.traffic {
+		if _, found := keep[l]; !found {
+			delete(lrn.traffic, l)
+		}
+	}
+}
+
+// sample adds the current measurements of the link to the average of its peer and network
+func (lrn *learner) sample(l net.Link) {
+	c, ok := l.(latencyChecker)
+	if !ok {
+		return
+	}
+
+	var latency = c.Latency()
+	if latency <= 0 {
+		return
+	}
+
+	var stats = l.Stats()
+	var penalty = float64(latency/latencyUnit) + float64(stats.Queued/queueUnit)
+	if penalty > maxPenalty {
+		penalty = maxPenalty
+	}
+
+	var key = learnKey{peer: l.RemoteIdentity().PublicKeyHex(), network: net.Network(l)}
+	var now = time.Now()
+	var bytes = stats.BytesSent + stats.BytesReceived
+
+	lrn.mu.Lock()
+	defer lrn.mu.Unlock()
+
+	if last, found := lrn.traffic[l]; found && bytes >= last.bytes && now.After(last.at) {
+		var throughput = float64(bytes-last.bytes) / now.Sub(last.at).Seconds()
+		penalty -= min(throughput/throughputUnit, maxBonus)
+	}
+	lrn.traffic[l] = trafficSample{bytes: bytes, at: now}
+
+	if avg, found := lrn.penalties[key]; found {
+		lrn.penalties[key] = avg + learnWeight*(penalty-avg)
+	} else {
+		lrn.penalties[key] = penalty
+	}
+}
+
+// score returns the network priority of the link reduced by the penalty learned for its peer and network.
+// The second value is false if the pair wasn't sampled yet, in which case the score is the network priority.
+func (lrn *learner) score(l net.Link) (int, bool) {
+	return lrn.networkScore(l.RemoteIdentity(), net.Network(l))
+}
+
+// networkScore returns the learned score of the network for the peer
+func (lrn *learner) networkScore(peer id.Identity, network string) (int, bool) {
+	var key = learnKey{peer: peer.PublicKeyHex(), network: network}
+
+	lrn.mu.Lock()
+	penalty, found := lrn.penalties[key]
+	lrn.mu.Unlock()
+
+	if !found {
+		return getNetworkPriority(network), false
+	}
+
+	return getNetworkPriority(network) - int(penalty), true
+}
@@ -0,0 +1,109 @@
+package network
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"testing"
+	"time"
+)
+
+type testTransport struct {
+	net.SecureConn
+	endpoint net.Endpoint
+}
+
+func (t *testTransport) RemoteEndpoint() net.Endpoint { return t.endpoint }
+
+// testLink is a link over the network with fixed measurements
+type testLink struct {
+	net.Link
+	remoteID  id.Identity
+	transport *testTransport
+	latency   time.Duration
+	queued    int
+	bytes     uint64
+}
+
+func newTestLink(remoteID id.Identity, network string, latency time.Duration) *testLink {
+	return &testLink{
+		remoteID:  remoteID,
+		transport: &testTransport{endpoint: net.NewGenericEndpoint(network, nil)},
+		latency:   latency,
+	}
+}
+
+func (l *testLink) RemoteIdentity() id.Identity { return l.remoteID }
+func (l *testLink) Transport() net.SecureConn   { return l.transport }
+func (l *testLink) Latency() time.Duration      { return l.latency }
+func (l *testLink) Stats() net.LinkStats        { return net.LinkStats{Queued: l.queued, BytesSent: l.bytes} }
+
+func TestLearnedPriorities(t *testing.T) {
+	peer, _ := id.GenerateIdentity()
+
+	var inet = newTestLink(peer, "inet", 5*time.Millisecond)
+	var gw = newTestLink(peer, "gw", 40*time.Millisecond)
+
+	var n = &CoreNetwork{learner: newLearner()}
+
+	// unmeasured links fall back to network priorities
+	if n.bestLink(gw, inet) != inet {
+		t.Fatal("expected inet to win without measurements")
+	}
+
+	n.learner.sample(inet)
+	n.learner.sample(gw)
+	if n.bestLink(gw, inet) != inet {
+		t.Fatal("expected fast inet to win")
+	}
+
+	// the inet path gets congested
+	inet.latency = 500 * time.Millisecond
+	inet.queued = 64 * queueUnit
+	for i := 0; i < 20; i++ {
+		n.learner.sample(inet)
+	}
+
+	if n.bestLink(inet, gw) != gw {
+		t.Fatal("expected gw to win over congested inet")
+	}
+	if !n.isBetterLink(inet, gw) {
+		t.Fatal("expected sessions to move to gw")
+	}
+
+	// similar scores don't cause a switch
+	SetNetworkPriority("test", getNetworkPriority("gw")+switchMargin/2)
+	defer SetNetworkPriority("test", 0)
+	var other = newTestLink(peer, "test", 40*time.Millisecond)
+	n.learner.sample(other)
+	if n.isBetterLink(gw, other) {
+		t.Fatal("switched to a link with a similar score")
+	}
+
+	// links that weren't sampled yet are compared by network priority, even against congested ones
+	var fresh = newTestLink(peer, "pipe", time.Millisecond)
+	if n.bestLink(inet, fresh) != inet {
+		t.Fatal("expected an unsampled link to be compared by network priority")
+	}
+}
+
+func TestLearnedThroughput(t *testing.T) {
+	peer, _ := id.GenerateIdentity()
+
+	var busy = newTestLink(peer, "inet", 20*time.Millisecond)
+	var lrn = newLearner()
+
+	lrn.sample(busy)
+	idle, _ := lrn.score(busy)
+
+	// carry about 1MB/s over the link
+	lrn.traffic[busy] = trafficSample{at: time.Now().Add(-time.Second)}
+	busy.bytes = 1 << 20
+	for i := 0; i < 20; i++ {
+		lrn.sample(busy)
+		lrn.traffic[busy] = trafficSample{at: time.Now().Add(-time.Second)}
+	}
+
+	if score, _ := lrn.score(busy); score <= idle {
+		t.Fatalf("throughput didn't raise the score (%d <= %d)", score, idle)
+	}
+}
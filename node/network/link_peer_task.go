@@ -6,6 +6,8 @@ import (
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"sync/atomic"
 )
 
 var ErrNodeUnreachable = errors.New("node unreachable")
@@ -41,8 +43,8 @@ func (task *LinkPeerTask) Run(ctx context.Context) (net.Link, error) {
 	// Get a list of supported networks
 	var networks = task.Network.node.Infra().Drivers()
 
-	// Populate a channel with addresses
-	var ch = make(chan net.Endpoint, len(endpoints))
+	// Filter out addresses we can't use
+	var usable = make([]net.Endpoint, 0, len(endpoints))
 	for _, e := range endpoints {
 		if _, found := networks[e.Network()]; !found {
 			continue
@@ -53,42 +55,96 @@ func (task *LinkPeerTask) Run(ctx context.Context) (net.Link, error) {
 				continue
 			}
 		}
+		usable = append(usable, e)
+	}
+
+	// use the faster IK handshake with nodes known to support it and fall back to XK if it fails
+	var ik = task.Network.supportsIK(task.RemoteID)
+
+	l, err := task.link(ctx, usable, ik)
+	if err != nil && ik && ctx.Err() == nil {
+		// transport errors and timeouts don't tell whether the node still supports IK, a rejected handshake does
+		if errors.Is(err, link.ErrIKFailed) {
+			task.Network.forgetIK(task.RemoteID)
+		}
+		l, err = task.link(ctx, usable, false)
+	}
+	if err != nil {
+		return nil, ErrNodeUnreachable
+	}
+
+	// the dial reporter already reported the link along with its dial time
+	if err := task.Network.addLink(l, false); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// link dials the endpoints and returns the first link that completes the handshake. If no link was established
+// and any of the endpoints rejected the IK handshake, the error is link.ErrIKFailed.
+func (task *LinkPeerTask) link(ctx context.Context, endpoints []net.Endpoint, ik bool) (net.Link, error) {
+	// Populate a channel with addresses
+	var ch = make(chan net.Endpoint, len(endpoints))
+	for _, e := range endpoints {
 		ch <- e
 	}
 	close(ch)
 
+	// stop dialing other endpoints as soon as one of them completes the handshake
+	dialCtx, cancelDial := context.WithCancel(ctx)
+	defer cancelDial()
+
+	var reporter = newDialReporter(
+		task.Network.node.Infra(),
+		task.Network.node.Tracker(),
+		task.RemoteID,
+	)
+
+	var ikFailed atomic.Bool
+
 	links := NewConcurrentHandshake(
 		task.Network.node.Identity(),
 		task.RemoteID,
 		workers,
-	).Outbound(
+	).SetIK(ik).SetErrorHandler(func(conn net.Conn, err error) {
+		reporter.handshakeFailed(conn, err)
+		if errors.Is(err, link.ErrIKFailed) {
+			ikFailed.Store(true)
+		}
+	}).Outbound(
 		ctx,
 		NewConcurrentDialer(
-			task.Network.node.Infra(),
+			reporter,
 			workers,
-		).Dial(
-			ctx,
+		).SetScoreFunc(func(network string) int {
+			return task.Network.NetworkScore(task.RemoteID, network)
+		}).Dial(
+			dialCtx,
 			ch,
 		),
 	)
 
-	defer func() {
-		go func() {
-			for a := range links {
-				a.Close()
-			}
-		}()
-	}()
-
 	l, ok := <-links
-	if !ok {
-		return nil, ErrNodeUnreachable
-	}
+	cancelDial()
 
-	if err := task.Network.AddLink(l); err != nil {
-		l.Close()
-		return nil, err
+	if ok {
+		reporter.linked(l)
 	}
 
-	return l, nil
+	go func() {
+		for a := range links {
+			a.Close()
+		}
+	}()
+
+	switch {
+	case ok:
+		return l, nil
+	case ikFailed.Load():
+		return nil, link.ErrIKFailed
+	default:
+		return nil, ErrNodeUnreachable
+	}
 }
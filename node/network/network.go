@@ -5,6 +5,7 @@ import (
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/events"
+	"time"
 )
 
 type Network interface {
@@ -14,4 +15,8 @@ type Network interface {
 	Server() *Server
 	AddLink(net.Link) error
 	Links() *LinkSet
+	Sessions() *SessionSet
+	SendDatagram(net.Datagram) error
+	AuthorizeRelay(identity id.Identity, relay id.Identity, expiresAt time.Time)
+	NetworkScore(id.Identity, string) int
 }
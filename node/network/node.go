@@ -3,6 +3,7 @@ package network
 import (
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/events"
 	"github.com/cryptopunkscc/astrald/node/infra"
 	"github.com/cryptopunkscc/astrald/node/tracker"
 )
@@ -12,4 +13,5 @@ type Node interface {
 	Router() net.Router
 	Infra() infra.Infra
 	Tracker() tracker.Tracker
+	Events() *events.Queue
 }
@@ -17,7 +17,7 @@ func NewPeerRouter(network *CoreNetwork, target id.Identity) *PeerRouter {
 
 func (router *PeerRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
 	var links = router.links.ByRemoteIdentity(router.Target).ByLocalIdentity(query.Caller())
-	var best = net.SelectLink(links.AllRaw(), BestQuality)
+	var best = net.SelectLink(usableLinks(links.AllRaw()), router.bestLink)
 
 	if best == nil {
 		best, _ = router.Link(ctx, query.Target())
@@ -27,5 +27,9 @@ func (router *PeerRouter) RouteQuery(ctx context.Context, query net.Query, calle
 		return nil, &net.ErrRouteNotFound{Router: router}
 	}
 
+	if supportsSessions(best) {
+		return router.openSession(ctx, best, query, caller, hints)
+	}
+
 	return best.RouteQuery(ctx, query, caller, hints)
 }
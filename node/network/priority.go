@@ -2,11 +2,13 @@ package network
 
 import (
 	"github.com/cryptopunkscc/astrald/net"
+	"sync"
 )
 
 var networkPriorities map[string]int
+var prioritiesMu sync.RWMutex
 
-// BestQuality selects the best available link.
+// BestQuality selects the best available link by network priority.
 func BestQuality(current net.Link, next net.Link) net.Link {
 	if current == nil {
 		return next
@@ -22,16 +24,36 @@ func BestQuality(current net.Link, next net.Link) net.Link {
 	return current
 }
 
+// NetworkPriority returns network's priority
+func NetworkPriority(netName string) int {
+	return getNetworkPriority(netName)
+}
+
+// SetNetworkPriority sets network's priority
+func SetNetworkPriority(netName string, priority int) {
+	prioritiesMu.Lock()
+	defer prioritiesMu.Unlock()
+
+	networkPriorities[netName] = priority
+}
+
 // getNetworkPriority returns network's priority
 func getNetworkPriority(netName string) int {
+	prioritiesMu.RLock()
+	defer prioritiesMu.RUnlock()
+
 	return networkPriorities[netName]
 }
 
 func init() {
 	networkPriorities = map[string]int{
+		"unix": 500,
 		"inet": 400,
+		"udp":  350,
+		"ws":   325,
 		"bt":   300,
 		"gw":   200,
+		"pipe": 150,
 		"tor":  100,
 	}
 }
@@ -0,0 +1,673 @@
+package network
+
+import (
+	"encoding/hex"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ net.SecureWriteCloser = &Session{}
+
+// Session is a resumable stream between two nodes. Data written to a session is kept until the remote party
+// acknowledges it, so that the session can move to another link to the same node without losing any data.
+// A multipath session stripes its frames across all paths attached to it.
+type Session struct {
+	*net.SourceField
+	id        []byte
+	localID   id.Identity // identity of the local party
+	remoteID  id.Identity // identity of the remote party
+	query     string
+	outbound  bool
+	multipath bool
+	output    net.SecureWriteCloser
+
+	wmu    sync.Mutex // serializes writes of data frames
+	rmu    sync.Mutex // serializes delivery of data to the output
+	mu     sync.Mutex
+	cond   *sync.Cond
+	paths  []*sessionPath
+	next   int // index of the next path used for sending
+	expiry *time.Timer
+
+	sent     uint64 // number of bytes written to the session
+	acked    uint64 // number of bytes acknowledged by the remote party
+	received uint64 // number of bytes delivered to the output
+	unacked  []byte
+	pending  map[uint64][]byte // out of order data waiting for delivery indexed by offset
+	length   uint64            // total length of the remote stream, valid if finRecv is set
+	finRecv  bool
+
+	localFin  bool
+	remoteFin bool
+	err       error
+
+	ackSig    chan struct{}
+	done      chan struct{}
+	resuming  atomic.Bool
+	onDetach  func(*Session)
+	onDone    func(*Session)
+	createdAt time.Time
+}
+
+// sessionPath is a single carrier of session frames
+type sessionPath struct {
+	conn net.SecureConn
+	link net.Link
+	done chan struct{} // closed when the reader exits
+}
+
+func newSession(sid []byte, localID id.Identity, remoteID id.Identity, query string, outbound bool) *Session {
+	s := &Session{
+		SourceField: net.NewSourceField(nil),
+		id:          sid,
+		localID:     localID,
+		remoteID:    remoteID,
+		query:       query,
+		outbound:    outbound,
+		pending:     make(map[uint64][]byte),
+		ackSig:      make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		createdAt:   time.Now(),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	go s.runAcks()
+
+	return s
+}
+
+// ID returns the session ID as a hex string
+func (s *Session) ID() string {
+	return hex.EncodeToString(s.id)
+}
+
+// Identity returns the identity of the remote party
+func (s *Session) Identity() id.Identity {
+	return s.remoteID
+}
+
+// LocalIdentity returns the identity of the local party
+func (s *Session) LocalIdentity() id.Identity {
+	return s.localID
+}
+
+// Query returns the query that opened the session
+func (s *Session) Query() string {
+	return s.query
+}
+
+// Outbound returns true if the session was opened by the local party
+func (s *Session) Outbound() bool {
+	return s.outbound
+}
+
+// Multipath returns true if the session stripes its frames across all available links
+func (s *Session) Multipath() bool {
+	return s.multipath
+}
+
+// Link returns the first link carrying the session or nil if the session is suspended
+func (s *Session) Link() net.Link {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.paths) == 0 {
+		return nil
+	}
+	return s.paths[0].link
+}
+
+// Links returns all links currently carrying the session
+func (s *Session) Links() []net.Link {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list = make([]net.Link, 0, len(s.paths))
+	for _, p := range s.paths {
+		list = append(list, p.link)
+	}
+	return list
+}
+
+// Unacked returns the number of bytes waiting for the remote party's acknowledgement
+func (s *Session) Unacked() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.unacked)
+}
+
+// CreatedAt returns the time the session was created
+func (s *Session) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// Output returns the writer receiving the session's data
+func (s *Session) Output() net.SecureWriteCloser {
+	return s.output
+}
+
+// Done returns a channel that will be closed when the session ends
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that ended the session
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Write writes data to the session. If the session is suspended, data is buffered until it resumes.
+func (s *Session) Write(p []byte) (n int, err error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	for len(p) > 0 {
+		var chunk = p[:min(len(p), sessionMaxFrameSize)]
+
+		s.mu.Lock()
+		for s.err == nil && !s.localFin && len(s.unacked)+len(chunk) > sessionMaxUnacked {
+			s.cond.Wait()
+		}
+		if s.err != nil {
+			err = s.err
+			s.mu.Unlock()
+			return
+		}
+		if s.localFin {
+			s.mu.Unlock()
+			return n, ErrSessionClosed
+		}
+
+		var offset = s.sent
+		var path = s.pickPath()
+		s.unacked = append(s.unacked, chunk...)
+		s.sent += uint64(len(chunk))
+		s.mu.Unlock()
+
+		if path != nil {
+			err := writeSessionFrame(path.conn, sessionFrame{
+				Type:   sessionFrameData,
+				Offset: offset,
+				Data:   chunk,
+			})
+			if err != nil {
+				s.detach(path.conn)
+			}
+		}
+
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+
+	return
+}
+
+// Close ends the local side of the session. The session is done once both parties closed it and all data
+// was acknowledged.
+func (s *Session) Close() error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	s.mu.Lock()
+	if s.localFin || s.err != nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.localFin = true
+	var paths = append([]*sessionPath{}, s.paths...)
+	var sent = s.sent
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	// send the fin over every path, so that it's not stuck behind a slow one
+	for _, path := range paths {
+		if err := writeSessionFrame(path.conn, sessionFrame{Type: sessionFrameFin, Offset: sent}); err != nil {
+			s.detach(path.conn)
+		}
+	}
+
+	s.checkDone()
+
+	return nil
+}
+
+// attach adds conn to the carriers of the session. remoteReceived is the number of bytes the remote party has
+// received so far. If the session had no carriers, data that did not reach the remote party is retransmitted.
+func (s *Session) attach(conn net.SecureConn, l net.Link, remoteReceived uint64) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		conn.Close()
+		return s.err
+	}
+	if err := s.ack(remoteReceived); err != nil {
+		s.mu.Unlock()
+		conn.Close()
+		return err
+	}
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+
+	var resend = len(s.paths) == 0
+	var path = &sessionPath{
+		conn: conn,
+		link: l,
+		done: make(chan struct{}),
+	}
+	s.paths = append(s.paths, path)
+	go s.read(path)
+	s.mu.Unlock()
+
+	if resend {
+		s.retransmit(path)
+	}
+
+	return nil
+}
+
+// retransmit sends all unacknowledged data and the fin over the path
+func (s *Session) retransmit(path *sessionPath) {
+	s.mu.Lock()
+	var pending = append([]byte{}, s.unacked...)
+	var offset = s.acked
+	var fin = s.localFin
+	var sent = s.sent
+	s.mu.Unlock()
+
+	for len(pending) > 0 {
+		var chunk = pending[:min(len(pending), sessionMaxFrameSize)]
+
+		err := writeSessionFrame(path.conn, sessionFrame{
+			Type:   sessionFrameData,
+			Offset: offset,
+			Data:   chunk,
+		})
+		if err != nil {
+			s.detach(path.conn)
+			return
+		}
+
+		offset += uint64(len(chunk))
+		pending = pending[len(chunk):]
+	}
+
+	if fin {
+		if err := writeSessionFrame(path.conn, sessionFrame{Type: sessionFrameFin, Offset: sent}); err != nil {
+			s.detach(path.conn)
+		}
+	}
+}
+
+// suspend detaches the session from all its carriers and returns the number of bytes received so far
+func (s *Session) suspend() uint64 {
+	s.mu.Lock()
+	var paths = append([]*sessionPath{}, s.paths...)
+	s.mu.Unlock()
+
+	for _, path := range paths {
+		s.detach(path.conn)
+		<-path.done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.received
+}
+
+// detach removes conn from the carriers of the session
+func (s *Session) detach(conn net.SecureConn) {
+	s.mu.Lock()
+	var idx = -1
+	for i, p := range s.paths {
+		if p.conn == conn {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		s.mu.Unlock()
+		return
+	}
+	s.paths = append(s.paths[:idx], s.paths[idx+1:]...)
+
+	var ended = s.err != nil || s.isComplete()
+	var suspended = len(s.paths) == 0
+	var next *sessionPath
+	if !suspended {
+		next = s.pickPath()
+	}
+	if !ended && suspended && !s.outbound {
+		s.expiry = time.AfterFunc(sessionResumeTimeout, s.expire)
+	}
+	s.mu.Unlock()
+
+	conn.Close()
+
+	if ended {
+		return
+	}
+
+	// frames sent over the lost path might never arrive, send them again over the remaining ones
+	if next != nil {
+		go s.retransmit(next)
+		return
+	}
+
+	if s.onDetach != nil {
+		s.onDetach(s)
+	}
+}
+
+// pickPath returns the next path in round robin order or nil if the session has no carriers. Caller must hold
+// the mutex.
+func (s *Session) pickPath() *sessionPath {
+	if len(s.paths) == 0 {
+		return nil
+	}
+	if !s.multipath {
+		return s.paths[0]
+	}
+
+	s.next = (s.next + 1) % len(s.paths)
+	return s.paths[s.next]
+}
+
+// expire ends a session that was not resumed in time
+func (s *Session) expire() {
+	s.mu.Lock()
+	var complete = s.localFin && s.remoteFin
+	var attached = len(s.paths) > 0
+	s.mu.Unlock()
+
+	switch {
+	case attached:
+	case complete:
+		s.finish(nil)
+	default:
+		s.finish(ErrSessionExpired)
+	}
+}
+
+func (s *Session) read(path *sessionPath) {
+	defer close(path.done)
+	defer s.detach(path.conn)
+
+	for {
+		var frame sessionFrame
+		if err := cslq.Decode(path.conn, "v", &frame); err != nil {
+			return
+		}
+
+		var err error
+		switch frame.Type {
+		case sessionFrameData:
+			err = s.deliver(frame.Offset, frame.Data)
+		case sessionFrameAck:
+			err = s.handleAck(frame.Offset)
+		case sessionFrameFin:
+			err = s.handleFin(frame.Offset)
+		default:
+			err = ErrSessionProtocol
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// deliver writes data received at the offset to the output in stream order. Data that arrived ahead of
+// the stream is held until the gap is filled, bytes that were already delivered are skipped.
+func (s *Session) deliver(offset uint64, data []byte) error {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	s.mu.Lock()
+	var received = s.received
+	s.mu.Unlock()
+
+	var end = offset + uint64(len(data))
+	switch {
+	case end <= received:
+		return nil
+
+	case offset > received:
+		// the sender never has more than sessionMaxUnacked bytes in flight
+		if end-received > sessionMaxUnacked {
+			return ErrSessionProtocol
+		}
+		s.mu.Lock()
+		if p, found := s.pending[offset]; !found || len(p) < len(data) {
+			s.pending[offset] = append([]byte{}, data...)
+		}
+		s.mu.Unlock()
+		return nil
+	}
+
+	for data != nil {
+		if err := s.writeOutput(data[received-offset:]); err != nil {
+			return err
+		}
+
+		offset, data, received = s.nextPending()
+	}
+
+	s.sendAck()
+
+	return s.checkFin()
+}
+
+// nextPending removes and returns the held data that continues the stream or nil if there's none
+func (s *Session) nextPending() (uint64, []byte, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		var found = false
+		for offset, data := range s.pending {
+			if offset > s.received {
+				continue
+			}
+			delete(s.pending, offset)
+			if offset+uint64(len(data)) > s.received {
+				return offset, data, s.received
+			}
+			found = true
+		}
+		if !found {
+			return 0, nil, s.received
+		}
+	}
+}
+
+func (s *Session) writeOutput(data []byte) error {
+	if _, err := s.output.Write(data); err != nil {
+		s.finish(err)
+		return err
+	}
+
+	s.mu.Lock()
+	s.received += uint64(len(data))
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *Session) handleAck(received uint64) error {
+	s.mu.Lock()
+	var err error
+	// with multiple paths acks can arrive out of order
+	if received > s.acked {
+		err = s.ack(received)
+	}
+	s.mu.Unlock()
+
+	if err == nil {
+		s.checkDone()
+	}
+	return err
+}
+
+// ack drops acknowledged data from the buffer. Caller must hold the mutex.
+func (s *Session) ack(received uint64) error {
+	if received < s.acked || received > s.sent {
+		return ErrSessionProtocol
+	}
+
+	s.unacked = s.unacked[received-s.acked:]
+	s.acked = received
+	s.cond.Broadcast()
+
+	return nil
+}
+
+func (s *Session) handleFin(length uint64) error {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	s.mu.Lock()
+	if length < s.received || (s.finRecv && length != s.length) {
+		s.mu.Unlock()
+		return ErrSessionProtocol
+	}
+	s.length = length
+	s.finRecv = true
+	s.mu.Unlock()
+
+	return s.checkFin()
+}
+
+// checkFin ends the remote stream once all of its data was delivered. Caller must hold the delivery mutex.
+func (s *Session) checkFin() error {
+	s.mu.Lock()
+	if !s.finRecv || s.remoteFin || s.received != s.length {
+		s.mu.Unlock()
+		return nil
+	}
+	s.remoteFin = true
+	s.mu.Unlock()
+
+	s.output.Close()
+	s.sendAck()
+	s.checkDone()
+
+	return nil
+}
+
+// sendAck schedules an acknowledgement of received data
+func (s *Session) sendAck() {
+	select {
+	case s.ackSig <- struct{}{}:
+	default:
+	}
+}
+
+// runAcks writes acknowledgements in the background, so that the reader never waits for the writer. It also
+// finishes the session once it's complete.
+func (s *Session) runAcks() {
+	for {
+		select {
+		case <-s.ackSig:
+		case <-s.done:
+			return
+		}
+
+		s.writeAck()
+
+		s.mu.Lock()
+		var complete = s.isComplete()
+		s.mu.Unlock()
+
+		if complete {
+			s.finish(nil)
+		}
+	}
+}
+
+func (s *Session) writeAck() {
+	s.mu.Lock()
+	var path = s.pickPath()
+	var received = s.received
+	s.mu.Unlock()
+
+	if path == nil {
+		return
+	}
+
+	if err := writeSessionFrame(path.conn, sessionFrame{Type: sessionFrameAck, Offset: received}); err != nil {
+		s.detach(path.conn)
+	}
+}
+
+// isComplete returns true if both parties closed the session and all data was acknowledged. Caller must hold
+// the mutex.
+func (s *Session) isComplete() bool {
+	return s.localFin && s.remoteFin && s.acked == s.sent
+}
+
+// checkDone schedules the end of the session if it's complete
+func (s *Session) checkDone() {
+	s.mu.Lock()
+	var complete = s.isComplete()
+	s.mu.Unlock()
+
+	if complete {
+		// make sure the remote party knows we have everything before we let go
+		s.sendAck()
+	}
+}
+
+// finish ends the session. If err is not nil, the session is aborted.
+func (s *Session) finish(err error) {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		return
+	default:
+	}
+
+	if err != nil {
+		s.err = err
+	} else {
+		s.err = ErrSessionClosed
+	}
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+	var paths = s.paths
+	var remoteFin = s.remoteFin
+	s.paths = nil
+	s.pending = make(map[uint64][]byte)
+	close(s.done)
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	// outbound party closes the carriers of a completed session, the inbound party waits for it
+	if err != nil || s.outbound {
+		for _, p := range paths {
+			p.conn.Close()
+		}
+	}
+
+	if !remoteFin && s.output != nil {
+		s.output.Close()
+	}
+
+	if s.onDone != nil {
+		s.onDone(s)
+	}
+}
@@ -0,0 +1,115 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	"time"
+)
+
+// SessionQuery is the query used to carry session traffic between nodes
+const SessionQuery = ".session"
+
+const sessionIDSize = 16
+const sessionMaxFrameSize = 8 * 1024
+const sessionMaxUnacked = 4 * 1024 * 1024
+const sessionResumeTimeout = time.Minute
+const sessionRetryInterval = 2 * time.Second
+
+const (
+	sessionOpOpen = iota
+	sessionOpResume
+	sessionOpJoin
+)
+
+const (
+	sessionFrameData = iota
+	sessionFrameAck
+	sessionFrameFin
+)
+
+const (
+	sessionOK = iota
+	sessionRejected
+	sessionRouteNotFound
+	sessionNotFound
+	sessionUnexpected
+)
+
+// sessionRequest is sent by the caller right after the session query is accepted
+type sessionRequest struct {
+	Op       int      `cslq:"c"`
+	ID       []byte   `cslq:"[c]c"`
+	Query    string   `cslq:"[s]c"`
+	Args     []string `cslq:"[s][s]c"` // flat list of query argument keys and values
+	Received uint64   `cslq:"q"`
+}
+
+// sessionResponse is sent by the target in response to a sessionRequest
+type sessionResponse struct {
+	Code     int    `cslq:"c"`
+	Received uint64 `cslq:"q"`
+}
+
+// sessionFrame carries data, acknowledgements and end of stream markers. Offset holds the position of the data
+// in the stream for data frames, the number of received bytes for acks and the total length of the stream for fins.
+type sessionFrame struct {
+	Type   int    `cslq:"c"`
+	Offset uint64 `cslq:"q"`
+	Data   []byte `cslq:"[s]c"`
+}
+
+// writeSessionFrame writes the frame with a single write, so that concurrent frames don't interleave
+func writeSessionFrame(conn net.SecureConn, frame sessionFrame) error {
+	var buf = &bytes.Buffer{}
+	if err := cslq.Encode(buf, "v", frame); err != nil {
+		return err
+	}
+	_, err := conn.Write(buf.Bytes())
+	return err
+}
+
+// sessionHandshake sends the request and waits for the response. The connection is closed if the context ends
+// before the handshake is done.
+func sessionHandshake(ctx context.Context, conn net.SecureConn, req sessionRequest) (res sessionResponse, err error) {
+	defer closeOnDone(ctx, conn)()
+
+	if err = cslq.Encode(conn, "v", req); err != nil {
+		return
+	}
+
+	err = cslq.Decode(conn, "v", &res)
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return
+}
+
+// closeOnDone closes the conn if the context ends before the returned function is called
+func closeOnDone(ctx context.Context, conn net.SecureConn) func() {
+	var done = make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+func sessionCodeToError(code int) error {
+	switch code {
+	case sessionOK:
+		return nil
+	case sessionRejected:
+		return net.ErrRejected
+	case sessionRouteNotFound:
+		return &net.ErrRouteNotFound{}
+	case sessionNotFound:
+		return ErrSessionNotFound
+	default:
+		return ErrSessionProtocol
+	}
+}
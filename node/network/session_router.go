@@ -0,0 +1,480 @@
+package network
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/streams"
+	"io"
+	"time"
+)
+
+var _ net.Router = &SessionRouter{}
+
+// SessionRouter serves session queries arriving over a link and passes all other queries to the next router
+type SessionRouter struct {
+	network *CoreNetwork
+	link    net.Link
+	next    net.Router
+}
+
+func NewSessionRouter(network *CoreNetwork, link net.Link, next net.Router) *SessionRouter {
+	return &SessionRouter{network: network, link: link, next: next}
+}
+
+func (router *SessionRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	if query.Query() != SessionQuery {
+		return router.next.RouteQuery(ctx, query, caller, hints)
+	}
+
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		router.network.serveSession(router.link, query, conn)
+	})
+}
+
+// Sessions returns the set of active sessions
+func (n *CoreNetwork) Sessions() *SessionSet {
+	return n.sessions
+}
+
+// serveSession handles a session request from the remote party
+func (n *CoreNetwork) serveSession(l net.Link, query net.Query, conn net.SecureConn) {
+	ctx, cancel := context.WithTimeout(n.ctx, HandshakeTimeout)
+	defer cancel()
+
+	var req sessionRequest
+	var stop = closeOnDone(ctx, conn)
+	var err = cslq.Decode(conn, "v", &req)
+	stop()
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	if len(req.ID) != sessionIDSize {
+		n.respondSession(conn, sessionUnexpected, 0)
+		conn.Close()
+		return
+	}
+
+	switch req.Op {
+	case sessionOpOpen:
+		n.acceptSession(l, query, conn, req)
+
+	case sessionOpResume:
+		n.acceptResume(l, query, conn, req)
+
+	case sessionOpJoin:
+		n.acceptJoin(l, query, conn, req)
+
+	default:
+		n.respondSession(conn, sessionUnexpected, 0)
+		conn.Close()
+	}
+}
+
+// acceptSession routes the query carried by the session request and opens a new session
+func (n *CoreNetwork) acceptSession(l net.Link, query net.Query, conn net.SecureConn, req sessionRequest) {
+	ctx, cancel := context.WithTimeout(n.ctx, defaultQueryTimeout)
+	defer cancel()
+
+	// stop routing the query if the caller goes away
+	conn = &watchedConn{SecureConn: conn, input: streams.WatchReader(conn, cancel)}
+
+	args, err := net.QueryArgsFromPairs(req.Args)
+	if err != nil {
+		n.respondSession(conn, sessionUnexpected, 0)
+		conn.Close()
+		return
+	}
+
+	var s = newSession(req.ID, query.Target(), query.Caller(), req.Query, false)
+
+	target, err := n.node.Router().RouteQuery(
+		ctx,
+		net.NewQueryWithArgs(query.Caller(), query.Target(), req.Query, args),
+		s,
+		net.Hints{Origin: net.OriginNetwork},
+	)
+	if err != nil {
+		var code = sessionRejected
+		if errors.Is(err, &net.ErrRouteNotFound{}) {
+			code = sessionRouteNotFound
+		}
+		s.finish(err)
+		n.respondSession(conn, code, 0)
+		conn.Close()
+		return
+	}
+
+	s.output = target
+
+	if err := n.addSession(s); err != nil {
+		s.finish(err)
+		n.respondSession(conn, sessionUnexpected, 0)
+		conn.Close()
+		return
+	}
+
+	if err := n.respondSession(conn, sessionOK, 0); err != nil {
+		conn.Close()
+	}
+
+	s.attach(conn, l, 0)
+}
+
+// acceptResume moves an existing session to the connection
+func (n *CoreNetwork) acceptResume(l net.Link, query net.Query, conn net.SecureConn, req sessionRequest) {
+	s, err := n.sessions.Find(query.Caller(), hex.EncodeToString(req.ID))
+	if err != nil || s.outbound || !s.localID.IsEqual(query.Target()) {
+		n.respondSession(conn, sessionNotFound, 0)
+		conn.Close()
+		return
+	}
+
+	var received = s.suspend()
+
+	n.log.Logv(2, "resuming session %s with %v over %s", s.ID(), s.remoteID, net.Network(l))
+
+	if err := n.respondSession(conn, sessionOK, received); err != nil {
+		conn.Close()
+	}
+
+	s.attach(conn, l, req.Received)
+}
+
+// acceptJoin adds the connection to the carriers of an existing session
+func (n *CoreNetwork) acceptJoin(l net.Link, query net.Query, conn net.SecureConn, req sessionRequest) {
+	s, err := n.sessions.Find(query.Caller(), hex.EncodeToString(req.ID))
+	if err != nil || s.outbound || !s.localID.IsEqual(query.Target()) {
+		n.respondSession(conn, sessionNotFound, 0)
+		conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	var received = s.received
+	s.mu.Unlock()
+
+	n.log.Logv(2, "session %s with %v joined by %s", s.ID(), s.remoteID, net.Network(l))
+
+	if err := n.respondSession(conn, sessionOK, received); err != nil {
+		conn.Close()
+	}
+
+	s.attach(conn, l, req.Received)
+}
+
+// watchedConn is a conn read through a WatchReader
+type watchedConn struct {
+	net.SecureConn
+	input io.ReadCloser
+}
+
+func (c *watchedConn) Read(p []byte) (int, error) {
+	return c.input.Read(p)
+}
+
+func (c *watchedConn) Close() error {
+	c.input.Close()
+	return c.SecureConn.Close()
+}
+
+func (n *CoreNetwork) respondSession(conn net.SecureConn, code int, received uint64) error {
+	return cslq.Encode(conn, "v", sessionResponse{Code: code, Received: received})
+}
+
+// openSession routes the query over the link as a new session
+func (n *CoreNetwork) openSession(ctx context.Context, l net.Link, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	var sid = make([]byte, sessionIDSize)
+	if _, err := rand.Read(sid); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.RouteWithHints(ctx, l, net.NewQuery(query.Caller(), query.Target(), SessionQuery), hints)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := sessionHandshake(ctx, conn, sessionRequest{
+		Op:    sessionOpOpen,
+		ID:    sid,
+		Query: query.Query(),
+		Args:  query.Args().Pairs(),
+	})
+	if err == nil {
+		err = sessionCodeToError(res.Code)
+	}
+	if err != nil {
+		conn.Close()
+		if errors.Is(err, &net.ErrRouteNotFound{}) {
+			return nil, &net.ErrRouteNotFound{Router: l}
+		}
+		return nil, err
+	}
+
+	var s = newSession(sid, query.Caller(), query.Target(), query.Query(), true)
+	s.output = caller
+	s.multipath = hints.Multipath && supportsMultipath(l)
+
+	if err := n.addSession(s); err != nil {
+		s.finish(err)
+		conn.Close()
+		return nil, err
+	}
+
+	s.attach(conn, l, 0)
+
+	if s.multipath {
+		go n.joinLinks(s)
+	}
+
+	return s, nil
+}
+
+func (n *CoreNetwork) addSession(s *Session) error {
+	s.onDone = n.sessions.Remove
+	if s.outbound {
+		s.onDetach = func(s *Session) {
+			go n.resumeSession(s)
+		}
+	}
+
+	return n.sessions.Add(s)
+}
+
+// resumeSession keeps trying to resume a detached outbound session until it succeeds or the session expires
+func (n *CoreNetwork) resumeSession(s *Session) {
+	if !s.resuming.CompareAndSwap(false, true) {
+		return
+	}
+
+	n.resume(s)
+
+	s.resuming.Store(false)
+
+	// the session could have been detached again before the flag was cleared
+	select {
+	case <-s.Done():
+	default:
+		if s.Link() == nil {
+			go n.resumeSession(s)
+		}
+	}
+}
+
+func (n *CoreNetwork) resume(s *Session) {
+	var received = s.suspend()
+	var deadline = time.Now().Add(sessionResumeTimeout)
+
+	for {
+		err := n.resumeOnce(s, received)
+		if err == nil {
+			return
+		}
+
+		select {
+		case <-s.Done():
+			return
+		default:
+		}
+
+		if errors.Is(err, ErrSessionNotFound) || time.Now().After(deadline) {
+			n.log.Errorv(1, "cannot resume session %s with %v: %v", s.ID(), s.remoteID, err)
+			s.mu.Lock()
+			var complete = s.localFin && s.remoteFin
+			s.mu.Unlock()
+			if complete {
+				s.finish(nil)
+			} else {
+				s.finish(ErrSessionExpired)
+			}
+			return
+		}
+
+		n.log.Logv(2, "retrying session %s with %v: %v", s.ID(), s.remoteID, err)
+
+		select {
+		case <-time.After(sessionRetryInterval):
+		case <-s.Done():
+			return
+		case <-n.ctx.Done():
+			s.finish(n.ctx.Err())
+			return
+		}
+	}
+}
+
+func (n *CoreNetwork) resumeOnce(s *Session, received uint64) error {
+	ctx, cancel := context.WithTimeout(n.ctx, HandshakeTimeout)
+	defer cancel()
+
+	l, err := n.sessionLink(ctx, s.localID, s.remoteID)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.RouteWithHints(ctx, l, net.NewQuery(s.localID, s.remoteID, SessionQuery), net.Hints{Origin: net.OriginLocal})
+	if err != nil {
+		return err
+	}
+
+	res, err := sessionHandshake(ctx, conn, sessionRequest{
+		Op:       sessionOpResume,
+		ID:       s.id,
+		Received: received,
+	})
+	if err == nil {
+		err = sessionCodeToError(res.Code)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	n.log.Logv(2, "resumed session %s with %v over %s", s.ID(), s.remoteID, net.Network(l))
+
+	if err := s.attach(conn, l, res.Received); err != nil {
+		return err
+	}
+
+	if s.multipath {
+		go n.joinLinks(s)
+	}
+
+	return nil
+}
+
+// joinLinks adds all links with the remote party that aren't carrying the session yet to its carriers
+func (n *CoreNetwork) joinLinks(s *Session) {
+	for _, l := range n.links.ByRemoteIdentity(s.remoteID).ByLocalIdentity(s.localID).AllRaw() {
+		if err := n.joinLink(s, l); err != nil {
+			n.log.Logv(2, "cannot join session %s over %s: %v", s.ID(), net.Network(l), err)
+		}
+	}
+}
+
+// joinLink adds the link to the carriers of a multipath session
+func (n *CoreNetwork) joinLink(s *Session, l net.Link) error {
+	if !supportsMultipath(l) {
+		return ErrSessionsUnsupported
+	}
+	for _, c := range s.Links() {
+		if c == l {
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, HandshakeTimeout)
+	defer cancel()
+
+	conn, err := net.RouteWithHints(ctx, l, net.NewQuery(s.localID, s.remoteID, SessionQuery), net.Hints{Origin: net.OriginLocal})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	var received = s.received
+	s.mu.Unlock()
+
+	res, err := sessionHandshake(ctx, conn, sessionRequest{
+		Op:       sessionOpJoin,
+		ID:       s.id,
+		Received: received,
+	})
+	if err == nil {
+		err = sessionCodeToError(res.Code)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	n.log.Logv(2, "session %s with %v joined over %s", s.ID(), s.remoteID, net.Network(l))
+
+	return s.attach(conn, l, res.Received)
+}
+
+// sessionLink returns the best link between the parties that supports sessions, linking the remote party
+// if necessary
+func (n *CoreNetwork) sessionLink(ctx context.Context, localID id.Identity, remoteID id.Identity) (net.Link, error) {
+	var best net.Link
+	for _, l := range usableLinks(n.links.ByRemoteIdentity(remoteID).ByLocalIdentity(localID).AllRaw()) {
+		if supportsSessions(l) {
+			best = n.bestLink(best, l)
+		}
+	}
+	if best != nil {
+		return best, nil
+	}
+
+	l, err := n.Link(ctx, remoteID)
+	if err != nil {
+		return nil, err
+	}
+	if !supportsSessions(l) {
+		return nil, ErrSessionsUnsupported
+	}
+
+	return l, nil
+}
+
+// migrateSessions moves outbound sessions with the link's remote party to the link if it's better than
+// the link currently carrying them
+func (n *CoreNetwork) migrateSessions(l net.Link) {
+	if !supportsSessions(l) {
+		return
+	}
+
+	for _, s := range n.sessions.All() {
+		if !s.outbound || !s.remoteID.IsEqual(l.RemoteIdentity()) || !s.localID.IsEqual(l.LocalIdentity()) {
+			continue
+		}
+
+		// suspended sessions are handled by the resumer
+		var current = s.Link()
+		if current == nil {
+			continue
+		}
+
+		// multipath sessions use all links instead of moving to the best one
+		if s.multipath {
+			if err := n.joinLink(s, l); err != nil {
+				n.log.Logv(2, "cannot join session %s over %s: %v", s.ID(), net.Network(l), err)
+			}
+			continue
+		}
+
+		if current == l || !n.isBetterLink(current, l) {
+			continue
+		}
+
+		n.log.Logv(1, "moving session %s with %v to %s", s.ID(), s.remoteID, net.Network(l))
+
+		// detaching an outbound session makes it resume over the best available link
+		s.suspend()
+	}
+}
+
+func supportsMultipath(l net.Link) bool {
+	if l, ok := l.(featureChecker); ok {
+		return l.HasFeature(link.FeatureMultipath)
+	}
+	return false
+}
+
+type featureChecker interface {
+	HasFeature(string) bool
+}
+
+func supportsSessions(l net.Link) bool {
+	if l, ok := l.(featureChecker); ok {
+		return l.HasFeature(link.FeatureSessions)
+	}
+	return false
+}
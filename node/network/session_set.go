@@ -0,0 +1,75 @@
+package network
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"sync"
+)
+
+// SessionSet holds active sessions indexed by the remote identity and the session ID
+type SessionSet struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewSessionSet() *SessionSet {
+	return &SessionSet{
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Add adds a session to the set.
+// Errors: ErrDuplicateSession
+func (set *SessionSet) Add(s *Session) error {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	var key = sessionKey(s.remoteID, s.ID())
+	if _, found := set.sessions[key]; found {
+		return ErrDuplicateSession
+	}
+
+	set.sessions[key] = s
+
+	return nil
+}
+
+// Find returns the session with the remote identity and the ID.
+// Errors: ErrSessionNotFound
+func (set *SessionSet) Find(remoteID id.Identity, sessionID string) (*Session, error) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	if s, found := set.sessions[sessionKey(remoteID, sessionID)]; found {
+		return s, nil
+	}
+
+	return nil, ErrSessionNotFound
+}
+
+// Remove removes a session from the set
+func (set *SessionSet) Remove(s *Session) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	var key = sessionKey(s.remoteID, s.ID())
+	if set.sessions[key] == s {
+		delete(set.sessions, key)
+	}
+}
+
+// All returns a copy of an array holding all sessions in the set
+func (set *SessionSet) All() []*Session {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	var list = make([]*Session, 0, len(set.sessions))
+	for _, s := range set.sessions {
+		list = append(list, s)
+	}
+
+	return list
+}
+
+func sessionKey(remoteID id.Identity, sessionID string) string {
+	return remoteID.PublicKeyHex() + ":" + sessionID
+}
@@ -0,0 +1,223 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/tracker"
+	"io"
+	"testing"
+	"time"
+)
+
+func sessionConnPair(a id.Identity, b id.Identity) (net.SecureConn, net.SecureConn) {
+	r1, w1 := net.SecurePipe(b)
+	r2, w2 := net.SecurePipe(a)
+	return net.NewSecureConn(w1, r2, true), net.NewSecureConn(w2, r1, false)
+}
+
+func readSession(t *testing.T, r io.Reader, n int) string {
+	var buf = make([]byte, n)
+	var done = make(chan error, 1)
+	go func() {
+		_, err := io.ReadFull(r, buf)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	return string(buf)
+}
+
+func TestSessionResume(t *testing.T) {
+	a, _ := id.GenerateIdentity()
+	b, _ := id.GenerateIdentity()
+	var sid = make([]byte, sessionIDSize)
+
+	sessionA := newSession(sid, a, b, "test", true)
+	sessionB := newSession(sid, b, a, "test", false)
+
+	outA, outAW := net.SecurePipe(a)
+	outB, outBW := net.SecurePipe(b)
+	sessionA.output = outAW
+	sessionB.output = outBW
+
+	connA, connB := sessionConnPair(a, b)
+	go sessionB.attach(connB, nil, 0)
+	sessionA.attach(connA, nil, 0)
+
+	if _, err := sessionA.Write([]byte("hello ")); err != nil {
+		t.Fatal(err)
+	}
+	if s := readSession(t, outB, 6); s != "hello " {
+		t.Fatalf("received %q, expected %q", s, "hello ")
+	}
+
+	// break the carrier and keep writing while the session is suspended
+	connA.Close()
+	receivedA := sessionA.suspend()
+	receivedB := sessionB.suspend()
+
+	if _, err := sessionA.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+
+	connA, connB = sessionConnPair(a, b)
+	go sessionB.attach(connB, nil, receivedA)
+	sessionA.attach(connA, nil, receivedB)
+
+	if s := readSession(t, outB, 5); s != "world" {
+		t.Fatalf("received %q, expected %q", s, "world")
+	}
+
+	if _, err := sessionB.Write([]byte("bye")); err != nil {
+		t.Fatal(err)
+	}
+	if s := readSession(t, outA, 3); s != "bye" {
+		t.Fatalf("received %q, expected %q", s, "bye")
+	}
+
+	sessionA.Close()
+	sessionB.Close()
+
+	for _, s := range []*Session{sessionA, sessionB} {
+		select {
+		case <-s.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("session did not end")
+		}
+		if !errors.Is(s.Err(), ErrSessionClosed) {
+			t.Fatalf("session ended with %v", s.Err())
+		}
+	}
+}
+
+func TestSessionMultipath(t *testing.T) {
+	a, _ := id.GenerateIdentity()
+	b, _ := id.GenerateIdentity()
+	var sid = make([]byte, sessionIDSize)
+
+	sessionA := newSession(sid, a, b, "test", true)
+	sessionB := newSession(sid, b, a, "test", false)
+	sessionA.multipath = true
+
+	_, outAW := net.SecurePipe(a)
+	outB, outBW := net.SecurePipe(b)
+	sessionA.output = outAW
+	sessionB.output = outBW
+
+	var connsA []net.SecureConn
+	for i := 0; i < 3; i++ {
+		connA, connB := sessionConnPair(a, b)
+		go sessionB.attach(connB, nil, 0)
+		sessionA.attach(connA, nil, 0)
+		connsA = append(connsA, connA)
+	}
+
+	var data = make([]byte, 20*sessionMaxFrameSize)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	go func() {
+		sessionA.Write(data[:len(data)/2])
+		// lose one of the paths in the middle of the stream
+		connsA[1].Close()
+		sessionA.Write(data[len(data)/2:])
+		sessionA.Close()
+	}()
+
+	if s := readSession(t, outB, len(data)); s != string(data) {
+		t.Fatal("received data does not match")
+	}
+
+	sessionB.Close()
+
+	select {
+	case <-sessionA.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not end")
+	}
+}
+
+// blockingRouter blocks queries until they are cancelled
+type blockingRouter struct {
+	routing   chan struct{}
+	cancelled chan struct{}
+}
+
+func (r *blockingRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	close(r.routing)
+	<-ctx.Done()
+	close(r.cancelled)
+	return nil, ctx.Err()
+}
+
+// testNode is a node with only an identity and a router
+type testNode struct {
+	identity id.Identity
+	router   net.Router
+}
+
+func (n *testNode) Identity() id.Identity    { return n.identity }
+func (n *testNode) Router() net.Router       { return n.router }
+func (n *testNode) Infra() infra.Infra       { return nil }
+func (n *testNode) Tracker() tracker.Tracker { return nil }
+func (n *testNode) Events() *events.Queue    { return nil }
+
+func TestSessionQueryCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, _ := id.GenerateIdentity()
+	b, _ := id.GenerateIdentity()
+	var l = log.NewLogger(log.NewPrinterSplitter())
+	var router = &blockingRouter{routing: make(chan struct{}), cancelled: make(chan struct{})}
+
+	var networkA = &CoreNetwork{ctx: ctx, log: l, sessions: NewSessionSet()}
+	var networkB = &CoreNetwork{ctx: ctx, log: l, sessions: NewSessionSet(), node: &testNode{identity: b, router: router}}
+
+	connA, connB := sessionConnPair(a, b)
+	var linkA = link.NewCoreLink(connA, link.FeatureMux, link.FeatureSessions)
+	var linkB = link.NewCoreLink(connB, link.FeatureMux, link.FeatureSessions)
+	linkB.SetUplink(NewSessionRouter(networkB, linkB, router))
+
+	go linkA.Run(ctx)
+	go linkB.Run(ctx)
+
+	// cancel the query once the remote party is routing it
+	queryCtx, queryCancel := context.WithCancel(ctx)
+	defer queryCancel()
+	go func() {
+		<-router.routing
+		queryCancel()
+	}()
+
+	var errCh = make(chan error, 1)
+	go func() {
+		_, err := networkA.openSession(queryCtx, linkA, net.NewQuery(a, b, "test"), nil, net.Hints{})
+		errCh <- err
+	}()
+
+	<-router.routing
+	select {
+	case <-router.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("session query was not cancelled on the remote party")
+	}
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("query returned %v, expected %v", err, context.Canceled)
+	}
+}
@@ -17,3 +17,13 @@ var (
 
 const MaxPeerLinks = 8
 const HandshakeTimeout = 15 * time.Second
+
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrDuplicateSession = errors.New("duplicate session")
+	ErrSessionClosed    = errors.New("session closed")
+	ErrSessionExpired   = errors.New("session expired")
+	ErrSessionProtocol  = errors.New("session protocol error")
+)
+
+var ErrSessionsUnsupported = errors.New("link does not support sessions")
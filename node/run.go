@@ -3,6 +3,7 @@ package node
 import (
 	"context"
 	"fmt"
+	"github.com/cryptopunkscc/astrald/node/infra/drivers/ip"
 	"sync"
 )
 
@@ -37,6 +38,15 @@ func (node *CoreNode) Run(ctx context.Context) (err error) {
 		}
 	}()
 
+	// run the tracker
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := node.tracker.Run(ctx); err != nil {
+			errCh <- fmt.Errorf("tracker: %w", err)
+		}
+	}()
+
 	// run the module manager
 	wg.Add(1)
 	go func() {
@@ -46,6 +56,15 @@ func (node *CoreNode) Run(ctx context.Context) (err error) {
 		}
 	}()
 
+	// pass network interface changes to the event queue
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for event := range ip.Monitor(ctx) {
+			node.events.Emit(event)
+		}
+	}()
+
 	// event handling
 	wg.Add(1)
 	go func() {
@@ -18,16 +18,18 @@ var _ net.Router = &CoreServices{}
 
 // CoreServices facilitates registration of services and querying them.
 type CoreServices struct {
-	services []*Service
-	mu       sync.Mutex
-	events   events.Queue
-	log      *log.Logger
+	services  []*Service
+	datagrams map[datagramKey]net.DatagramHandler
+	mu        sync.Mutex
+	events    events.Queue
+	log       *log.Logger
 }
 
 func NewCoreServices(eventParent *events.Queue, log *log.Logger) *CoreServices {
 	hub := &CoreServices{
-		services: make([]*Service, 0),
-		log:      log.Tag(logTag),
+		services:  make([]*Service, 0),
+		datagrams: make(map[datagramKey]net.DatagramHandler),
+		log:       log.Tag(logTag),
 	}
 	hub.events.SetParent(eventParent)
 	return hub
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+var _ net.DatagramHandler = &CoreServices{}
+
+// datagramKey identifies a datagram handler. Datagram handlers are registered separately from query handlers, so
+// a service can use the same name for both.
+type datagramKey struct {
+	identity string
+	name     string
+}
+
+// RegisterDatagram registers a handler of datagrams sent to the service of the identity. The handler is released
+// when the context is done.
+func (srv *CoreServices) RegisterDatagram(ctx context.Context, identity id.Identity, name string, handler net.DatagramHandler) error {
+	var key = datagramKey{identity: identity.PublicKeyHex(), name: name}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if _, found := srv.datagrams[key]; found {
+		return ErrAlreadyRegistered
+	}
+	srv.datagrams[key] = handler
+
+	srv.log.Infov(1, "registered datagram handler %v:%s", identity, name)
+
+	go func() {
+		<-ctx.Done()
+
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+
+		delete(srv.datagrams, key)
+
+		srv.log.Infov(1, "released datagram handler %v:%s", identity, name)
+	}()
+
+	return nil
+}
+
+// HandleDatagram passes the datagram to the handler registered by the target. Datagrams sent to services without
+// a handler are dropped.
+func (srv *CoreServices) HandleDatagram(d net.Datagram) {
+	srv.mu.Lock()
+	handler, found := srv.datagrams[datagramKey{identity: d.Target.PublicKeyHex(), name: d.Service}]
+	srv.mu.Unlock()
+
+	if found {
+		handler.HandleDatagram(d)
+	}
+}
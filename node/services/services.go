@@ -9,10 +9,12 @@ import (
 
 type Services interface {
 	net.Router
+	net.DatagramHandler
 	Register(ctx context.Context, identity id.Identity, name string, handler net.Router) (*Service, error)
 	Find(identity id.Identity, name string) (*Service, error)
 	FindByName(name string) ([]*Service, error)
 	List() []ServiceInfo
+	RegisterDatagram(ctx context.Context, identity id.Identity, name string, handler net.DatagramHandler) error
 }
 
 type ServiceInfo struct {
@@ -3,9 +3,11 @@ package tracker
 import (
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
+	"time"
 )
 
-// AddEndpoint adds an endpoint to the identity. If the endpoint already exists, its expiry time will be replaced.
+// AddEndpoint adds an endpoint to the identity. If the endpoint already exists, it is refreshed and its expiry
+// time starts over.
 func (tracker *CoreTracker) AddEndpoint(identity id.Identity, e net.Endpoint) (err error) {
 	var dbEp dbEndpoint
 
@@ -16,9 +18,10 @@ func (tracker *CoreTracker) AddEndpoint(identity id.Identity, e net.Endpoint) (e
 
 	if dbEp, err = tracker.find(identity, e); err != nil {
 		err = tracker.db.Create(&dbEndpoint{
-			Identity: identity.String(),
-			Network:  e.Network(),
-			Address:  e.String(),
+			Identity:    identity.String(),
+			Network:     e.Network(),
+			Address:     e.String(),
+			RefreshedAt: time.Now(),
 		}).Error
 
 		if err == nil {
@@ -30,5 +33,7 @@ func (tracker *CoreTracker) AddEndpoint(identity id.Identity, e net.Endpoint) (e
 		return
 	}
 
+	dbEp.RefreshedAt = time.Now()
+
 	return tracker.db.Save(&dbEp).Error
 }
@@ -0,0 +1,12 @@
+package tracker
+
+import "time"
+
+type Config struct {
+	// EndpointTTL is how long an endpoint is kept after it was last added or successfully dialed. Zero disables expiry.
+	EndpointTTL time.Duration `yaml:"endpoint_ttl"`
+}
+
+var DefaultConfig = Config{
+	EndpointTTL: 30 * 24 * time.Hour,
+}
@@ -16,6 +16,7 @@ var _ Tracker = &CoreTracker{}
 
 // CoreTracker stores information about addresses of other nodes on the network.
 type CoreTracker struct {
+	config Config
 	db     *gorm.DB
 	parser EndpointParser
 	events events.Queue
@@ -28,9 +29,10 @@ type EndpointParser interface {
 
 // NewCoreTracker returns a new instance of a CoreTracker. It will use db for persistency and the provided unpacker
 // to unpack addresses stored in the database.
-func NewCoreTracker(assets assets.Store, parser EndpointParser, log *log.Logger, events *events.Queue) (*CoreTracker, error) {
+func NewCoreTracker(assets assets.Store, parser EndpointParser, config Config, log *log.Logger, events *events.Queue) (*CoreTracker, error) {
 	var err error
 	var tracker = &CoreTracker{
+		config: config,
 		parser: parser,
 		log:    log.Tag(logTag),
 	}
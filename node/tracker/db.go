@@ -6,11 +6,17 @@ import (
 )
 
 type dbEndpoint struct {
-	Identity      string `gorm:"primaryKey"`
-	Network       string `gorm:"primaryKey"`
-	Address       string `gorm:"primaryKey"`
-	CreatedAt     time.Time
-	LastSuccessAt time.Time
+	Identity            string `gorm:"primaryKey"`
+	Network             string `gorm:"primaryKey"`
+	Address             string `gorm:"primaryKey"`
+	CreatedAt           time.Time
+	RefreshedAt         time.Time
+	LastSuccessAt       time.Time
+	LastFailureAt       time.Time
+	Successes           int
+	Failures            int
+	ConsecutiveFailures int
+	Latency             time.Duration
 }
 
 func (dbEndpoint) TableName() string { return "endpoints" }
@@ -25,10 +31,20 @@ func (tracker *CoreTracker) dbEndpointToEndopoint(src dbEndpoint) (net.Endpoint,
 }
 
 func (tracker *CoreTracker) dbAutoMigrate() (err error) {
-	return tracker.db.AutoMigrate(
+	err = tracker.db.AutoMigrate(
 		&dbEndpoint{},
 		&dbAliases{},
+		&dbNode{},
 	)
+	if err != nil {
+		return
+	}
+
+	// endpoints stored before refresh times were tracked get a full TTL from now, so that they are not expired
+	// on the first sweep after an upgrade
+	return tracker.db.Model(&dbEndpoint{}).
+		Where("refreshed_at IS NULL OR refreshed_at = ?", time.Time{}).
+		Update("refreshed_at", time.Now()).Error
 }
 
 type dbAliases struct {
@@ -37,3 +53,11 @@ type dbAliases struct {
 }
 
 func (dbAliases) TableName() string { return "aliases" }
+
+// dbNode holds what is known about a node apart from its endpoints
+type dbNode struct {
+	Identity string `gorm:"primaryKey"`
+	IK       bool
+}
+
+func (dbNode) TableName() string { return "nodes" }
@@ -0,0 +1,62 @@
+package tracker
+
+import (
+	"context"
+	"time"
+)
+
+const expireInterval = time.Hour
+
+// Run periodically removes endpoints that were not refreshed or dialed successfully within the configured TTL
+func (tracker *CoreTracker) Run(ctx context.Context) error {
+	if tracker.config.EndpointTTL <= 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	var ticker = time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := tracker.expireEndpoints(); err != nil {
+			tracker.log.Error("error expiring endpoints: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (tracker *CoreTracker) expireEndpoints() error {
+	var rows []dbEndpoint
+
+	if err := tracker.db.Find(&rows).Error; err != nil {
+		return err
+	}
+
+	var cutoff = time.Now().Add(-tracker.config.EndpointTTL)
+	var count int
+
+	for _, row := range rows {
+		if row.lastSeen().After(cutoff) {
+			continue
+		}
+
+		err := tracker.db.Delete(&dbEndpoint{}, "identity = ? and network = ? and address = ?",
+			row.Identity, row.Network, row.Address,
+		).Error
+		if err != nil {
+			return err
+		}
+		count++
+	}
+
+	if count > 0 {
+		tracker.log.Logv(1, "expired %d endpoint(s)", count)
+	}
+
+	return nil
+}
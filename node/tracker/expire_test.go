@@ -0,0 +1,56 @@
+package tracker
+
+import (
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// legacyEndpoint is the endpoint row stored before refresh times were tracked
+type legacyEndpoint struct {
+	Identity      string `gorm:"primaryKey"`
+	Network       string `gorm:"primaryKey"`
+	Address       string `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	LastSuccessAt time.Time
+}
+
+func (legacyEndpoint) TableName() string { return "endpoints" }
+
+func TestExpireAfterUpgrade(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "tracker.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&legacyEndpoint{}); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Create(&legacyEndpoint{
+		Identity:  "node",
+		Network:   "inet",
+		Address:   "192.168.1.1:1791",
+		CreatedAt: time.Now().Add(-30 * 24 * time.Hour),
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tracker = &CoreTracker{db: db, config: Config{EndpointTTL: 7 * 24 * time.Hour}}
+	if err := tracker.dbAutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.expireEndpoints(); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	if err := db.Model(&dbEndpoint{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatal("endpoint stored before the upgrade was expired")
+	}
+}
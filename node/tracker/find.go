@@ -3,8 +3,10 @@ package tracker
 import (
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
+	"sort"
 )
 
+// EndpointsByIdentity returns endpoints of the identity ordered by their dial history, most reliable first
 func (tracker *CoreTracker) EndpointsByIdentity(identity id.Identity) ([]net.Endpoint, error) {
 	var rows []dbEndpoint
 
@@ -12,6 +14,10 @@ func (tracker *CoreTracker) EndpointsByIdentity(identity id.Identity) ([]net.End
 		return nil, err
 	}
 
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].better(rows[j])
+	})
+
 	var endpoints = make([]net.Endpoint, 0, len(rows))
 
 	for _, dbEp := range rows {
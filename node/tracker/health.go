@@ -0,0 +1,94 @@
+package tracker
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ReportSuccess records a successful dial of the endpoint and updates its average latency. A zero latency
+// counts the success without changing the average. Endpoints unknown to the tracker are ignored.
+func (tracker *CoreTracker) ReportSuccess(identity id.Identity, e net.Endpoint, latency time.Duration) error {
+	return tracker.update(identity, e, func(dbEp *dbEndpoint) {
+		dbEp.Successes++
+		dbEp.ConsecutiveFailures = 0
+		dbEp.LastSuccessAt = time.Now()
+
+		switch {
+		case latency == 0:
+		case dbEp.Latency == 0:
+			dbEp.Latency = latency
+		default:
+			dbEp.Latency = (3*dbEp.Latency + latency) / 4
+		}
+	})
+}
+
+// ReportFailure records a failed dial of the endpoint. Endpoints unknown to the tracker are ignored.
+func (tracker *CoreTracker) ReportFailure(identity id.Identity, e net.Endpoint) error {
+	return tracker.update(identity, e, func(dbEp *dbEndpoint) {
+		dbEp.Failures++
+		dbEp.ConsecutiveFailures++
+		dbEp.LastFailureAt = time.Now()
+	})
+}
+
+func (tracker *CoreTracker) update(identity id.Identity, e net.Endpoint, fn func(*dbEndpoint)) error {
+	dbEp, err := tracker.find(identity, e)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	fn(&dbEp)
+
+	return tracker.db.Save(&dbEp).Error
+}
+
+// rank returns the group the endpoint is dialed in. Endpoints that work come first, then the ones that were
+// never dialed, then the failing ones.
+func (dbEp dbEndpoint) rank() int {
+	switch {
+	case dbEp.ConsecutiveFailures > 0:
+		return 2
+	case dbEp.Successes > 0:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// better returns true if the endpoint should be dialed before the other one
+func (dbEp dbEndpoint) better(other dbEndpoint) bool {
+	if a, b := dbEp.rank(), other.rank(); a != b {
+		return a < b
+	}
+
+	switch dbEp.rank() {
+	case 0:
+		return dbEp.Latency < other.Latency
+	case 1:
+		return dbEp.CreatedAt.After(other.CreatedAt)
+	default:
+		if dbEp.ConsecutiveFailures != other.ConsecutiveFailures {
+			return dbEp.ConsecutiveFailures < other.ConsecutiveFailures
+		}
+		return dbEp.LastSuccessAt.After(other.LastSuccessAt)
+	}
+}
+
+// lastSeen returns the last time the endpoint was added or dialed successfully
+func (dbEp dbEndpoint) lastSeen() time.Time {
+	var t = dbEp.CreatedAt
+	if dbEp.RefreshedAt.After(t) {
+		t = dbEp.RefreshedAt
+	}
+	if dbEp.LastSuccessAt.After(t) {
+		t = dbEp.LastSuccessAt
+	}
+	return t
+}
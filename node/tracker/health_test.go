@@ -0,0 +1,51 @@
+package tracker
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestEndpointRanking(t *testing.T) {
+	var now = time.Now()
+
+	var rows = []dbEndpoint{
+		{Address: "failing", Successes: 5, ConsecutiveFailures: 3},
+		{Address: "untested-old", CreatedAt: now.Add(-time.Hour)},
+		{Address: "slow", Successes: 2, Latency: 300 * time.Millisecond},
+		{Address: "dead", Failures: 8, ConsecutiveFailures: 8},
+		{Address: "untested-new", CreatedAt: now},
+		{Address: "fast", Successes: 1, Latency: 20 * time.Millisecond},
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].better(rows[j])
+	})
+
+	var expected = []string{"fast", "slow", "untested-new", "untested-old", "failing", "dead"}
+	for i, e := range expected {
+		if rows[i].Address != e {
+			t.Fatalf("position %d: expected %s, got %s", i, e, rows[i].Address)
+		}
+	}
+}
+
+func TestEndpointLastSeen(t *testing.T) {
+	var now = time.Now()
+
+	var dbEp = dbEndpoint{
+		CreatedAt:     now.Add(-48 * time.Hour),
+		RefreshedAt:   now.Add(-24 * time.Hour),
+		LastSuccessAt: now.Add(-time.Hour),
+		LastFailureAt: now,
+	}
+
+	if !dbEp.lastSeen().Equal(dbEp.LastSuccessAt) {
+		t.Fatalf("expected last success, got %v", dbEp.lastSeen())
+	}
+
+	dbEp.LastSuccessAt = time.Time{}
+	if !dbEp.lastSeen().Equal(dbEp.RefreshedAt) {
+		t.Fatalf("expected refresh time, got %v", dbEp.lastSeen())
+	}
+}
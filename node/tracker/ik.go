@@ -0,0 +1,23 @@
+package tracker
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+)
+
+// SetSupportsIK remembers whether the node accepts IK handshakes, so that links made after a restart can use them
+func (tracker *CoreTracker) SetSupportsIK(identity id.Identity, supported bool) error {
+	return tracker.db.Save(&dbNode{
+		Identity: identity.String(),
+		IK:       supported,
+	}).Error
+}
+
+// SupportsIK returns true if the node agreed to use IK handshakes on a previous link
+func (tracker *CoreTracker) SupportsIK(identity id.Identity) bool {
+	var row dbNode
+	if err := tracker.db.First(&row, "identity = ?", identity.String()).Error; err != nil {
+		return false
+	}
+
+	return row.IK
+}
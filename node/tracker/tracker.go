@@ -3,14 +3,19 @@ package tracker
 import (
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
+	"time"
 )
 
 type Tracker interface {
 	AddEndpoint(identity id.Identity, endpoint net.Endpoint) error
 	EndpointsByIdentity(identity id.Identity) ([]net.Endpoint, error)
+	ReportSuccess(identity id.Identity, endpoint net.Endpoint, latency time.Duration) error
+	ReportFailure(identity id.Identity, endpoint net.Endpoint) error
 	DeleteAll(identity id.Identity) error
 	Identities() ([]id.Identity, error)
 	SetAlias(identity id.Identity, alias string) error
 	GetAlias(identity id.Identity) (string, error)
 	IdentityByAlias(alias string) (id.Identity, error)
+	SetSupportsIK(identity id.Identity, supported bool) error
+	SupportsIK(identity id.Identity) bool
 }
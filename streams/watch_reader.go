@@ -0,0 +1,92 @@
+package streams
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// watchBufferSize is how much data WatchReader buffers ahead of its reader
+const watchBufferSize = 256 * 1024
+
+// WatchReader returns a reader with the contents of r. The contents are read from r in the background and onDone
+// is called as soon as r fails or reaches EOF, which makes it possible to notice that the other side went away
+// while nobody is reading. Up to watchBufferSize bytes are buffered ahead of the reader, so EOF is noticed even
+// if the last chunk of data wasn't read yet. Closing the returned reader stops the background copy.
+func WatchReader(r io.Reader, onDone func()) io.ReadCloser {
+	var w = &watchReader{}
+	w.cond = sync.NewCond(&w.mu)
+
+	go w.watch(r, onDone)
+
+	return w
+}
+
+type watchReader struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    bytes.Buffer
+	err    error
+	closed bool
+}
+
+func (w *watchReader) watch(r io.Reader, onDone func()) {
+	defer onDone()
+
+	var chunk = make([]byte, 32*1024)
+	for {
+		w.mu.Lock()
+		for w.buf.Len() >= watchBufferSize && !w.closed {
+			w.cond.Wait()
+		}
+		var closed = w.closed
+		w.mu.Unlock()
+
+		if closed {
+			return
+		}
+
+		n, err := r.Read(chunk)
+
+		w.mu.Lock()
+		w.buf.Write(chunk[:n])
+		if err != nil {
+			w.err = err
+		}
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (w *watchReader) Read(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for w.buf.Len() == 0 && w.err == nil && !w.closed {
+		w.cond.Wait()
+	}
+
+	switch {
+	case w.closed:
+		return 0, io.ErrClosedPipe
+	case w.buf.Len() > 0:
+		n, _ := w.buf.Read(p)
+		w.cond.Broadcast()
+		return n, nil
+	default:
+		return 0, w.err
+	}
+}
+
+func (w *watchReader) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	w.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,33 @@
+package streams
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWatchReaderEOFWithPendingData(t *testing.T) {
+	var pr, pw = io.Pipe()
+	var done = make(chan struct{})
+
+	var r = WatchReader(pr, func() { close(done) })
+	defer r.Close()
+
+	// nobody reads the data, but the watcher should still notice the writer going away
+	pw.Write([]byte("pending"))
+	pw.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("EOF not noticed while data was pending")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "pending" {
+		t.Fatalf("read %q, expected %q", data, "pending")
+	}
+}